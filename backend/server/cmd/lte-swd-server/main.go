@@ -10,6 +10,7 @@ import (
 	"time"
 
 	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/commands"
 	"lte_swd/backend/server/internal/config"
 	"lte_swd/backend/server/internal/httpapi"
 	"lte_swd/backend/server/internal/service"
@@ -23,6 +24,18 @@ func main() {
 		os.Exit(1)
 	}
 
+	commandTypes := commands.Builtin()
+	cfg.ApprovalRequiredTypes, err = commandTypes.Resolve(cfg.ApprovalRequiredTypes)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: approval required types: %v\n", err)
+		os.Exit(1)
+	}
+	cfg.Interlocks.Types, err = commandTypes.Resolve(cfg.Interlocks.Types)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "config error: interlock types: %v\n", err)
+		os.Exit(1)
+	}
+
 	st, err := store.NewStateStore(cfg.DataFile, cfg.FleetLimit)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "store error: %v\n", err)
@@ -30,7 +43,11 @@ func main() {
 	}
 
 	opAuth := auth.NewOperatorAuth(cfg.OperatorPassword, cfg.OperatorTokenTTL)
-	svc := service.New(cfg, st, opAuth)
+	if err := opAuth.SetAccounts(cfg.OperatorAccounts); err != nil {
+		fmt.Fprintf(os.Stderr, "config error: %v\n", err)
+		os.Exit(1)
+	}
+	svc := service.New(cfg, st, opAuth, commandTypes)
 	api := httpapi.NewHandler(svc, cfg.StaticDir, httpapi.Options{
 		MaxJSONBytes:      cfg.MaxJSONBytes,
 		MaxArtifactBytes:  cfg.MaxArtifactBytes,
@@ -48,6 +65,12 @@ func main() {
 		WriteTimeout:      60 * time.Second,
 		IdleTimeout:       90 * time.Second,
 	}
+	server.RegisterOnShutdown(svc.Shutdown)
+
+	runCtx, stopRun := context.WithCancel(context.Background())
+	defer stopRun()
+	go svc.Run(runCtx)
+
 	useTLS := cfg.HTTPSAddr != ""
 	if useTLS {
 		server.Addr = cfg.HTTPSAddr
@@ -3,12 +3,16 @@ package auth
 import (
 	"crypto/subtle"
 	"errors"
+	"fmt"
 	"sync"
 	"time"
 
 	"lte_swd/backend/server/internal/util"
 )
 
+// SharedOperatorName identifies sessions opened with the shared operator password.
+const SharedOperatorName = "operator"
+
 var (
 	// ErrInvalidPassword informs caller that operator password mismatched.
 	ErrInvalidPassword = errors.New("invalid operator password")
@@ -16,55 +20,105 @@ var (
 	ErrInvalidToken = errors.New("invalid operator token")
 )
 
+type session struct {
+	operator  string
+	expiresAt time.Time
+}
+
 // OperatorAuth keeps short-lived operator sessions for R1.
 type OperatorAuth struct {
 	mu       sync.Mutex
 	password string
+	accounts map[string]string
 	ttl      time.Duration
-	tokens   map[string]time.Time
+	tokens   map[string]session
 }
 
 // NewOperatorAuth creates new auth manager.
 func NewOperatorAuth(password string, ttl time.Duration) *OperatorAuth {
 	return &OperatorAuth{
 		password: password,
+		accounts: make(map[string]string),
 		ttl:      ttl,
-		tokens:   make(map[string]time.Time),
+		tokens:   make(map[string]session),
+	}
+}
+
+// SetAccounts configures named operator accounts (name -> password).
+// SharedOperatorName is reserved so a named account never shares the
+// identity of shared-password sessions.
+func (a *OperatorAuth) SetAccounts(accounts map[string]string) error {
+	if _, ok := accounts[SharedOperatorName]; ok {
+		return fmt.Errorf("operator account name %q is reserved for shared password", SharedOperatorName)
+	}
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	a.accounts = make(map[string]string, len(accounts))
+	for name, password := range accounts {
+		a.accounts[name] = password
 	}
+	return nil
 }
 
 // Login validates password and returns bearer token.
 func (a *OperatorAuth) Login(password string, now time.Time) (string, time.Time, error) {
+	return a.LoginAs("", password, now)
+}
+
+// LoginAs validates named account password, or shared password when username
+// is empty, and returns bearer token bound to that operator identity.
+func (a *OperatorAuth) LoginAs(username, password string, now time.Time) (string, time.Time, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	if subtle.ConstantTimeCompare([]byte(password), []byte(a.password)) != 1 {
+	operator := SharedOperatorName
+	expected := a.password
+	if username != "" {
+		accountPassword, ok := a.accounts[username]
+		if !ok {
+			// Compare anyway so unknown names cost the same as wrong passwords.
+			subtle.ConstantTimeCompare([]byte(password), []byte(a.password))
+			return "", time.Time{}, ErrInvalidPassword
+		}
+		operator = username
+		expected = accountPassword
+	}
+
+	if subtle.ConstantTimeCompare([]byte(password), []byte(expected)) != 1 {
 		return "", time.Time{}, ErrInvalidPassword
 	}
 
 	token := util.RandomToken("op", 16)
 	expiresAt := now.Add(a.ttl)
-	a.tokens[token] = expiresAt
+	a.tokens[token] = session{operator: operator, expiresAt: expiresAt}
 	a.cleanupLocked(now)
 	return token, expiresAt, nil
 }
 
 // Validate checks token validity.
 func (a *OperatorAuth) Validate(token string, now time.Time) error {
+	_, err := a.Identify(token, now)
+	return err
+}
+
+// Identify checks token validity and returns operator name bound to it.
+func (a *OperatorAuth) Identify(token string, now time.Time) (string, error) {
 	a.mu.Lock()
 	defer a.mu.Unlock()
 
-	expiresAt, ok := a.tokens[token]
-	if !ok || now.After(expiresAt) {
+	current, ok := a.tokens[token]
+	if !ok || now.After(current.expiresAt) {
 		delete(a.tokens, token)
-		return ErrInvalidToken
+		return "", ErrInvalidToken
 	}
-	return nil
+	return current.operator, nil
 }
 
 func (a *OperatorAuth) cleanupLocked(now time.Time) {
-	for token, expiresAt := range a.tokens {
-		if now.After(expiresAt) {
+	for token, current := range a.tokens {
+		if now.After(current.expiresAt) {
 			delete(a.tokens, token)
 		}
 	}
@@ -24,3 +24,40 @@ func TestOperatorAuthLifecycle(t *testing.T) {
 		t.Fatalf("expected expired token")
 	}
 }
+
+func TestOperatorAuthNamedAccounts(t *testing.T) {
+	t.Parallel()
+
+	a := NewOperatorAuth("shared", time.Hour)
+	if err := a.SetAccounts(map[string]string{SharedOperatorName: "x"}); err == nil {
+		t.Fatalf("expected shared operator name to be reserved")
+	}
+	if err := a.SetAccounts(map[string]string{"alice": "a-secret"}); err != nil {
+		t.Fatalf("set accounts: %v", err)
+	}
+	now := time.Unix(1000, 0).UTC()
+
+	if _, _, err := a.LoginAs("alice", "shared", now); err == nil {
+		t.Fatalf("expected shared password to be rejected for named account")
+	}
+	if _, _, err := a.LoginAs("mallory", "shared", now); err == nil {
+		t.Fatalf("expected unknown account to be rejected")
+	}
+
+	token, _, err := a.LoginAs("alice", "a-secret", now)
+	if err != nil {
+		t.Fatalf("login alice: %v", err)
+	}
+	operator, err := a.Identify(token, now)
+	if err != nil || operator != "alice" {
+		t.Fatalf("unexpected identity %q err=%v", operator, err)
+	}
+
+	sharedToken, _, err := a.Login("shared", now)
+	if err != nil {
+		t.Fatalf("shared login: %v", err)
+	}
+	if operator, _ := a.Identify(sharedToken, now); operator != SharedOperatorName {
+		t.Fatalf("unexpected shared identity %q", operator)
+	}
+}
@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// SelectDestructive in a type list passed to Resolve means every destructive
+// type.
+const SelectDestructive = "destructive"
+
+// Info is static metadata a command type declares about itself.
+type Info struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	// Destructive types change target flash or memory; SelectDestructive in
+	// approval and interlock lists stands for all of them.
+	Destructive bool `json:"destructive"`
+	// Idempotent types can be repeated without changing the outcome.
+	Idempotent bool `json:"idempotent"`
+	// RequiresSignature types need a trusted artifact signature when
+	// REQUIRE_SIGNED_ARTIFACTS is on.
+	RequiresSignature bool `json:"requires_signature"`
+	// MinFirmware is the oldest probe firmware that implements the type.
+	MinFirmware string `json:"min_firmware,omitempty"`
+}
+
+// Artifacts gives result processors read access to stored artifacts.
+type Artifacts interface {
+	// OpenArtifact returns metadata and payload file; caller closes file.
+	OpenArtifact(artifactID string) (*model.Artifact, *os.File, error)
+	// OpenArtifactSegment returns one parsed segment as flat binary; caller closes file.
+	OpenArtifactSegment(artifactID string, index int) (*model.Artifact, *model.ArtifactSegment, *os.File, error)
+	ListArtifacts() []*model.Artifact
+}
+
+// Type is one command the server accepts and devices execute.
+type Type interface {
+	Info() Info
+	// ValidatePayload checks operator payload before command is queued.
+	ValidatePayload(payload json.RawMessage) error
+	// ProcessResult may annotate or downgrade device result before it is stored.
+	ProcessResult(artifacts Artifacts, command *model.Command, result *model.CommandResult)
+}
+
+// Registry holds command types by name.
+type Registry struct {
+	mu    sync.RWMutex
+	types map[string]Type
+}
+
+// NewRegistry creates empty registry.
+func NewRegistry() *Registry {
+	return &Registry{types: make(map[string]Type)}
+}
+
+// Register adds command type; names must be unique.
+func (r *Registry) Register(commandType Type) error {
+	name := commandType.Info().Name
+	if name == "" {
+		return fmt.Errorf("command type name is required")
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.types[name]; exists {
+		return fmt.Errorf("command type %s already registered", name)
+	}
+	r.types[name] = commandType
+	return nil
+}
+
+// Lookup returns registered type by name.
+func (r *Registry) Lookup(name string) (Type, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	commandType, ok := r.types[name]
+	return commandType, ok
+}
+
+// Infos returns metadata of all registered types ordered by name.
+func (r *Registry) Infos() []Info {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	out := make([]Info, 0, len(r.types))
+	for _, commandType := range r.types {
+		out = append(out, commandType.Info())
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Names returns registered type names in sorted order.
+func (r *Registry) Names() []string {
+	infos := r.Infos()
+	out := make([]string, 0, len(infos))
+	for _, info := range infos {
+		out = append(out, info.Name)
+	}
+	return out
+}
+
+// Check reports the first name that is not registered.
+func (r *Registry) Check(names []string) error {
+	for _, name := range names {
+		if _, ok := r.Lookup(name); !ok {
+			return fmt.Errorf("unsupported command type: %s", name)
+		}
+	}
+	return nil
+}
+
+// Resolve expands SelectDestructive into registered destructive types and
+// checks the remaining names. Result is sorted and free of duplicates.
+func (r *Registry) Resolve(names []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		if name != SelectDestructive {
+			if err := r.Check([]string{name}); err != nil {
+				return nil, err
+			}
+			seen[name] = struct{}{}
+			continue
+		}
+		for _, info := range r.Infos() {
+			if info.Destructive {
+				seen[info.Name] = struct{}{}
+			}
+		}
+	}
+
+	out := make([]string, 0, len(seen))
+	for name := range seen {
+		out = append(out, name)
+	}
+	sort.Strings(out)
+	return out, nil
+}
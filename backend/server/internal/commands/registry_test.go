@@ -0,0 +1,70 @@
+package commands
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestBuiltinRegistry(t *testing.T) {
+	t.Parallel()
+
+	registry := Builtin()
+	if got := len(registry.Names()); got != 8 {
+		t.Fatalf("builtin types = %d, want 8", got)
+	}
+	if err := registry.Register(&swdType{info: Info{Name: "swd_reset"}}); err == nil {
+		t.Fatal("expected duplicate registration error")
+	}
+	if err := registry.Check([]string{"swd_erase", "swd_unknown"}); err == nil {
+		t.Fatal("expected unknown type error")
+	}
+
+	resolved, err := registry.Resolve([]string{"swd_reset", SelectDestructive, "swd_erase"})
+	if err != nil {
+		t.Fatalf("resolve: %v", err)
+	}
+	if got, want := strings.Join(resolved, ","), "swd_erase,swd_program,swd_reset,swd_write_memory"; got != want {
+		t.Fatalf("resolved = %s, want %s", got, want)
+	}
+	if _, err := registry.Resolve([]string{SelectDestructive, "swd_unknown"}); err == nil {
+		t.Fatal("expected unknown type error from resolve")
+	}
+
+	program, _ := registry.Lookup("swd_program")
+	if info := program.Info(); !info.Destructive || info.Idempotent || !info.RequiresSignature {
+		t.Fatalf("unexpected swd_program info: %+v", info)
+	}
+	for payload, valid := range map[string]bool{
+		`{"artifact_id":"art_1","offset":0}`: true,
+		`{"address":"0x08000000"}`:           true,
+		`[]`:                                 false,
+		`{"artifact_id":""}`:                 false,
+		`{"length":-4}`:                      false,
+		`{"address":"flash"}`:                false,
+	} {
+		if err := program.ValidatePayload(json.RawMessage(payload)); (err == nil) != valid {
+			t.Fatalf("payload %s: err = %v, want valid=%v", payload, err, valid)
+		}
+	}
+}
+
+func TestFirmwareAtLeast(t *testing.T) {
+	t.Parallel()
+
+	cases := []struct {
+		version, minimum string
+		want             bool
+	}{
+		{"r1", "r1", true},
+		{"r1.2", "r1.10", false},
+		{"r2", "r1.9", true},
+		{"1.4.0", "1.4", true},
+		{"dev-build", "r2", true},
+	}
+	for _, tc := range cases {
+		if got := FirmwareAtLeast(tc.version, tc.minimum); got != tc.want {
+			t.Fatalf("FirmwareAtLeast(%q, %q) = %v, want %v", tc.version, tc.minimum, got, tc.want)
+		}
+	}
+}
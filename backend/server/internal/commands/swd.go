@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// baseFirmware is the first probe release; every built-in type ships in it.
+const baseFirmware = "r1"
+
+// swdType is a built-in SWD command. Payload checks cover the common fields
+// (artifact_id, segment, address, offset, length); other keys pass through to device.
+type swdType struct {
+	info    Info
+	process func(artifacts Artifacts, command *model.Command, result *model.CommandResult)
+}
+
+func (t *swdType) Info() Info { return t.info }
+
+func (t *swdType) ValidatePayload(payload json.RawMessage) error {
+	var fields map[string]interface{}
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return errors.New("invalid payload: must be a json object")
+	}
+
+	if raw, ok := fields["artifact_id"]; ok {
+		if id, isString := raw.(string); !isString || strings.TrimSpace(id) == "" {
+			return errors.New("invalid payload: artifact_id must be a non-empty string")
+		}
+	}
+
+	if raw, ok := fields["address"]; ok && !validAddress(raw) {
+		return errors.New("invalid payload: address must be a non-negative integer or hex string")
+	}
+	for _, key := range []string{"segment", "offset", "length"} {
+		if raw, ok := fields[key]; ok {
+			if value, isNumber := raw.(float64); !isNumber || value < 0 || value != float64(int64(value)) {
+				return fmt.Errorf("invalid payload: %s must be a non-negative integer", key)
+			}
+		}
+	}
+	return nil
+}
+
+func (t *swdType) ProcessResult(artifacts Artifacts, command *model.Command, result *model.CommandResult) {
+	if t.process != nil && result.Status == model.CommandSuccess {
+		t.process(artifacts, command, result)
+	}
+}
+
+func validAddress(raw interface{}) bool {
+	switch value := raw.(type) {
+	case float64:
+		return value >= 0 && value == float64(int64(value))
+	case string:
+		text := strings.ToLower(strings.TrimSpace(value))
+		if strings.HasPrefix(text, "0x") {
+			_, err := strconv.ParseUint(text[2:], 16, 64)
+			return err == nil
+		}
+		_, err := strconv.ParseUint(text, 10, 64)
+		return err == nil
+	}
+	return false
+}
+
+// Builtin returns registry with the probe's standard SWD command set.
+func Builtin() *Registry {
+	registry := NewRegistry()
+	for _, commandType := range builtinTypes() {
+		if err := registry.Register(commandType); err != nil {
+			panic(err)
+		}
+	}
+	return registry
+}
+
+func builtinTypes() []*swdType {
+	return []*swdType{
+		{info: Info{Name: "swd_connect", Description: "Attach to target over SWD and read IDCODE", Idempotent: true, MinFirmware: baseFirmware}},
+		{info: Info{Name: "swd_read_memory", Description: "Read target memory range", Idempotent: true, MinFirmware: baseFirmware}},
+		{info: Info{Name: "swd_write_memory", Description: "Write bytes to target memory", Destructive: true, Idempotent: false, MinFirmware: baseFirmware}},
+		{info: Info{Name: "swd_erase", Description: "Erase target flash", Destructive: true, Idempotent: false, MinFirmware: baseFirmware}},
+		{info: Info{Name: "swd_program", Description: "Program artifact into target flash", Destructive: true, Idempotent: false, RequiresSignature: true, MinFirmware: baseFirmware}},
+		{
+			info:    Info{Name: "swd_verify", Description: "Compare target flash with artifact", Idempotent: true, MinFirmware: baseFirmware},
+			process: verifyAgainstArtifact,
+		},
+		{
+			info:    Info{Name: "swd_copy_firmware", Description: "Read back target firmware image", Idempotent: true, MinFirmware: baseFirmware},
+			process: matchReadBack,
+		},
+		{info: Info{Name: "swd_reset", Description: "Reset target MCU", Idempotent: true, MinFirmware: baseFirmware}},
+	}
+}
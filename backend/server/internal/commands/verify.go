@@ -0,0 +1,177 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"hash/crc32"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// artifactRef is the part of swd_program/swd_verify payload naming the image.
+type artifactRef struct {
+	ArtifactID string `json:"artifact_id"`
+	Segment    *int   `json:"segment"`
+	Offset     int64  `json:"offset"`
+	Length     int64  `json:"length"`
+}
+
+// verifyAgainstArtifact recomputes swd_verify digest over the referenced
+// artifact; a mismatch turns device success into failure.
+func verifyAgainstArtifact(artifacts Artifacts, command *model.Command, result *model.CommandResult) {
+	var ref artifactRef
+	if err := json.Unmarshal(command.Payload, &ref); err != nil || ref.ArtifactID == "" {
+		return
+	}
+
+	method, reported := reportedDigest(result.Data)
+	if method == "" {
+		return
+	}
+
+	verification := &model.CommandVerification{
+		Method:     method,
+		ArtifactID: ref.ArtifactID,
+		Segment:    ref.Segment,
+		Reported:   reported,
+	}
+	result.Verification = verification
+
+	var (
+		file *os.File
+		size int64
+		err  error
+	)
+	if ref.Segment != nil {
+		var segment *model.ArtifactSegment
+		_, segment, file, err = artifacts.OpenArtifactSegment(ref.ArtifactID, *ref.Segment)
+		if err == nil {
+			size = segment.Size
+		}
+	} else {
+		var artifact *model.Artifact
+		artifact, file, err = artifacts.OpenArtifact(ref.ArtifactID)
+		if err == nil {
+			size = artifact.Size
+		}
+	}
+	if err != nil {
+		failVerification(result, fmt.Sprintf("artifact %s: %v", ref.ArtifactID, err))
+		return
+	}
+	defer file.Close()
+
+	offset := int64FromData(result.Data, "offset", ref.Offset)
+	length := int64FromData(result.Data, "length", ref.Length)
+	if offset < 0 || offset > size {
+		failVerification(result, fmt.Sprintf("offset %d outside artifact of %d bytes", offset, size))
+		return
+	}
+	if length <= 0 {
+		length = size - offset
+	} else if length > size-offset {
+		failVerification(result, fmt.Sprintf("length %d beyond artifact end", length))
+		return
+	}
+
+	var hasher hash.Hash = sha256.New()
+	if method == model.VerificationCRC32 {
+		hasher = crc32.NewIEEE()
+	}
+	if _, err := io.Copy(hasher, io.NewSectionReader(file, offset, length)); err != nil {
+		failVerification(result, fmt.Sprintf("read artifact %s: %v", ref.ArtifactID, err))
+		return
+	}
+
+	verification.Offset = offset
+	verification.Length = length
+	verification.Expected = hex.EncodeToString(hasher.Sum(nil))
+	verification.Match = verification.Expected == reported
+
+	if !verification.Match {
+		failVerification(result, fmt.Sprintf("%s expected %s, device reported %s", method, verification.Expected, reported))
+	}
+}
+
+func failVerification(result *model.CommandResult, detail string) {
+	result.Status = model.CommandFailed
+	result.Message = fmt.Sprintf("server verification failed: %s; device message: %s", detail, result.Message)
+}
+
+// matchReadBack matches swd_copy_firmware read-back against known artifacts.
+func matchReadBack(artifacts Artifacts, command *model.Command, result *model.CommandResult) {
+	method, reported := reportedDigest(result.Data)
+	if method == "" && command.ResultBlob != nil && command.ResultBlob.Complete {
+		method, reported = model.VerificationSHA256, command.ResultBlob.SHA256
+	}
+	if method == "" {
+		return
+	}
+
+	verification := &model.CommandVerification{Method: method, Reported: reported}
+	result.Verification = verification
+	for _, artifact := range artifacts.ListArtifacts() {
+		expected := artifact.PayloadSHA256
+		if method == model.VerificationCRC32 {
+			expected = artifact.PayloadCRC32
+		}
+		if expected == reported {
+			verification.Match = true
+			verification.MatchedArtifactID = artifact.ArtifactID
+			verification.Expected = expected
+			return
+		}
+		// Flash read-back of a HEX/ELF image equals one of its segments, not the file.
+		for _, segment := range artifact.Segments {
+			expected := segment.SHA256
+			if method == model.VerificationCRC32 {
+				expected = segment.CRC32
+			}
+			if expected == reported {
+				index := segment.Index
+				verification.Match = true
+				verification.MatchedArtifactID = artifact.ArtifactID
+				verification.MatchedSegment = &index
+				verification.Expected = expected
+				return
+			}
+		}
+	}
+}
+
+// reportedDigest extracts sha256 (preferred) or crc32 from result data.
+func reportedDigest(data map[string]interface{}) (string, string) {
+	for _, key := range []string{"sha256", "image_sha256"} {
+		if raw, ok := data[key].(string); ok && strings.TrimSpace(raw) != "" {
+			return model.VerificationSHA256, strings.ToLower(strings.TrimSpace(raw))
+		}
+	}
+
+	switch value := data["crc32"].(type) {
+	case string:
+		raw := strings.TrimPrefix(strings.ToLower(strings.TrimSpace(value)), "0x")
+		if parsed, err := strconv.ParseUint(raw, 16, 32); err == nil {
+			return model.VerificationCRC32, fmt.Sprintf("%08x", parsed)
+		}
+	case float64:
+		if value >= 0 && value <= math.MaxUint32 && value == math.Trunc(value) {
+			return model.VerificationCRC32, fmt.Sprintf("%08x", uint32(value))
+		}
+	}
+	return "", ""
+}
+
+func int64FromData(data map[string]interface{}, key string, def int64) int64 {
+	if value, ok := data[key].(float64); ok && value == math.Trunc(value) {
+		return int64(value)
+	}
+	return def
+}
@@ -0,0 +1,44 @@
+package commands
+
+import "strconv"
+
+// FirmwareAtLeast compares numeric components of probe firmware versions such
+// as "r1", "r1.2" or "1.4.0". Versions without digits are not comparable and
+// are treated as satisfying the minimum.
+func FirmwareAtLeast(version, minimum string) bool {
+	have, want := versionNumbers(version), versionNumbers(minimum)
+	if len(have) == 0 || len(want) == 0 {
+		return true
+	}
+
+	for i := range want {
+		var current int
+		if i < len(have) {
+			current = have[i]
+		}
+		if current != want[i] {
+			return current > want[i]
+		}
+	}
+	return true
+}
+
+func versionNumbers(version string) []int {
+	var out []int
+	start := -1
+	for i := 0; i <= len(version); i++ {
+		isDigit := i < len(version) && version[i] >= '0' && version[i] <= '9'
+		switch {
+		case isDigit && start < 0:
+			start = i
+		case !isDigit && start >= 0:
+			value, err := strconv.Atoi(version[start:i])
+			if err != nil {
+				return nil
+			}
+			out = append(out, value)
+			start = -1
+		}
+	}
+	return out
+}
@@ -1,11 +1,16 @@
 package config
 
 import (
+	"crypto/ed25519"
+	"encoding/base64"
 	"fmt"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+
+	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/commands"
 )
 
 // Config keeps runtime settings for backend process.
@@ -27,6 +32,38 @@ type Config struct {
 	LoginRatePerMinute int
 	LoginBurst         int
 	TrustProxyHeaders  bool
+	IdempotencyTTL     time.Duration
+	MaxPullWait        time.Duration
+	CommandStallAfter  time.Duration
+	// OperatorAccounts maps named operator to password; shared password stays valid.
+	OperatorAccounts map[string]string
+	// ApprovalRequiredTypes lists command types that need a second operator;
+	// main resolves commands.SelectDestructive against the registry.
+	ApprovalRequiredTypes []string
+	// Interlocks holds preflight rules checked against telemetry at dispatch.
+	Interlocks Interlocks
+	// ArtifactSigningKeys maps release key id to trusted Ed25519 public key.
+	ArtifactSigningKeys map[string]ed25519.PublicKey
+	// RequireSignedArtifacts refuses command types declaring RequiresSignature
+	// (swd_program) for artifacts without trusted signature.
+	RequireSignedArtifacts bool
+	// ArtifactQuotaBytes bounds stored artifact bytes; 0 is unlimited.
+	ArtifactQuotaBytes int64
+	// ArtifactGCMinAge is how long an unreferenced artifact is kept after last use.
+	ArtifactGCMinAge time.Duration
+	// ArtifactGCInterval runs garbage collection periodically; 0 leaves it on demand only.
+	ArtifactGCInterval time.Duration
+}
+
+// Interlocks configures telemetry preflight for risky commands. Zero values disable a rule.
+type Interlocks struct {
+	// Types defaults to every destructive command type.
+	Types           []string
+	MinBatteryMV    int
+	MinSupplyMV     int
+	MinRSSIDBM      int
+	BlockRoaming    bool
+	MaxTelemetryAge time.Duration
 }
 
 // Load reads environment variables and applies defaults for R1.
@@ -49,6 +86,42 @@ func Load() (Config, error) {
 		LoginRatePerMinute: getEnvInt("LOGIN_RATE_PER_MINUTE", 20),
 		LoginBurst:         getEnvInt("LOGIN_BURST", 5),
 		TrustProxyHeaders:  getEnvBool("TRUST_PROXY_HEADERS", false),
+		IdempotencyTTL:     getEnvDuration("IDEMPOTENCY_TTL", 24*time.Hour),
+		MaxPullWait:        getEnvDuration("MAX_PULL_WAIT", 25*time.Second),
+		CommandStallAfter:  getEnvDuration("COMMAND_STALL_AFTER", 2*time.Minute),
+	}
+
+	accounts, err := parseAccounts(getEnv("OPERATOR_ACCOUNTS", ""))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.OperatorAccounts = accounts
+	cfg.ApprovalRequiredTypes = getEnvList("APPROVAL_REQUIRED_TYPES")
+	signingKeys, err := parseSigningKeys(getEnv("ARTIFACT_SIGNING_KEYS", ""))
+	if err != nil {
+		return Config{}, err
+	}
+	cfg.ArtifactSigningKeys = signingKeys
+	cfg.RequireSignedArtifacts = getEnvBool("REQUIRE_SIGNED_ARTIFACTS", false)
+	if cfg.RequireSignedArtifacts && len(cfg.ArtifactSigningKeys) == 0 {
+		return Config{}, fmt.Errorf("REQUIRE_SIGNED_ARTIFACTS needs at least one ARTIFACT_SIGNING_KEYS entry")
+	}
+	cfg.ArtifactQuotaBytes = int64(getEnvInt("ARTIFACT_QUOTA_BYTES", 0))
+	cfg.ArtifactGCMinAge = getEnvDuration("ARTIFACT_GC_MIN_AGE", 30*24*time.Hour)
+	cfg.ArtifactGCInterval = getEnvDuration("ARTIFACT_GC_INTERVAL", 0)
+	cfg.Interlocks = Interlocks{
+		Types:           getEnvList("INTERLOCK_TYPES"),
+		MinBatteryMV:    getEnvInt("INTERLOCK_MIN_BATTERY_MV", 0),
+		MinSupplyMV:     getEnvInt("INTERLOCK_MIN_SUPPLY_MV", 0),
+		MinRSSIDBM:      getEnvInt("INTERLOCK_MIN_RSSI_DBM", 0),
+		BlockRoaming:    getEnvBool("INTERLOCK_BLOCK_ROAMING", false),
+		MaxTelemetryAge: getEnvDuration("INTERLOCK_MAX_TELEMETRY_AGE", 10*time.Minute),
+	}
+	if os.Getenv("INTERLOCK_TYPES") == "" {
+		cfg.Interlocks.Types = []string{commands.SelectDestructive}
+	}
+	if cfg.Interlocks.MinRSSIDBM > 0 {
+		return Config{}, fmt.Errorf("interlock min rssi must be negative dBm or 0 to disable")
 	}
 
 	if cfg.FleetLimit <= 0 {
@@ -69,6 +142,21 @@ func Load() (Config, error) {
 	if cfg.APIRatePerMinute <= 0 || cfg.LoginRatePerMinute <= 0 || cfg.LoginBurst <= 0 {
 		return Config{}, fmt.Errorf("rate limits must be positive")
 	}
+	if cfg.ArtifactQuotaBytes < 0 {
+		return Config{}, fmt.Errorf("artifact quota must not be negative")
+	}
+	if cfg.ArtifactGCMinAge <= 0 || cfg.ArtifactGCInterval < 0 {
+		return Config{}, fmt.Errorf("artifact gc min age must be positive and interval not negative")
+	}
+	if cfg.IdempotencyTTL <= 0 {
+		return Config{}, fmt.Errorf("idempotency ttl must be positive")
+	}
+	if cfg.MaxPullWait < 0 || cfg.MaxPullWait >= 60*time.Second {
+		return Config{}, fmt.Errorf("max pull wait must be within [0s, 60s) to fit http write timeout")
+	}
+	if cfg.CommandStallAfter <= 0 {
+		return Config{}, fmt.Errorf("command stall threshold must be positive")
+	}
 	if (cfg.HTTPSAddr != "" || cfg.TLSCertFile != "" || cfg.TLSKeyFile != "") &&
 		(cfg.HTTPSAddr == "" || cfg.TLSCertFile == "" || cfg.TLSKeyFile == "") {
 		return Config{}, fmt.Errorf("https requires HTTPS_ADDR, TLS_CERT_FILE and TLS_KEY_FILE together")
@@ -77,6 +165,60 @@ func Load() (Config, error) {
 	return cfg, nil
 }
 
+// parseSigningKeys reads "key_id:base64(raw 32-byte Ed25519 public key),...".
+func parseSigningKeys(raw string) (map[string]ed25519.PublicKey, error) {
+	keys := make(map[string]ed25519.PublicKey)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		keyID, encoded, ok := strings.Cut(entry, ":")
+		keyID = strings.TrimSpace(keyID)
+		if !ok || keyID == "" {
+			return nil, fmt.Errorf("artifact signing keys must be key_id:base64 pairs")
+		}
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(encoded))
+		if err != nil || len(key) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("artifact signing key %q must be base64 of %d-byte Ed25519 public key", keyID, ed25519.PublicKeySize)
+		}
+		keys[keyID] = ed25519.PublicKey(key)
+	}
+	return keys, nil
+}
+
+// parseAccounts reads "name:password,name2:password2".
+func parseAccounts(raw string) (map[string]string, error) {
+	accounts := make(map[string]string)
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		name, password, ok := strings.Cut(entry, ":")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || password == "" {
+			return nil, fmt.Errorf("operator accounts must be name:password pairs")
+		}
+		if name == auth.SharedOperatorName {
+			return nil, fmt.Errorf("operator account name %q is reserved for shared password", name)
+		}
+		accounts[name] = password
+	}
+	return accounts, nil
+}
+
+func getEnvList(key string) []string {
+	var out []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			out = append(out, item)
+		}
+	}
+	return out
+}
+
 func getEnv(key, def string) string {
 	v := os.Getenv(key)
 	if v == "" {
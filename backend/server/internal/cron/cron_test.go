@@ -0,0 +1,52 @@
+package cron
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	t.Parallel()
+
+	base := time.Date(2026, time.January, 30, 23, 59, 30, 0, time.UTC)
+	cases := []struct {
+		spec string
+		want time.Time
+	}{
+		{spec: "@daily", want: time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "*/15 * * * *", want: time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)},
+		{spec: "30 2 * * 1-5", want: time.Date(2026, time.February, 2, 2, 30, 0, 0, time.UTC)},
+		{spec: "0 3 31 * *", want: time.Date(2026, time.January, 31, 3, 0, 0, 0, time.UTC)},
+		{spec: "0 0 29 2 *", want: time.Date(2028, time.February, 29, 0, 0, 0, 0, time.UTC)},
+		{spec: "0 12 1 * 7", want: time.Date(2026, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either may match.
+		{spec: "0 12 31 * 1", want: time.Date(2026, time.January, 31, 12, 0, 0, 0, time.UTC)},
+		// A "*" step leaves its day field unrestricted, so the other one decides.
+		{spec: "0 12 1 * */1", want: time.Date(2026, time.February, 1, 12, 0, 0, 0, time.UTC)},
+		{spec: "0 12 */1 * 1", want: time.Date(2026, time.February, 2, 12, 0, 0, 0, time.UTC)},
+	}
+
+	for _, tc := range cases {
+		schedule, err := Parse(tc.spec)
+		if err != nil {
+			t.Fatalf("parse %q: %v", tc.spec, err)
+		}
+		if got := schedule.Next(base); !got.Equal(tc.want) {
+			t.Fatalf("%q: next = %s, want %s", tc.spec, got, tc.want)
+		}
+	}
+
+	never, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatalf("parse impossible date: %v", err)
+	}
+	if got := never.Next(base); !got.IsZero() {
+		t.Fatalf("expected no activation, got %s", got)
+	}
+
+	for _, bad := range []string{"* * *", "60 * * * *", "*/0 * * * *", "5-1 * * * *"} {
+		if _, err := Parse(bad); err == nil {
+			t.Fatalf("expected parse error for %q", bad)
+		}
+	}
+}
@@ -0,0 +1,178 @@
+// Package delta builds block-level binary patches between two firmware images
+// so probes holding the base image download only what changed.
+//
+// Patch layout: 8-byte magic, uvarint base size, uvarint target size, base
+// SHA-256, target SHA-256, then operations until opEnd. opCopy carries uvarint
+// base offset and length; opAdd carries uvarint length and literal bytes.
+package delta
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// BlockSize is the shortest base run worth a copy operation.
+const BlockSize = 32
+
+const (
+	opEnd  byte = 0
+	opCopy byte = 1
+	opAdd  byte = 2
+
+	hashBase uint64 = 0x100000001b3
+)
+
+var magic = []byte("LSWDDLT1")
+
+var (
+	// ErrCorrupt indicates patch is malformed or truncated.
+	ErrCorrupt = errors.New("delta: corrupt patch")
+	// ErrBaseMismatch indicates patch was built against different base bytes.
+	ErrBaseMismatch = errors.New("delta: base image does not match patch")
+)
+
+// Diff returns patch turning base into target. Runs of at least BlockSize
+// bytes found anywhere in base become copies, so code shifted by an insert
+// still matches; everything else is sent literally.
+func Diff(base, target []byte) []byte {
+	baseSum := sha256.Sum256(base)
+	targetSum := sha256.Sum256(target)
+
+	out := append([]byte(nil), magic...)
+	out = binary.AppendUvarint(out, uint64(len(base)))
+	out = binary.AppendUvarint(out, uint64(len(target)))
+	out = append(out, baseSum[:]...)
+	out = append(out, targetSum[:]...)
+
+	index := indexBlocks(base)
+	var top uint64 = 1
+	for i := 1; i < BlockSize; i++ {
+		top *= hashBase
+	}
+
+	literal, pos := 0, 0
+	var h uint64
+	if len(target) >= BlockSize {
+		h = blockHash(target[:BlockSize])
+	}
+	for pos+BlockSize <= len(target) {
+		if offset, ok := index[h]; ok && bytes.Equal(base[offset:offset+BlockSize], target[pos:pos+BlockSize]) {
+			for offset > 0 && pos > literal && base[offset-1] == target[pos-1] {
+				offset--
+				pos--
+			}
+			length := 0
+			for offset+length < len(base) && pos+length < len(target) && base[offset+length] == target[pos+length] {
+				length++
+			}
+			out = appendAdd(out, target[literal:pos])
+			out = append(out, opCopy)
+			out = binary.AppendUvarint(out, uint64(offset))
+			out = binary.AppendUvarint(out, uint64(length))
+			pos += length
+			literal = pos
+			if pos+BlockSize <= len(target) {
+				h = blockHash(target[pos : pos+BlockSize])
+			}
+			continue
+		}
+		if pos+BlockSize < len(target) {
+			h = (h-uint64(target[pos])*top)*hashBase + uint64(target[pos+BlockSize])
+		}
+		pos++
+	}
+	out = appendAdd(out, target[literal:])
+	return append(out, opEnd)
+}
+
+// Apply rebuilds target from base and patch, checking both digests.
+func Apply(base, patch []byte) ([]byte, error) {
+	reader := bytes.NewReader(patch)
+	head := make([]byte, len(magic))
+	if _, err := io.ReadFull(reader, head); err != nil || !bytes.Equal(head, magic) {
+		return nil, ErrCorrupt
+	}
+	baseSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	targetSize, err := binary.ReadUvarint(reader)
+	if err != nil {
+		return nil, ErrCorrupt
+	}
+	var baseSum, targetSum [sha256.Size]byte
+	if _, err := io.ReadFull(reader, baseSum[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if _, err := io.ReadFull(reader, targetSum[:]); err != nil {
+		return nil, ErrCorrupt
+	}
+	if uint64(len(base)) != baseSize || sha256.Sum256(base) != baseSum {
+		return nil, ErrBaseMismatch
+	}
+
+	out := make([]byte, 0, len(base))
+	for {
+		op, err := reader.ReadByte()
+		if err != nil {
+			return nil, ErrCorrupt
+		}
+		switch op {
+		case opEnd:
+			if uint64(len(out)) != targetSize || sha256.Sum256(out) != targetSum {
+				return nil, fmt.Errorf("%w: target digest mismatch", ErrCorrupt)
+			}
+			return out, nil
+		case opCopy:
+			offset, err1 := binary.ReadUvarint(reader)
+			length, err2 := binary.ReadUvarint(reader)
+			if err1 != nil || err2 != nil || offset > baseSize || length > baseSize-offset || uint64(len(out))+length > targetSize {
+				return nil, ErrCorrupt
+			}
+			out = append(out, base[offset:offset+length]...)
+		case opAdd:
+			length, err := binary.ReadUvarint(reader)
+			if err != nil || length > uint64(reader.Len()) || uint64(len(out))+length > targetSize {
+				return nil, ErrCorrupt
+			}
+			start := len(patch) - reader.Len()
+			out = append(out, patch[start:start+int(length)]...)
+			_, _ = reader.Seek(int64(length), io.SeekCurrent)
+		default:
+			return nil, ErrCorrupt
+		}
+	}
+}
+
+// indexBlocks maps hash of every aligned base block to its first offset.
+func indexBlocks(base []byte) map[uint64]int {
+	index := make(map[uint64]int, len(base)/BlockSize)
+	for offset := 0; offset+BlockSize <= len(base); offset += BlockSize {
+		h := blockHash(base[offset : offset+BlockSize])
+		if _, ok := index[h]; !ok {
+			index[h] = offset
+		}
+	}
+	return index
+}
+
+func blockHash(block []byte) uint64 {
+	var h uint64
+	for _, b := range block {
+		h = h*hashBase + uint64(b)
+	}
+	return h
+}
+
+func appendAdd(out, literal []byte) []byte {
+	if len(literal) == 0 {
+		return out
+	}
+	out = append(out, opAdd)
+	out = binary.AppendUvarint(out, uint64(len(literal)))
+	return append(out, literal...)
+}
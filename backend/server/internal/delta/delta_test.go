@@ -0,0 +1,70 @@
+package delta
+
+import (
+	"bytes"
+	"errors"
+	"math/rand"
+	"testing"
+)
+
+func firmwareImage(size int, seed int64) []byte {
+	image := make([]byte, size)
+	rand.New(rand.NewSource(seed)).Read(image)
+	return image
+}
+
+func TestDiffApplyRoundTrip(t *testing.T) {
+	t.Parallel()
+
+	base := firmwareImage(64*1024, 1)
+
+	patched := append([]byte(nil), base...)
+	copy(patched[1000:], []byte("fixed bug"))
+	inserted := append(append(append([]byte(nil), base[:5000]...), []byte("new feature code")...), base[5000:]...)
+
+	cases := map[string][]byte{
+		"identical": base,
+		"patched":   patched,
+		"inserted":  inserted,
+		"truncated": base[:40000],
+		"unrelated": firmwareImage(1000, 2),
+		"empty":     nil,
+	}
+	for name, target := range cases {
+		patch := Diff(base, target)
+		got, err := Apply(base, patch)
+		if err != nil {
+			t.Fatalf("%s: apply: %v", name, err)
+		}
+		if !bytes.Equal(got, target) {
+			t.Fatalf("%s: round trip mismatch", name)
+		}
+		if name == "patched" || name == "inserted" {
+			if len(patch) > 200 {
+				t.Fatalf("%s: patch is %d bytes, want small", name, len(patch))
+			}
+		}
+	}
+}
+
+func TestApplyRejectsWrongBaseAndCorruptPatch(t *testing.T) {
+	t.Parallel()
+
+	base := firmwareImage(4096, 3)
+	target := append(append([]byte(nil), base[:2000]...), firmwareImage(100, 4)...)
+	patch := Diff(base, target)
+
+	other := append([]byte(nil), base...)
+	other[0] ^= 0xff
+	if _, err := Apply(other, patch); !errors.Is(err, ErrBaseMismatch) {
+		t.Fatalf("expected base mismatch, got %v", err)
+	}
+	if _, err := Apply(base, patch[:len(patch)-10]); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected corrupt for truncated patch, got %v", err)
+	}
+	broken := append([]byte(nil), patch...)
+	broken[len(broken)-5] ^= 0xff
+	if _, err := Apply(base, broken); !errors.Is(err, ErrCorrupt) {
+		t.Fatalf("expected corrupt for altered literal, got %v", err)
+	}
+}
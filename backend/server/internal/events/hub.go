@@ -0,0 +1,158 @@
+package events
+
+import (
+	"sync"
+	"time"
+)
+
+// Event types published to operator live stream.
+const (
+	TypeDeviceRegistered = "device.registered"
+	TypeDeviceStatus     = "device.status"
+	TypeDeviceTelemetry  = "device.telemetry"
+	TypeDeviceLocation   = "device.location"
+	TypeCommandStatus    = "command.status"
+)
+
+const subscriberBuffer = 64
+
+// Event is one entry of operator live stream.
+type Event struct {
+	ID        uint64      `json:"id"`
+	Type      string      `json:"type"`
+	DeviceID  string      `json:"device_id,omitempty"`
+	Timestamp time.Time   `json:"timestamp"`
+	Data      interface{} `json:"data,omitempty"`
+}
+
+// Subscription delivers events published after Subscribe.
+// C is closed when hub shuts down or subscriber falls too far behind.
+type Subscription struct {
+	// Backlog holds retained events newer than requested Last-Event-ID.
+	Backlog []Event
+	// Resumed reports whether Backlog covers every event after Last-Event-ID.
+	Resumed bool
+	C       <-chan Event
+
+	hub *Hub
+	ch  chan Event
+}
+
+// Close detaches subscription from hub.
+func (s *Subscription) Close() {
+	s.hub.unsubscribe(s.ch)
+}
+
+// Hub fans out events to subscribers and keeps bounded backlog for resume.
+type Hub struct {
+	mu          sync.Mutex
+	nextID      uint64
+	backlog     []Event
+	backlogSize int
+	subscribers map[chan Event]struct{}
+	closed      bool
+}
+
+// NewHub creates event hub. IDs are seeded from wall clock so that they keep
+// growing across restarts and stale Last-Event-ID values are detected.
+func NewHub(backlogSize int, now time.Time) *Hub {
+	if backlogSize <= 0 {
+		backlogSize = 1024
+	}
+	return &Hub{
+		nextID:      uint64(now.UnixMilli()) * 1000,
+		backlogSize: backlogSize,
+		subscribers: make(map[chan Event]struct{}),
+	}
+}
+
+// Publish assigns event id and delivers it without blocking.
+func (h *Hub) Publish(eventType, deviceID string, data interface{}, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+
+	h.nextID++
+	event := Event{
+		ID:        h.nextID,
+		Type:      eventType,
+		DeviceID:  deviceID,
+		Timestamp: now,
+		Data:      data,
+	}
+
+	h.backlog = append(h.backlog, event)
+	if len(h.backlog) > h.backlogSize {
+		h.backlog = append([]Event(nil), h.backlog[len(h.backlog)-h.backlogSize:]...)
+	}
+
+	for ch := range h.subscribers {
+		select {
+		case ch <- event:
+		default:
+			// Slow consumer: drop it, client resumes from backlog on reconnect.
+			delete(h.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Subscribe registers new subscriber. When lastEventID is zero no backlog is
+// replayed and Resumed is false.
+func (h *Hub) Subscribe(lastEventID uint64) *Subscription {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	ch := make(chan Event, subscriberBuffer)
+	sub := &Subscription{C: ch, hub: h, ch: ch}
+	if h.closed {
+		close(ch)
+		return sub
+	}
+	h.subscribers[ch] = struct{}{}
+
+	if lastEventID == 0 {
+		return sub
+	}
+
+	oldest := h.nextID + 1
+	if len(h.backlog) > 0 {
+		oldest = h.backlog[0].ID
+	}
+	sub.Resumed = lastEventID+1 >= oldest && lastEventID <= h.nextID
+
+	for _, event := range h.backlog {
+		if event.ID > lastEventID {
+			sub.Backlog = append(sub.Backlog, event)
+		}
+	}
+	return sub
+}
+
+// Close ends all subscriptions; used on graceful shutdown.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		return
+	}
+	h.closed = true
+	for ch := range h.subscribers {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
+
+func (h *Hub) unsubscribe(ch chan Event) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if _, ok := h.subscribers[ch]; ok {
+		delete(h.subscribers, ch)
+		close(ch)
+	}
+}
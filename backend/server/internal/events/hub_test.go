@@ -0,0 +1,44 @@
+package events
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHubResumeFromLastEventID(t *testing.T) {
+	t.Parallel()
+
+	now := time.Unix(500, 0).UTC()
+	hub := NewHub(2, now)
+
+	live := hub.Subscribe(0)
+	defer live.Close()
+
+	hub.Publish(TypeDeviceStatus, "dev-1", nil, now)
+	first := <-live.C
+	hub.Publish(TypeDeviceTelemetry, "dev-1", nil, now)
+	hub.Publish(TypeDeviceLocation, "dev-1", nil, now)
+
+	resumed := hub.Subscribe(first.ID)
+	defer resumed.Close()
+	if !resumed.Resumed || len(resumed.Backlog) != 2 {
+		t.Fatalf("expected resume with 2 events, got resumed=%v backlog=%d", resumed.Resumed, len(resumed.Backlog))
+	}
+	if resumed.Backlog[0].Type != TypeDeviceTelemetry {
+		t.Fatalf("unexpected first backlog event: %s", resumed.Backlog[0].Type)
+	}
+
+	hub.Publish(TypeCommandStatus, "dev-1", nil, now)
+	stale := hub.Subscribe(first.ID)
+	defer stale.Close()
+	if stale.Resumed {
+		t.Fatalf("expected stale resume once backlog rolled over")
+	}
+
+	hub.Close()
+	if _, ok := <-resumed.C; !ok {
+		t.Fatalf("expected buffered live event before close")
+	}
+	for range resumed.C {
+	}
+}
@@ -0,0 +1,46 @@
+package firmware
+
+import (
+	"debug/elf"
+	"fmt"
+	"io"
+)
+
+const elfMagic = "\x7fELF"
+
+// parseELF takes PT_LOAD program headers with file data and places them at
+// their physical (load) address, which is where flash images live when
+// .data is copied to RAM at startup.
+func parseELF(r io.ReaderAt, size int64) (*Image, error) {
+	file, err := elf.NewFile(r)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	entry := file.Entry
+	image := &Image{Format: FormatELF, Entry: &entry}
+	var mem memoryMap
+	for _, prog := range file.Progs {
+		if prog.Type != elf.PT_LOAD || prog.Filesz == 0 {
+			continue
+		}
+		if prog.Filesz > uint64(size) {
+			return nil, fmt.Errorf("segment at 0x%x is larger than file", prog.Paddr)
+		}
+		data := make([]byte, prog.Filesz)
+		if _, err := io.ReadFull(prog.Open(), data); err != nil {
+			return nil, fmt.Errorf("read segment at 0x%x: %w", prog.Paddr, err)
+		}
+		if err := mem.add(prog.Paddr, data); err != nil {
+			return nil, err
+		}
+	}
+
+	segments, err := mem.segments()
+	if err != nil {
+		return nil, err
+	}
+	image.Segments = segments
+	return image, nil
+}
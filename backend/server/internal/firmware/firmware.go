@@ -0,0 +1,159 @@
+// Package firmware parses build outputs (Intel HEX, ELF, S-record, UF2) into
+// flat memory segments the probe can program at their load addresses.
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// MaxSegments bounds how many disjoint memory regions one image may carry.
+const MaxSegments = 64
+
+// Format names artifact encoding.
+type Format string
+
+const (
+	// FormatBinary is raw image without address information.
+	FormatBinary Format = "bin"
+	// FormatIntelHex is Intel HEX text.
+	FormatIntelHex Format = "ihex"
+	// FormatELF is ELF executable; PT_LOAD segments are placed at their physical address.
+	FormatELF Format = "elf"
+	// FormatSREC is Motorola S-record text.
+	FormatSREC Format = "srec"
+	// FormatUF2 is USB Flashing Format with 512-byte blocks.
+	FormatUF2 Format = "uf2"
+)
+
+// Segment is contiguous memory region of the image.
+type Segment struct {
+	Address uint64
+	Data    []byte
+}
+
+// Image is parsed artifact. Raw binaries have no segments.
+type Image struct {
+	Format   Format
+	Entry    *uint64
+	Segments []Segment
+}
+
+// Detect guesses format from file name and first bytes of content. Binary
+// magic wins over extension; text formats are also sniffed when the name
+// carries no known extension.
+func Detect(name string, head []byte) Format {
+	switch {
+	case bytes.HasPrefix(head, []byte(elfMagic)):
+		return FormatELF
+	case len(head) >= 8 && binary.LittleEndian.Uint32(head) == uf2Magic0 && binary.LittleEndian.Uint32(head[4:]) == uf2Magic1:
+		return FormatUF2
+	}
+
+	switch strings.ToLower(filepath.Ext(strings.TrimSpace(name))) {
+	case ".hex", ".ihex", ".ihx":
+		return FormatIntelHex
+	case ".srec", ".s19", ".s28", ".s37", ".mot":
+		return FormatSREC
+	case ".elf", ".axf":
+		return FormatELF
+	case ".uf2":
+		return FormatUF2
+	}
+
+	line := bytes.TrimLeft(head, " \t\r\n")
+	if end := bytes.IndexAny(line, "\r\n"); end >= 0 {
+		line = line[:end]
+	}
+	switch {
+	case len(line) >= 11 && line[0] == ':' && isHexText(line[1:]):
+		return FormatIntelHex
+	case len(line) >= 10 && line[0] == 'S' && line[1] >= '0' && line[1] <= '9' && isHexText(line[2:]):
+		return FormatSREC
+	}
+	return FormatBinary
+}
+
+// Parse decodes size bytes of r as format.
+func Parse(format Format, r io.ReaderAt, size int64) (*Image, error) {
+	var (
+		image *Image
+		err   error
+	)
+	switch format {
+	case FormatBinary:
+		return &Image{Format: FormatBinary}, nil
+	case FormatIntelHex:
+		image, err = parseIntelHex(io.NewSectionReader(r, 0, size))
+	case FormatSREC:
+		image, err = parseSREC(io.NewSectionReader(r, 0, size))
+	case FormatELF:
+		image, err = parseELF(r, size)
+	case FormatUF2:
+		image, err = parseUF2(io.NewSectionReader(r, 0, size), size)
+	default:
+		return nil, fmt.Errorf("unsupported firmware format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", format, err)
+	}
+	if len(image.Segments) == 0 {
+		return nil, fmt.Errorf("%s: image contains no loadable data", format)
+	}
+	return image, nil
+}
+
+func isHexText(text []byte) bool {
+	for _, c := range text {
+		if !(c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// memoryMap collects data records in any order and folds them into segments.
+type memoryMap struct {
+	chunks []Segment
+}
+
+func (m *memoryMap) add(address uint64, data []byte) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if address+uint64(len(data)) < address {
+		return fmt.Errorf("data at 0x%x overflows address space", address)
+	}
+	m.chunks = append(m.chunks, Segment{Address: address, Data: append([]byte(nil), data...)})
+	return nil
+}
+
+func (m *memoryMap) segments() ([]Segment, error) {
+	sort.SliceStable(m.chunks, func(i, j int) bool { return m.chunks[i].Address < m.chunks[j].Address })
+
+	var out []Segment
+	for _, chunk := range m.chunks {
+		if len(out) > 0 {
+			last := &out[len(out)-1]
+			end := last.Address + uint64(len(last.Data))
+			if chunk.Address < end {
+				return nil, fmt.Errorf("overlapping data at 0x%x", chunk.Address)
+			}
+			if chunk.Address == end {
+				last.Data = append(last.Data, chunk.Data...)
+				continue
+			}
+		}
+		if len(out) == MaxSegments {
+			return nil, fmt.Errorf("image has more than %d segments", MaxSegments)
+		}
+		out = append(out, chunk)
+	}
+	m.chunks = nil
+	return out, nil
+}
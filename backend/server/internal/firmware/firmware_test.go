@@ -0,0 +1,138 @@
+package firmware
+
+import (
+	"bytes"
+	"encoding/binary"
+	"strings"
+	"testing"
+)
+
+func parseString(t *testing.T, name, content string) *Image {
+	t.Helper()
+	format := Detect(name, []byte(content))
+	image, err := Parse(format, strings.NewReader(content), int64(len(content)))
+	if err != nil {
+		t.Fatalf("parse %s: %v", name, err)
+	}
+	return image
+}
+
+func TestParseIntelHex(t *testing.T) {
+	t.Parallel()
+
+	content := strings.Join([]string{
+		":020000040800F2",
+		":0400000001020304F2",
+		":0400040005060708DE",
+		":040010001122334442",
+		":0400000508000101ED",
+		":00000001FF",
+	}, "\r\n")
+	image := parseString(t, "app.hex", content)
+
+	if image.Format != FormatIntelHex || image.Entry == nil || *image.Entry != 0x08000101 {
+		t.Fatalf("unexpected image header: %+v", image)
+	}
+	if len(image.Segments) != 2 {
+		t.Fatalf("expected two segments, got %d", len(image.Segments))
+	}
+	first, second := image.Segments[0], image.Segments[1]
+	if first.Address != 0x08000000 || !bytes.Equal(first.Data, []byte{1, 2, 3, 4, 5, 6, 7, 8}) {
+		t.Fatalf("unexpected first segment: %+v", first)
+	}
+	if second.Address != 0x08000010 || len(second.Data) != 4 {
+		t.Fatalf("unexpected second segment: %+v", second)
+	}
+
+	broken := strings.Replace(content, ":0400000001020304F2", ":0400000001020304F3", 1)
+	if _, err := Parse(FormatIntelHex, strings.NewReader(broken), int64(len(broken))); err == nil || !strings.Contains(err.Error(), "checksum") {
+		t.Fatalf("expected checksum error, got %v", err)
+	}
+	if _, err := Parse(FormatIntelHex, strings.NewReader(":0400000001020304F2"), 19); err == nil {
+		t.Fatal("expected error for missing end-of-file record")
+	}
+}
+
+func TestParseSREC(t *testing.T) {
+	t.Parallel()
+
+	content := "S00600004844521B\nS1070100AABBCCDDE9\nS9030100FB\n"
+	image := parseString(t, "app.bin", content)
+
+	if image.Format != FormatSREC || image.Entry == nil || *image.Entry != 0x0100 {
+		t.Fatalf("unexpected image header: %+v", image)
+	}
+	if len(image.Segments) != 1 || image.Segments[0].Address != 0x0100 || !bytes.Equal(image.Segments[0].Data, []byte{0xaa, 0xbb, 0xcc, 0xdd}) {
+		t.Fatalf("unexpected segments: %+v", image.Segments)
+	}
+}
+
+func TestParseUF2(t *testing.T) {
+	t.Parallel()
+
+	var content []byte
+	for i, address := range []uint32{0x10000000, 0x10000100, 0x20000000} {
+		block := make([]byte, uf2BlockSize)
+		binary.LittleEndian.PutUint32(block[0:], uf2Magic0)
+		binary.LittleEndian.PutUint32(block[4:], uf2Magic1)
+		if i == 2 {
+			binary.LittleEndian.PutUint32(block[8:], uf2FlagNotMain)
+		}
+		binary.LittleEndian.PutUint32(block[12:], address)
+		binary.LittleEndian.PutUint32(block[16:], 256)
+		binary.LittleEndian.PutUint32(block[20:], uint32(i))
+		binary.LittleEndian.PutUint32(block[24:], 3)
+		block[32] = byte(i + 1)
+		binary.LittleEndian.PutUint32(block[uf2BlockSize-4:], uf2MagicEnd)
+		content = append(content, block...)
+	}
+
+	image := parseString(t, "app.uf2", string(content))
+	if len(image.Segments) != 1 || image.Segments[0].Address != 0x10000000 || len(image.Segments[0].Data) != 512 {
+		t.Fatalf("unexpected segments: %+v", image.Segments)
+	}
+	if image.Segments[0].Data[256] != 2 {
+		t.Fatal("second block not appended after first")
+	}
+}
+
+func TestParseELF(t *testing.T) {
+	t.Parallel()
+
+	payload := []byte{0xde, 0xad, 0xbe, 0xef}
+	var buf bytes.Buffer
+	header := []byte{0x7f, 'E', 'L', 'F', 1, 1, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0}
+	buf.Write(header)
+	for _, value := range []interface{}{
+		uint16(2), uint16(40), uint32(1), uint32(0x08000009), // type, machine, version, entry
+		uint32(52), uint32(0), uint32(0), // phoff, shoff, flags
+		uint16(52), uint16(32), uint16(1), uint16(40), uint16(0), uint16(0),
+		// program header: PT_LOAD with VMA in RAM and LMA in flash
+		uint32(1), uint32(84), uint32(0x20000000), uint32(0x08000000),
+		uint32(len(payload)), uint32(len(payload)), uint32(5), uint32(4),
+	} {
+		if err := binary.Write(&buf, binary.LittleEndian, value); err != nil {
+			t.Fatalf("build elf: %v", err)
+		}
+	}
+	buf.Write(payload)
+
+	image := parseString(t, "firmware", buf.String())
+	if image.Format != FormatELF || image.Entry == nil || *image.Entry != 0x08000009 {
+		t.Fatalf("unexpected image header: %+v", image)
+	}
+	if len(image.Segments) != 1 || image.Segments[0].Address != 0x08000000 || !bytes.Equal(image.Segments[0].Data, payload) {
+		t.Fatalf("unexpected segments: %+v", image.Segments)
+	}
+}
+
+func TestDetectFallsBackToBinary(t *testing.T) {
+	t.Parallel()
+
+	if format := Detect("image.bin", []byte{0x00, 0x20, 0x00, 0x20}); format != FormatBinary {
+		t.Fatalf("expected binary, got %s", format)
+	}
+	if format := Detect("", []byte(":10000000")); format != FormatBinary {
+		t.Fatalf("short text must not be sniffed as hex, got %s", format)
+	}
+}
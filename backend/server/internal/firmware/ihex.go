@@ -0,0 +1,101 @@
+package firmware
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// maxRecordLine fits the longest valid HEX or S-record line with slack for whitespace.
+const maxRecordLine = 1024
+
+func newRecordScanner(r io.Reader) *bufio.Scanner {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, maxRecordLine), maxRecordLine)
+	return scanner
+}
+
+func parseIntelHex(r io.Reader) (*Image, error) {
+	image := &Image{Format: FormatIntelHex}
+	var (
+		mem   memoryMap
+		base  uint64
+		ended bool
+	)
+
+	scanner := newRecordScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if ended {
+			return nil, fmt.Errorf("line %d: data after end-of-file record", lineNo)
+		}
+		if text[0] != ':' {
+			return nil, fmt.Errorf("line %d: record must start with ':'", lineNo)
+		}
+		raw, err := hex.DecodeString(text[1:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hex digits", lineNo)
+		}
+		if len(raw) < 5 || len(raw) != int(raw[0])+5 {
+			return nil, fmt.Errorf("line %d: record length mismatch", lineNo)
+		}
+		var sum byte
+		for _, b := range raw {
+			sum += b
+		}
+		if sum != 0 {
+			return nil, fmt.Errorf("line %d: checksum mismatch", lineNo)
+		}
+
+		offset := uint64(binary.BigEndian.Uint16(raw[1:3]))
+		data := raw[4 : len(raw)-1]
+		switch recordType := raw[3]; recordType {
+		case 0x00:
+			if err := mem.add(base+offset, data); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		case 0x01:
+			ended = true
+		case 0x02, 0x04:
+			if len(data) != 2 {
+				return nil, fmt.Errorf("line %d: extended address record needs 2 bytes", lineNo)
+			}
+			shift := 4
+			if recordType == 0x04 {
+				shift = 16
+			}
+			base = uint64(binary.BigEndian.Uint16(data)) << shift
+		case 0x03, 0x05:
+			if len(data) != 4 {
+				return nil, fmt.Errorf("line %d: start address record needs 4 bytes", lineNo)
+			}
+			entry := uint64(binary.BigEndian.Uint32(data))
+			if recordType == 0x03 {
+				entry = uint64(binary.BigEndian.Uint16(data[:2]))<<4 + uint64(binary.BigEndian.Uint16(data[2:]))
+			}
+			image.Entry = &entry
+		default:
+			return nil, fmt.Errorf("line %d: unsupported record type %02x", lineNo, recordType)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if !ended {
+		return nil, errors.New("missing end-of-file record")
+	}
+
+	segments, err := mem.segments()
+	if err != nil {
+		return nil, err
+	}
+	image.Segments = segments
+	return image, nil
+}
@@ -0,0 +1,75 @@
+package firmware
+
+import (
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// srecAddressBytes is address width per S-record type; 4 is reserved.
+var srecAddressBytes = map[byte]int{
+	'0': 2, '1': 2, '2': 3, '3': 4,
+	'5': 2, '6': 3,
+	'7': 4, '8': 3, '9': 2,
+}
+
+func parseSREC(r io.Reader) (*Image, error) {
+	image := &Image{Format: FormatSREC}
+	var mem memoryMap
+
+	scanner := newRecordScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		if len(text) < 4 || text[0] != 'S' {
+			return nil, fmt.Errorf("line %d: record must start with 'S'", lineNo)
+		}
+		recordType := text[1]
+		addressBytes, ok := srecAddressBytes[recordType]
+		if !ok {
+			return nil, fmt.Errorf("line %d: unsupported record type S%c", lineNo, recordType)
+		}
+		raw, err := hex.DecodeString(text[2:])
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid hex digits", lineNo)
+		}
+		if len(raw) != int(raw[0])+1 || int(raw[0]) < addressBytes+1 {
+			return nil, fmt.Errorf("line %d: record length mismatch", lineNo)
+		}
+		var sum byte
+		for _, b := range raw {
+			sum += b
+		}
+		if sum != 0xff {
+			return nil, fmt.Errorf("line %d: checksum mismatch", lineNo)
+		}
+
+		var address uint64
+		for _, b := range raw[1 : 1+addressBytes] {
+			address = address<<8 | uint64(b)
+		}
+		data := raw[1+addressBytes : len(raw)-1]
+		switch recordType {
+		case '1', '2', '3':
+			if err := mem.add(address, data); err != nil {
+				return nil, fmt.Errorf("line %d: %w", lineNo, err)
+			}
+		case '7', '8', '9':
+			entry := address
+			image.Entry = &entry
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	segments, err := mem.segments()
+	if err != nil {
+		return nil, err
+	}
+	image.Segments = segments
+	return image, nil
+}
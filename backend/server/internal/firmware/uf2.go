@@ -0,0 +1,58 @@
+package firmware
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	uf2BlockSize      = 512
+	uf2MaxPayload     = 476
+	uf2Magic0         = 0x0A324655
+	uf2Magic1         = 0x9E5D5157
+	uf2MagicEnd       = 0x0AB16F30
+	uf2FlagNotMain    = 0x00000001
+	uf2FlagFileHolder = 0x00001000
+)
+
+func parseUF2(r io.Reader, size int64) (*Image, error) {
+	if size%uf2BlockSize != 0 {
+		return nil, fmt.Errorf("size %d is not a multiple of %d-byte blocks", size, uf2BlockSize)
+	}
+
+	var mem memoryMap
+	block := make([]byte, uf2BlockSize)
+	for blockNo := 0; ; blockNo++ {
+		if _, err := io.ReadFull(r, block); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return nil, fmt.Errorf("block %d: %w", blockNo, err)
+		}
+		if binary.LittleEndian.Uint32(block[0:]) != uf2Magic0 ||
+			binary.LittleEndian.Uint32(block[4:]) != uf2Magic1 ||
+			binary.LittleEndian.Uint32(block[uf2BlockSize-4:]) != uf2MagicEnd {
+			return nil, fmt.Errorf("block %d: bad magic", blockNo)
+		}
+		flags := binary.LittleEndian.Uint32(block[8:])
+		if flags&(uf2FlagNotMain|uf2FlagFileHolder) != 0 {
+			continue
+		}
+		address := uint64(binary.LittleEndian.Uint32(block[12:]))
+		payloadSize := binary.LittleEndian.Uint32(block[16:])
+		if payloadSize > uf2MaxPayload {
+			return nil, fmt.Errorf("block %d: payload size %d exceeds %d", blockNo, payloadSize, uf2MaxPayload)
+		}
+		if err := mem.add(address, block[32:32+payloadSize]); err != nil {
+			return nil, fmt.Errorf("block %d: %w", blockNo, err)
+		}
+	}
+
+	segments, err := mem.segments()
+	if err != nil {
+		return nil, err
+	}
+	return &Image{Format: FormatUF2, Segments: segments}, nil
+}
@@ -0,0 +1,73 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleSearchArtifacts(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	page, err := h.svc.OperatorSearchArtifacts(service.ArtifactSearchQuery{
+		Text:      query.Get("q"),
+		Tags:      queryList(query, "tag"),
+		TargetMCU: query.Get("target_mcu"),
+		Version:   query.Get("version"),
+		Formats:   queryList(query, "format"),
+		Operators: queryList(query, "operator"),
+		Cursor:    query.Get("cursor"),
+		Limit:     parseIntOrDefault(query.Get("limit"), 0),
+	})
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) handleGetArtifactMetadata(w http.ResponseWriter, r *http.Request) {
+	artifact, err := h.svc.OperatorGetArtifact(r.PathValue("artifact_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, artifact)
+}
+
+func (h *Handler) handleUpdateArtifactMetadata(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorArtifactMetadataRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	artifact, err := h.svc.OperatorUpdateArtifactMetadata(r.PathValue("artifact_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, artifact)
+}
+
+func (h *Handler) handleDeleteArtifact(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.OperatorDeleteArtifact(r.PathValue("artifact_id")); err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handleSignArtifact(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorArtifactSignatureRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	artifact, err := h.svc.OperatorSignArtifact(r.PathValue("artifact_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, artifact)
+}
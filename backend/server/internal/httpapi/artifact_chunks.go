@@ -0,0 +1,102 @@
+package httpapi
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleDeviceArtifactManifest(w http.ResponseWriter, r *http.Request) {
+	req, err := chunkRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	query := r.URL.Query()
+	if req.First, err = optionalInt(query, "first"); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	if req.Count, err = optionalInt(query, "count"); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	manifest, err := h.svc.DeviceArtifactManifest(req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, manifest)
+}
+
+func (h *Handler) handleDeviceArtifactChunk(w http.ResponseWriter, r *http.Request) {
+	req, err := chunkRequest(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	index, err := strconv.Atoi(strings.TrimSpace(r.PathValue("index")))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid chunk index"))
+		return
+	}
+
+	chunk, data, err := h.svc.DeviceArtifactChunk(req, index)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	name := fmt.Sprintf("%s.chunk%d.bin", req.ArtifactID, chunk.Index)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Chunk-Offset", strconv.FormatInt(chunk.Offset, 10))
+	w.Header().Set("X-Chunk-Sha256", chunk.SHA256)
+	w.Header().Set("X-Chunk-Crc32", chunk.CRC32)
+	serveResumable(w, r, name, time.Time{}, chunk.SHA256, bytes.NewReader(data))
+}
+
+// chunkRequest reads device credentials and chunk layout shared by manifest
+// and chunk endpoints; both must use same chunk_size and segment.
+func chunkRequest(r *http.Request) (service.DeviceChunkRequest, error) {
+	query := r.URL.Query()
+	req := service.DeviceChunkRequest{
+		DeviceID:    query.Get("device_id"),
+		DeviceToken: query.Get("device_token"),
+		ArtifactID:  r.PathValue("artifact_id"),
+	}
+	if raw := strings.TrimSpace(query.Get("chunk_size")); raw != "" {
+		size, err := strconv.ParseInt(raw, 10, 64)
+		if err != nil {
+			return req, errors.New("invalid chunk_size")
+		}
+		req.ChunkSize = size
+	}
+	if raw := strings.TrimSpace(query.Get("segment")); raw != "" {
+		segment, err := strconv.Atoi(raw)
+		if err != nil || segment < 0 {
+			return req, errors.New("invalid segment")
+		}
+		req.Segment = &segment
+	}
+	return req, nil
+}
+
+func optionalInt(query url.Values, key string) (int, error) {
+	raw := strings.TrimSpace(query.Get(key))
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return value, nil
+}
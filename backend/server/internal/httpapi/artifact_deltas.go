@@ -0,0 +1,59 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleCreateArtifactDelta(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorArtifactDeltaRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	delta, err := h.svc.OperatorCreateArtifactDelta(r.PathValue("artifact_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, delta)
+}
+
+// handleDeviceArtifactDelta serves patch from the artifact the probe last
+// programmed; 404 tells it to fetch the full image instead.
+func (h *Handler) handleDeviceArtifactDelta(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	req := service.DeviceDeltaRequest{
+		DeviceID:    query.Get("device_id"),
+		DeviceToken: query.Get("device_token"),
+		ArtifactID:  r.PathValue("artifact_id"),
+		Resume:      strings.TrimSpace(r.Header.Get("Range")) != "",
+	}
+	if query.Has("target") {
+		target, err := optionalInt(query, "target")
+		if err != nil {
+			writeError(w, http.StatusBadRequest, err)
+			return
+		}
+		req.Target = &target
+	}
+
+	artifact, delta, file, err := h.svc.DeviceArtifactDelta(req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	defer file.Close()
+
+	name := fmt.Sprintf("%s.from-%s.delta", artifact.ArtifactID, delta.BaseArtifactID)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Delta-Base-Artifact-Id", delta.BaseArtifactID)
+	w.Header().Set("X-Artifact-Sha256", artifact.PayloadSHA256)
+	w.Header().Set("X-Delta-Sha256", delta.SHA256)
+	serveResumable(w, r, name, delta.CreatedAt, delta.SHA256, file)
+}
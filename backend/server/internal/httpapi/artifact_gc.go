@@ -0,0 +1,35 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleArtifactStorage(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.svc.OperatorArtifactStorage())
+}
+
+func (h *Handler) handleCollectArtifacts(w http.ResponseWriter, r *http.Request) {
+	var req service.ArtifactGCRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	report, err := h.svc.OperatorCollectArtifacts(req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+func (h *Handler) handleArtifactReferences(w http.ResponseWriter, r *http.Request) {
+	refs, err := h.svc.OperatorArtifactReferences(r.PathValue("artifact_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": refs})
+}
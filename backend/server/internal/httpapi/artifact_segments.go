@@ -0,0 +1,82 @@
+package httpapi
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+func (h *Handler) handleListArtifactSegments(w http.ResponseWriter, r *http.Request) {
+	artifact, err := h.svc.OperatorGetArtifact(r.PathValue("artifact_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, artifactResponse(artifact))
+}
+
+func (h *Handler) handleGetArtifactSegment(w http.ResponseWriter, r *http.Request) {
+	index, err := segmentIndex(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	artifact, segment, file, err := h.svc.OperatorOpenArtifactSegment(r.PathValue("artifact_id"), index)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	defer file.Close()
+	serveArtifactSegment(w, r, artifact, segment, file)
+}
+
+func (h *Handler) handleDeviceListArtifactSegments(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	artifact, err := h.svc.DeviceGetArtifact(query.Get("device_id"), query.Get("device_token"), r.PathValue("artifact_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, artifactResponse(artifact))
+}
+
+func (h *Handler) handleDeviceGetArtifactSegment(w http.ResponseWriter, r *http.Request) {
+	index, err := segmentIndex(r)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	query := r.URL.Query()
+	artifact, segment, file, err := h.svc.DeviceOpenArtifactSegment(query.Get("device_id"), query.Get("device_token"), r.PathValue("artifact_id"), index)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	defer file.Close()
+	serveArtifactSegment(w, r, artifact, segment, file)
+}
+
+func segmentIndex(r *http.Request) (int, error) {
+	index, err := strconv.Atoi(strings.TrimSpace(r.PathValue("index")))
+	if err != nil || index < 0 {
+		return 0, errors.New("invalid segment index")
+	}
+	return index, nil
+}
+
+// serveArtifactSegment sends flat segment bytes; load address travels in
+// headers so probe can program without a metadata round trip.
+func serveArtifactSegment(w http.ResponseWriter, r *http.Request, artifact *model.Artifact, segment *model.ArtifactSegment, file *os.File) {
+	name := fmt.Sprintf("%s.seg%d.bin", artifact.ArtifactID, segment.Index)
+	w.Header().Set("Content-Type", "application/octet-stream")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("X-Segment-Address", fmt.Sprintf("0x%08x", segment.Address))
+	w.Header().Set("X-Segment-Sha256", segment.SHA256)
+	w.Header().Set("X-Segment-Crc32", segment.CRC32)
+	serveResumable(w, r, name, artifact.CreatedAt, segment.SHA256, file)
+}
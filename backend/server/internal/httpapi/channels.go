@@ -0,0 +1,44 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleListChannels(w http.ResponseWriter, r *http.Request) {
+	channels := h.svc.OperatorListChannels(r.URL.Query().Get("product"))
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": channels})
+}
+
+func (h *Handler) handleGetChannel(w http.ResponseWriter, r *http.Request) {
+	channel, err := h.svc.OperatorGetChannel(r.PathValue("product"), r.PathValue("name"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func (h *Handler) handlePromoteChannel(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorChannelPromotion
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	channel, err := h.svc.OperatorPromoteChannel(r.PathValue("product"), r.PathValue("name"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, channel)
+}
+
+func (h *Handler) handleDeleteChannel(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.OperatorDeleteChannel(r.PathValue("product"), r.PathValue("name")); err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
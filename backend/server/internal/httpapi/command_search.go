@@ -0,0 +1,54 @@
+package httpapi
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleSearchCommands(w http.ResponseWriter, r *http.Request) {
+	page, err := h.svc.OperatorSearchCommands(commandSearchQuery(r.URL.Query()))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, page)
+}
+
+func (h *Handler) handleCommandSummary(w http.ResponseWriter, r *http.Request) {
+	summary, err := h.svc.OperatorCommandSummary(commandSearchQuery(r.URL.Query()))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, summary)
+}
+
+func commandSearchQuery(query url.Values) service.CommandSearchQuery {
+	return service.CommandSearchQuery{
+		DeviceIDs:  queryList(query, "device_id"),
+		Statuses:   queryList(query, "status"),
+		Types:      queryList(query, "type"),
+		Operators:  queryList(query, "operator"),
+		ScheduleID: query.Get("schedule_id"),
+		Since:      query.Get("since"),
+		Until:      query.Get("until"),
+		Cursor:     query.Get("cursor"),
+		Limit:      parseIntOrDefault(query.Get("limit"), 0),
+	}
+}
+
+// queryList accepts both repeated keys and comma-separated values.
+func queryList(query url.Values, key string) []string {
+	var out []string
+	for _, value := range query[key] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				out = append(out, item)
+			}
+		}
+	}
+	return out
+}
@@ -0,0 +1,22 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleSetDeviceLabels(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorDeviceLabelsRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	device, err := h.svc.OperatorSetDeviceLabels(r.PathValue("device_id"), req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, device)
+}
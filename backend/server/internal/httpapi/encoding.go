@@ -0,0 +1,80 @@
+package httpapi
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"lte_swd/backend/server/internal/store"
+)
+
+// acceptedEncodings returns artifact content codings allowed by
+// Accept-Encoding, most preferred first; gzip wins ties.
+func acceptedEncodings(header string) []string {
+	weights := make(map[string]float64)
+	wildcard := -1.0
+	for _, part := range strings.Split(header, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		weight := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		switch name {
+		case store.EncodingGzip, "x-gzip":
+			weights[store.EncodingGzip] = weight
+		case store.EncodingDeflate:
+			weights[store.EncodingDeflate] = weight
+		case "*":
+			wildcard = weight
+		}
+	}
+
+	var out []string
+	for _, encoding := range []string{store.EncodingGzip, store.EncodingDeflate} {
+		weight, ok := weights[encoding]
+		if !ok {
+			weight = wildcard
+		}
+		if weight > 0 {
+			weights[encoding] = weight
+			out = append(out, encoding)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return weights[out[i]] > weights[out[j]] })
+	return out
+}
+
+// encodedLengthWriter restores Content-Length that http.ServeContent leaves
+// out once Content-Encoding is set; precompressed variants have a known size.
+type encodedLengthWriter struct {
+	http.ResponseWriter
+	size int64
+}
+
+func (w *encodedLengthWriter) WriteHeader(code int) {
+	header := w.Header()
+	if header.Get("Content-Length") == "" {
+		switch code {
+		case http.StatusOK:
+			header.Set("Content-Length", strconv.FormatInt(w.size, 10))
+		case http.StatusPartialContent:
+			var start, end, total int64
+			if _, err := fmt.Sscanf(header.Get("Content-Range"), "bytes %d-%d/%d", &start, &end, &total); err == nil {
+				header.Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+			}
+		}
+	}
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// Unwrap lets http.ResponseController reach the connection deadlines.
+func (w *encodedLengthWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
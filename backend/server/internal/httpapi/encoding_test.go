@@ -0,0 +1,100 @@
+package httpapi
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"testing"
+)
+
+func TestAcceptedEncodings(t *testing.T) {
+	t.Parallel()
+
+	for _, tc := range []struct {
+		header string
+		want   []string
+	}{
+		{header: "", want: nil},
+		{header: "identity", want: nil},
+		{header: "gzip", want: []string{"gzip"}},
+		{header: "x-gzip", want: []string{"gzip"}},
+		{header: "GZIP, Deflate", want: []string{"gzip", "deflate"}},
+		{header: "deflate, gzip", want: []string{"gzip", "deflate"}},
+		{header: "gzip;q=0.5, deflate", want: []string{"deflate", "gzip"}},
+		{header: "deflate;q=0.8, gzip;q=0.8", want: []string{"gzip", "deflate"}},
+		{header: "gzip;q=0", want: nil},
+		{header: "gzip;q=0, deflate", want: []string{"deflate"}},
+		{header: "*", want: []string{"gzip", "deflate"}},
+		{header: "*;q=0.3, deflate", want: []string{"deflate", "gzip"}},
+		{header: "*, gzip;q=0", want: []string{"deflate"}},
+		{header: "*;q=0", want: nil},
+		{header: "gzip;q=bogus, deflate", want: []string{"deflate"}},
+		{header: "br, zstd", want: nil},
+	} {
+		if got := acceptedEncodings(tc.header); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("acceptedEncodings(%q) = %q, want %q", tc.header, got, tc.want)
+		}
+	}
+}
+
+func TestEncodedArtifactDownloadContentLength(t *testing.T) {
+	t.Parallel()
+
+	api := newTestAPI(t)
+
+	request := func() *http.Request {
+		r := api.deviceDownload()
+		r.Header.Set("Accept-Encoding", "gzip")
+		return r
+	}
+
+	full := api.do(request())
+	if full.Code != http.StatusOK {
+		t.Fatalf("encoded download = %d", full.Code)
+	}
+	if got := full.Header().Get("Content-Encoding"); got != "gzip" {
+		t.Fatalf("Content-Encoding = %q", got)
+	}
+	if got, want := full.Header().Get("Content-Length"), strconv.Itoa(full.Body.Len()); got != want {
+		t.Fatalf("Content-Length = %q, want %q", got, want)
+	}
+	if full.Body.Len() >= len(api.payload) {
+		t.Fatalf("encoded body is %d bytes, payload %d", full.Body.Len(), len(api.payload))
+	}
+	reader, err := gzip.NewReader(bytes.NewReader(full.Body.Bytes()))
+	if err != nil {
+		t.Fatalf("gzip reader: %v", err)
+	}
+	if decoded, err := io.ReadAll(reader); err != nil || !bytes.Equal(decoded, api.payload) {
+		t.Fatalf("decoded body mismatch: %v", err)
+	}
+
+	encoded := full.Body.Bytes()
+	etag := full.Header().Get("ETag")
+	ranged := request()
+	ranged.Header.Set("Range", "bytes=10-29")
+	ranged.Header.Set("If-Range", etag)
+	partial := api.do(ranged)
+	if partial.Code != http.StatusPartialContent {
+		t.Fatalf("encoded ranged download = %d", partial.Code)
+	}
+	if got, want := partial.Header().Get("Content-Range"), "bytes 10-29/"+strconv.Itoa(len(encoded)); got != want {
+		t.Fatalf("Content-Range = %q, want %q", got, want)
+	}
+	if got := partial.Header().Get("Content-Length"); got != "20" {
+		t.Fatalf("ranged Content-Length = %q, want 20", got)
+	}
+	if !bytes.Equal(partial.Body.Bytes(), encoded[10:30]) {
+		t.Fatalf("ranged body mismatch")
+	}
+
+	plain := api.deviceDownload()
+	plain.Header.Set("Range", "bytes=10-29")
+	plain.Header.Set("If-Range", etag)
+	if got := api.do(plain); got.Code != http.StatusOK || got.Header().Get("Content-Encoding") != "" {
+		t.Fatalf("encoded ETag must not resume identity body: %d %q", got.Code, got.Header().Get("Content-Encoding"))
+	}
+}
@@ -0,0 +1,96 @@
+package httpapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/events"
+)
+
+const eventStreamPing = 15 * time.Second
+
+func (h *Handler) handleEvents(w http.ResponseWriter, r *http.Request) {
+	controller := http.NewResponseController(w)
+	// Stream outlives server WriteTimeout; keepalive pings detect dead peers instead.
+	if err := controller.SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		writeError(w, http.StatusInternalServerError, err)
+		return
+	}
+
+	lastEventID := parseEventID(r.Header.Get("Last-Event-ID"))
+	if lastEventID == 0 {
+		lastEventID = parseEventID(r.URL.Query().Get("last_event_id"))
+	}
+
+	sub := h.svc.SubscribeEvents(lastEventID)
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	fmt.Fprint(w, "retry: 3000\n\n")
+	if lastEventID != 0 && !sub.Resumed {
+		// Backlog no longer covers client position; it must reload full state.
+		fmt.Fprintf(w, "event: resync\ndata: {}\n\n")
+	}
+	for _, event := range sub.Backlog {
+		if err := writeEvent(w, event); err != nil {
+			return
+		}
+	}
+	if err := controller.Flush(); err != nil {
+		return
+	}
+
+	ping := time.NewTicker(eventStreamPing)
+	defer ping.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case event, ok := <-sub.C:
+			if !ok {
+				return
+			}
+			if err := writeEvent(w, event); err != nil {
+				return
+			}
+		case <-ping.C:
+			if _, err := fmt.Fprint(w, ": ping\n\n"); err != nil {
+				return
+			}
+		}
+		if err := controller.Flush(); err != nil {
+			return
+		}
+	}
+}
+
+func writeEvent(w http.ResponseWriter, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+func parseEventID(raw string) uint64 {
+	value, err := strconv.ParseUint(strings.TrimSpace(raw), 10, 64)
+	if err != nil {
+		return 0
+	}
+	return value
+}
+
+func isEventStreamRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet && r.URL.Path == "/api/v1/events"
+}
@@ -2,21 +2,27 @@ package httpapi
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
+	"os"
 	"path/filepath"
 	"strconv"
 	"strings"
 	"time"
 
 	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/model"
 	"lte_swd/backend/server/internal/service"
 	"lte_swd/backend/server/internal/store"
 )
 
+// artifactTransferTimeout bounds one raw artifact upload or download over slow links.
+const artifactTransferTimeout = 10 * time.Minute
+
 // Handler exposes HTTP API and static frontend for R1.
 type Handler struct {
 	svc               *service.Service
@@ -67,22 +73,67 @@ func (h *Handler) BuildMux() http.Handler {
 
 	mux.HandleFunc("POST /api/v1/operator/login", h.handleOperatorLogin)
 	mux.HandleFunc("GET /api/v1/operator/capabilities", h.requireOperator(h.handleOperatorCapabilities))
+	mux.HandleFunc("GET /api/v1/events", h.requireOperator(h.handleEvents))
 
 	mux.HandleFunc("GET /api/v1/devices", h.requireOperator(h.handleListDevices))
 	mux.HandleFunc("GET /api/v1/devices/{device_id}", h.requireOperator(h.handleGetDevice))
+	mux.HandleFunc("PUT /api/v1/devices/{device_id}/labels", h.requireOperator(h.handleSetDeviceLabels))
 	mux.HandleFunc("GET /api/v1/devices/{device_id}/telemetry", h.requireOperator(h.handleListTelemetry))
 	mux.HandleFunc("GET /api/v1/devices/{device_id}/commands", h.requireOperator(h.handleListCommands))
+	mux.HandleFunc("GET /api/v1/commands", h.requireOperator(h.handleSearchCommands))
+	mux.HandleFunc("GET /api/v1/commands/summary", h.requireOperator(h.handleCommandSummary))
 	mux.HandleFunc("POST /api/v1/commands", h.requireOperator(h.handleCreateCommand))
+	mux.HandleFunc("POST /api/v1/commands/{command_id}/approve", h.requireOperator(h.handleApproveCommand))
+	mux.HandleFunc("POST /api/v1/commands/{command_id}/reject", h.requireOperator(h.handleRejectCommand))
+	mux.HandleFunc("POST /api/v1/commands/{command_id}/override", h.requireOperator(h.handleOverrideCommand))
+	mux.HandleFunc("GET /api/v1/commands/{command_id}/result-blob", h.requireOperator(h.handleGetResultBlob))
+	mux.HandleFunc("POST /api/v1/commands/{command_id}/result-blob/promote", h.requireOperator(h.handlePromoteResultBlob))
+	mux.HandleFunc("GET /api/v1/templates", h.requireOperator(h.handleListTemplates))
+	mux.HandleFunc("POST /api/v1/templates", h.requireOperator(h.handleCreateTemplate))
+	mux.HandleFunc("GET /api/v1/templates/{name}", h.requireOperator(h.handleGetTemplate))
+	mux.HandleFunc("PUT /api/v1/templates/{name}", h.requireOperator(h.handleUpdateTemplate))
+	mux.HandleFunc("DELETE /api/v1/templates/{name}", h.requireOperator(h.handleDeleteTemplate))
+	mux.HandleFunc("GET /api/v1/schedules", h.requireOperator(h.handleListSchedules))
+	mux.HandleFunc("POST /api/v1/schedules", h.requireOperator(h.handleCreateSchedule))
+	mux.HandleFunc("GET /api/v1/schedules/preview", h.requireOperator(h.handlePreviewSchedule))
+	mux.HandleFunc("GET /api/v1/schedules/{schedule_id}", h.requireOperator(h.handleGetSchedule))
+	mux.HandleFunc("PATCH /api/v1/schedules/{schedule_id}", h.requireOperator(h.handleUpdateSchedule))
+	mux.HandleFunc("DELETE /api/v1/schedules/{schedule_id}", h.requireOperator(h.handleDeleteSchedule))
+	mux.HandleFunc("GET /api/v1/schedules/{schedule_id}/preview", h.requireOperator(h.handlePreviewSchedule))
+	mux.HandleFunc("GET /api/v1/artifacts", h.requireOperator(h.handleSearchArtifacts))
 	mux.HandleFunc("POST /api/v1/artifacts", h.requireOperator(h.handleUploadArtifact))
+	mux.HandleFunc("PUT /api/v1/artifacts", h.requireOperator(h.handleStreamArtifact))
+	mux.HandleFunc("GET /api/v1/channels", h.requireOperator(h.handleListChannels))
+	mux.HandleFunc("GET /api/v1/channels/{product}/{name}", h.requireOperator(h.handleGetChannel))
+	mux.HandleFunc("PUT /api/v1/channels/{product}/{name}", h.requireOperator(h.handlePromoteChannel))
+	mux.HandleFunc("DELETE /api/v1/channels/{product}/{name}", h.requireOperator(h.handleDeleteChannel))
+	mux.HandleFunc("GET /api/v1/artifacts/storage", h.requireOperator(h.handleArtifactStorage))
+	mux.HandleFunc("POST /api/v1/artifacts/gc", h.requireOperator(h.handleCollectArtifacts))
 	mux.HandleFunc("GET /api/v1/artifacts/{artifact_id}", h.requireOperator(h.handleGetArtifact))
+	mux.HandleFunc("GET /api/v1/artifacts/{artifact_id}/references", h.requireOperator(h.handleArtifactReferences))
+	mux.HandleFunc("DELETE /api/v1/artifacts/{artifact_id}", h.requireOperator(h.handleDeleteArtifact))
+	mux.HandleFunc("PUT /api/v1/artifacts/{artifact_id}/signature", h.requireOperator(h.handleSignArtifact))
+	mux.HandleFunc("POST /api/v1/artifacts/{artifact_id}/deltas", h.requireOperator(h.handleCreateArtifactDelta))
+	mux.HandleFunc("GET /api/v1/artifacts/{artifact_id}/metadata", h.requireOperator(h.handleGetArtifactMetadata))
+	mux.HandleFunc("PATCH /api/v1/artifacts/{artifact_id}/metadata", h.requireOperator(h.handleUpdateArtifactMetadata))
+	mux.HandleFunc("GET /api/v1/artifacts/{artifact_id}/segments", h.requireOperator(h.handleListArtifactSegments))
+	mux.HandleFunc("GET /api/v1/artifacts/{artifact_id}/segments/{index}", h.requireOperator(h.handleGetArtifactSegment))
 
 	mux.HandleFunc("POST /api/v1/device/register", h.handleDeviceRegister)
 	mux.HandleFunc("POST /api/v1/device/heartbeat", h.handleDeviceHeartbeat)
 	mux.HandleFunc("POST /api/v1/device/telemetry", h.handleDeviceTelemetry)
 	mux.HandleFunc("POST /api/v1/device/location", h.handleDeviceLocation)
 	mux.HandleFunc("POST /api/v1/device/commands/pull", h.handleDevicePullCommand)
+	mux.HandleFunc("POST /api/v1/device/commands/{command_id}/progress", h.handleDeviceCommandProgress)
 	mux.HandleFunc("POST /api/v1/device/commands/{command_id}/result", h.handleDeviceCommandResult)
+	mux.HandleFunc("PUT /api/v1/device/commands/{command_id}/result-blob", h.handleDeviceUploadResultBlob)
+	mux.HandleFunc("GET /api/v1/device/commands/{command_id}/result-blob", h.handleDeviceResultBlobStatus)
 	mux.HandleFunc("GET /api/v1/device/artifacts/{artifact_id}", h.handleDeviceGetArtifact)
+	mux.HandleFunc("GET /api/v1/device/artifacts/{artifact_id}/segments", h.handleDeviceListArtifactSegments)
+	mux.HandleFunc("GET /api/v1/device/artifacts/{artifact_id}/segments/{index}", h.handleDeviceGetArtifactSegment)
+	mux.HandleFunc("GET /api/v1/device/artifacts/{artifact_id}/manifest", h.handleDeviceArtifactManifest)
+	mux.HandleFunc("GET /api/v1/device/artifacts/{artifact_id}/chunks/{index}", h.handleDeviceArtifactChunk)
+	mux.HandleFunc("GET /api/v1/device/artifacts/{artifact_id}/delta", h.handleDeviceArtifactDelta)
 
 	staticRoot, _ := filepath.Abs(h.staticDir)
 	fs := http.FileServer(http.Dir(staticRoot))
@@ -102,6 +153,7 @@ func (h *Handler) handleOperatorLogin(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var req struct {
+		Username string `json:"username"`
 		Password string `json:"password"`
 	}
 	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
@@ -109,7 +161,7 @@ func (h *Handler) handleOperatorLogin(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	token, expiresAt, err := h.svc.LoginOperator(req.Password)
+	token, expiresAt, err := h.svc.LoginOperator(req.Username, req.Password)
 	if err != nil {
 		if errors.Is(err, auth.ErrInvalidPassword) {
 			h.loginGuard.onFailure(ip, now)
@@ -119,15 +171,27 @@ func (h *Handler) handleOperatorLogin(w http.ResponseWriter, r *http.Request) {
 	}
 	h.loginGuard.onSuccess(ip)
 
+	operator := strings.TrimSpace(req.Username)
+	if operator == "" {
+		operator = auth.SharedOperatorName
+	}
 	writeJSON(w, http.StatusOK, map[string]interface{}{
 		"token":      token,
 		"expires_at": expiresAt,
+		"operator":   operator,
 	})
 }
 
 func (h *Handler) handleOperatorCapabilities(w http.ResponseWriter, _ *http.Request) {
+	types := h.svc.CommandTypes()
+	names := make([]string, 0, len(types))
+	for _, info := range types {
+		names = append(names, info.Name)
+	}
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"supported_commands": service.SupportedCommandTypes(),
+		"supported_commands": names,
+		"command_types":      types,
+		"artifact_signing":   h.svc.ArtifactSigningPolicy(),
 	})
 }
 
@@ -180,6 +244,7 @@ func (h *Handler) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	req.IdempotencyKey = r.Header.Get("Idempotency-Key")
 
 	command, err := h.svc.OperatorCreateCommand(req, operatorFromRequest(r))
 	if err != nil {
@@ -189,39 +254,154 @@ func (h *Handler) handleCreateCommand(w http.ResponseWriter, r *http.Request) {
 	writeJSON(w, http.StatusCreated, command)
 }
 
+func (h *Handler) handleApproveCommand(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorApprovalRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	command, err := h.svc.OperatorApproveCommand(r.PathValue("command_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, command)
+}
+
+func (h *Handler) handleRejectCommand(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorApprovalRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	command, err := h.svc.OperatorRejectCommand(r.PathValue("command_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, command)
+}
+
+func (h *Handler) handleOverrideCommand(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorOverrideRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	command, err := h.svc.OperatorOverrideInterlocks(r.PathValue("command_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, command)
+}
+
 func (h *Handler) handleUploadArtifact(w http.ResponseWriter, r *http.Request) {
 	var req service.OperatorArtifactRequest
 	if err := decodeJSON(r, &req, h.maxArtifactBytes); err != nil {
 		writeError(w, http.StatusBadRequest, err)
 		return
 	}
+	req.IdempotencyKey = r.Header.Get("Idempotency-Key")
 
 	artifact, err := h.svc.OperatorUploadArtifact(req, operatorFromRequest(r))
 	if err != nil {
 		writeErrorFromDomain(w, err)
 		return
 	}
+	writeJSON(w, http.StatusCreated, artifactResponse(artifact))
+}
+
+// handleStreamArtifact accepts raw artifact bytes as request body, so large
+// images avoid base64 and in-memory buffering of the JSON path.
+func (h *Handler) handleStreamArtifact(w http.ResponseWriter, r *http.Request) {
+	if r.ContentLength > h.maxArtifactBytes {
+		writeError(w, http.StatusRequestEntityTooLarge, store.ErrArtifactTooLarge)
+		return
+	}
+
+	// Server-wide read/write timeouts are sized for small JSON requests.
+	controller := http.NewResponseController(w)
+	deadline := time.Now().Add(artifactTransferTimeout)
+	_ = controller.SetReadDeadline(deadline)
+	_ = controller.SetWriteDeadline(deadline.Add(10 * time.Second))
+
+	artifact, err := h.svc.OperatorStreamArtifact(service.OperatorArtifactStream{
+		Name:           r.URL.Query().Get("name"),
+		ContentType:    r.Header.Get("Content-Type"),
+		ContentLength:  r.ContentLength,
+		Body:           http.MaxBytesReader(w, r.Body, h.maxArtifactBytes+1),
+		Signature:      r.Header.Get("X-Artifact-Signature"),
+		SignatureKeyID: r.Header.Get("X-Artifact-Signature-Key-Id"),
+		IdempotencyKey: r.Header.Get("Idempotency-Key"),
+	}, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, artifactResponse(artifact))
+}
 
-	writeJSON(w, http.StatusCreated, map[string]interface{}{
+func artifactResponse(artifact *model.Artifact) map[string]interface{} {
+	return map[string]interface{}{
 		"artifact_id":    artifact.ArtifactID,
 		"name":           artifact.Name,
 		"content_type":   artifact.ContentType,
-		"size":           len(artifact.Payload),
+		"size":           artifact.Size,
 		"payload_sha256": artifact.PayloadSHA256,
-	})
+		"format":         artifact.Format,
+		"entry_point":    artifact.EntryPoint,
+		"segments":       artifact.Segments,
+		"signature":      artifact.Signature,
+		"deltas":         artifact.Deltas,
+	}
 }
 
 func (h *Handler) handleGetArtifact(w http.ResponseWriter, r *http.Request) {
-	artifactID := r.PathValue("artifact_id")
-	artifact, err := h.svc.OperatorGetArtifact(artifactID)
+	artifact, file, err := h.svc.OperatorOpenArtifact(r.PathValue("artifact_id"))
 	if err != nil {
 		writeErrorFromDomain(w, err)
 		return
 	}
+	defer file.Close()
+	serveArtifact(w, r, artifact, file, "")
+}
 
+// serveArtifact sends artifact payload; with encoding set, file holds the
+// compressed variant and gets its own ETag.
+func serveArtifact(w http.ResponseWriter, r *http.Request, artifact *model.Artifact, file *os.File, encoding string) {
 	w.Header().Set("Content-Type", artifact.ContentType)
 	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
-	_, _ = w.Write(artifact.Payload)
+	// Probes re-verify the release signature over the downloaded bytes.
+	if signature := artifact.Signature; signature != nil {
+		w.Header().Set("X-Artifact-Signature", signature.Signature)
+		w.Header().Set("X-Artifact-Signature-Key-Id", signature.KeyID)
+		w.Header().Set("X-Artifact-Signature-Alg", signature.Algorithm)
+	}
+	etag := artifact.PayloadSHA256
+	if encoding != "" {
+		w.Header().Set("Content-Encoding", encoding)
+		etag += "+" + encoding
+		if info, err := file.Stat(); err == nil {
+			w = &encodedLengthWriter{ResponseWriter: w, size: info.Size()}
+		}
+	}
+	serveResumable(w, r, artifact.Name, artifact.CreatedAt, etag, file)
+}
+
+// serveResumable answers Range and If-Range requests so probes can fetch in
+// chunks and resume after a dropped link. etag derives from the content
+// sha256, which makes If-Range refuse to splice bytes of a replaced payload.
+func serveResumable(w http.ResponseWriter, r *http.Request, name string, modTime time.Time, etag string, content io.ReadSeeker) {
+	if etag != "" {
+		w.Header().Set("ETag", strconv.Quote(etag))
+	}
+	w.Header().Set("Accept-Ranges", "bytes")
+	_ = http.NewResponseController(w).SetWriteDeadline(time.Now().Add(artifactTransferTimeout))
+	http.ServeContent(w, r, name, modTime, content)
 }
 
 func (h *Handler) handleDeviceRegister(w http.ResponseWriter, r *http.Request) {
@@ -288,7 +468,7 @@ func (h *Handler) handleDevicePullCommand(w http.ResponseWriter, r *http.Request
 		return
 	}
 
-	command, err := h.svc.DevicePullCommand(req)
+	command, err := h.svc.DevicePullCommand(r.Context(), req)
 	if err != nil {
 		writeErrorFromDomain(w, err)
 		return
@@ -302,6 +482,25 @@ func (h *Handler) handleDevicePullCommand(w http.ResponseWriter, r *http.Request
 	writeJSON(w, http.StatusOK, map[string]interface{}{"command": command})
 }
 
+func (h *Handler) handleDeviceCommandProgress(w http.ResponseWriter, r *http.Request) {
+	commandID := r.PathValue("command_id")
+
+	var req service.DeviceCommandProgressRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+	req.CommandID = commandID
+
+	command, err := h.svc.DeviceCommandProgress(req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, command)
+}
+
 func (h *Handler) handleDeviceCommandResult(w http.ResponseWriter, r *http.Request) {
 	commandID := r.PathValue("command_id")
 
@@ -326,31 +525,39 @@ func (h *Handler) handleDeviceGetArtifact(w http.ResponseWriter, r *http.Request
 	deviceToken := strings.TrimSpace(r.URL.Query().Get("device_token"))
 	artifactID := r.PathValue("artifact_id")
 
-	artifact, err := h.svc.DeviceGetArtifact(deviceID, deviceToken, artifactID)
+	encodings := acceptedEncodings(r.Header.Get("Accept-Encoding"))
+	artifact, file, encoding, err := h.svc.DeviceOpenArtifact(deviceID, deviceToken, artifactID, encodings)
 	if err != nil {
 		writeErrorFromDomain(w, err)
 		return
 	}
-
-	w.Header().Set("Content-Type", artifact.ContentType)
-	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", artifact.Name))
-	_, _ = w.Write(artifact.Payload)
+	defer file.Close()
+	// Probes check the inflated payload against these.
+	w.Header().Add("Vary", "Accept-Encoding")
+	w.Header().Set("X-Artifact-Sha256", artifact.PayloadSHA256)
+	w.Header().Set("X-Artifact-Size", strconv.FormatInt(artifact.Size, 10))
+	serveArtifact(w, r, artifact, file, encoding)
 }
 
 func (h *Handler) requireOperator(next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		token := bearerToken(r.Header.Get("Authorization"))
+		if token == "" && isEventStreamRequest(r) {
+			// EventSource cannot set headers, so the stream accepts a query token.
+			token = strings.TrimSpace(r.URL.Query().Get("access_token"))
+		}
 		if token == "" {
 			writeError(w, http.StatusUnauthorized, errors.New("missing bearer token"))
 			return
 		}
 
-		if err := h.svc.RequireOperator(token); err != nil {
+		operator, err := h.svc.OperatorIdentity(token)
+		if err != nil {
 			writeErrorFromDomain(w, err)
 			return
 		}
 
-		next(w, r)
+		next(w, r.WithContext(context.WithValue(r.Context(), operatorContextKey{}, operator)))
 	}
 }
 
@@ -363,7 +570,7 @@ func (h *Handler) withLogging(next http.Handler) http.Handler {
 
 func (h *Handler) withRateLimit(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !strings.HasPrefix(r.URL.Path, "/api/") {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || isChunkDownload(r) {
 			next.ServeHTTP(w, r)
 			return
 		}
@@ -379,6 +586,21 @@ func (h *Handler) withRateLimit(next http.Handler) http.Handler {
 	})
 }
 
+// isChunkDownload matches per-chunk artifact GETs. A probe needs hundreds of
+// them per image and each carries its device token, so they skip the per-IP
+// limit that would otherwise stall the download midway.
+func isChunkDownload(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/v1/device/artifacts/")
+	if !ok {
+		return false
+	}
+	parts := strings.Split(rest, "/")
+	return len(parts) == 3 && parts[1] == "chunks"
+}
+
 func (h *Handler) withSecurityHeaders(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("X-Content-Type-Options", "nosniff")
@@ -460,6 +682,50 @@ func writeErrorFromDomain(w http.ResponseWriter, err error) {
 		writeError(w, http.StatusNotFound, err)
 	case errors.Is(err, store.ErrArtifactNotFound):
 		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrArtifactSegmentNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrArtifactDeltaNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrArtifactInUse):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrArtifactSignature):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, store.ErrArtifactUnsigned):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, store.ErrArtifactTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, err)
+	case errors.Is(err, store.ErrArtifactQuotaExceeded):
+		writeError(w, http.StatusInsufficientStorage, err)
+	case errors.Is(err, store.ErrInvalidCommandTransition):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrCommandAlreadyCompleted):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrSelfApproval):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, store.ErrNamedOperatorRequired):
+		writeError(w, http.StatusForbidden, err)
+	case errors.Is(err, store.ErrResultBlobNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrResultBlobOffset):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrResultBlobIncomplete):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrResultBlobChecksum):
+		writeError(w, http.StatusUnprocessableEntity, err)
+	case errors.Is(err, store.ErrIdempotencyKeyReused):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrScheduleNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrScheduleConflict):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrTemplateNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrTemplateExists):
+		writeError(w, http.StatusConflict, err)
+	case errors.Is(err, store.ErrChannelNotFound):
+		writeError(w, http.StatusNotFound, err)
+	case errors.Is(err, store.ErrChannelConflict):
+		writeError(w, http.StatusConflict, err)
 	default:
 		message := strings.ToLower(err.Error())
 		if strings.Contains(message, "required") || strings.Contains(message, "unsupported") || strings.Contains(message, "invalid") {
@@ -489,6 +755,11 @@ func parseIntOrDefault(raw string, def int) int {
 	return value
 }
 
-func operatorFromRequest(_ *http.Request) string {
-	return "operator"
+type operatorContextKey struct{}
+
+func operatorFromRequest(r *http.Request) string {
+	if operator, ok := r.Context().Value(operatorContextKey{}).(string); ok && operator != "" {
+		return operator
+	}
+	return auth.SharedOperatorName
 }
@@ -0,0 +1,184 @@
+package httpapi
+
+import (
+	"bytes"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/commands"
+	"lte_swd/backend/server/internal/config"
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/service"
+	"lte_swd/backend/server/internal/store"
+)
+
+// testAPI is a mux over a fresh store with dev-1 registered and one artifact.
+type testAPI struct {
+	mux           http.Handler
+	operatorToken string
+	deviceToken   string
+	artifact      *model.Artifact
+	payload       []byte
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	return newTestAPIWith(t, Options{
+		APIRatePerMinute: 1000,
+		LoginRatePerMin:  100,
+		LoginBurst:       10,
+	})
+}
+
+func newTestAPIWith(t *testing.T, opts Options) *testAPI {
+	t.Helper()
+
+	st, err := store.NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	opAuth := auth.NewOperatorAuth("pass", time.Hour)
+	svc := service.New(config.Config{
+		DeviceEnrollKey:    "enroll",
+		DeviceOfflineAfter: 30 * time.Second,
+	}, st, opAuth, commands.Builtin())
+
+	device, err := svc.RegisterDevice(service.RegisterDeviceRequest{
+		EnrollKey:       "enroll",
+		DeviceID:        "dev-1",
+		HWUID:           "uid",
+		ModemIMEI:       "imei",
+		FirmwareVersion: "r1",
+	})
+	if err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+	operatorToken, _, err := opAuth.Login("pass", time.Now())
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	// Repetitive payload so compressed variants come out smaller.
+	payload := bytes.Repeat([]byte("firmware-block-0123456789abcdef\n"), 256)
+	artifact, err := svc.OperatorUploadArtifact(service.OperatorArtifactRequest{
+		Name:       "fw.bin",
+		Base64Data: base64.StdEncoding.EncodeToString(payload),
+	}, "operator")
+	if err != nil {
+		t.Fatalf("upload artifact: %v", err)
+	}
+
+	handler := NewHandler(svc, t.TempDir(), opts)
+	return &testAPI{
+		mux:           handler.BuildMux(),
+		operatorToken: operatorToken,
+		deviceToken:   device.DeviceToken,
+		artifact:      artifact,
+		payload:       payload,
+	}
+}
+
+func (a *testAPI) operatorDownload() *http.Request {
+	r := httptest.NewRequest(http.MethodGet, "/api/v1/artifacts/"+a.artifact.ArtifactID, nil)
+	r.Header.Set("Authorization", "Bearer "+a.operatorToken)
+	return r
+}
+
+func (a *testAPI) deviceDownload() *http.Request {
+	return httptest.NewRequest(http.MethodGet, "/api/v1/device/artifacts/"+a.artifact.ArtifactID+"?device_id=dev-1&device_token="+a.deviceToken, nil)
+}
+
+func (a *testAPI) do(r *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	a.mux.ServeHTTP(w, r)
+	return w
+}
+
+func TestArtifactDownloadsResume(t *testing.T) {
+	t.Parallel()
+
+	api := newTestAPI(t)
+	etag := strconv.Quote(api.artifact.PayloadSHA256)
+	size := int64(len(api.payload))
+
+	for name, request := range map[string]func() *http.Request{
+		"operator": api.operatorDownload,
+		"device":   api.deviceDownload,
+	} {
+		full := api.do(request())
+		if full.Code != http.StatusOK || !bytes.Equal(full.Body.Bytes(), api.payload) {
+			t.Fatalf("%s: full download = %d (%d bytes)", name, full.Code, full.Body.Len())
+		}
+		if got := full.Header().Get("ETag"); got != etag {
+			t.Fatalf("%s: ETag = %q, want %q", name, got, etag)
+		}
+		if got := full.Header().Get("Accept-Ranges"); got != "bytes" {
+			t.Fatalf("%s: Accept-Ranges = %q", name, got)
+		}
+
+		ranged := request()
+		ranged.Header.Set("Range", "bytes=100-199")
+		ranged.Header.Set("If-Range", etag)
+		partial := api.do(ranged)
+		if partial.Code != http.StatusPartialContent {
+			t.Fatalf("%s: ranged download = %d", name, partial.Code)
+		}
+		if got, want := partial.Header().Get("Content-Range"), "bytes 100-199/"+strconv.FormatInt(size, 10); got != want {
+			t.Fatalf("%s: Content-Range = %q, want %q", name, got, want)
+		}
+		if !bytes.Equal(partial.Body.Bytes(), api.payload[100:200]) {
+			t.Fatalf("%s: ranged body mismatch", name)
+		}
+
+		tail := request()
+		tail.Header.Set("Range", "bytes=-10")
+		if got := api.do(tail); got.Code != http.StatusPartialContent || !bytes.Equal(got.Body.Bytes(), api.payload[size-10:]) {
+			t.Fatalf("%s: suffix range = %d %q", name, got.Code, got.Body.Bytes())
+		}
+
+		stale := request()
+		stale.Header.Set("Range", "bytes=100-199")
+		stale.Header.Set("If-Range", strconv.Quote("replaced-payload"))
+		restarted := api.do(stale)
+		if restarted.Code != http.StatusOK || restarted.Header().Get("Content-Range") != "" {
+			t.Fatalf("%s: stale If-Range = %d, Content-Range %q", name, restarted.Code, restarted.Header().Get("Content-Range"))
+		}
+		if body, _ := io.ReadAll(restarted.Body); !bytes.Equal(body, api.payload) {
+			t.Fatalf("%s: stale If-Range must return the full body, got %d bytes", name, len(body))
+		}
+
+		cached := request()
+		cached.Header.Set("If-None-Match", etag)
+		if got := api.do(cached); got.Code != http.StatusNotModified {
+			t.Fatalf("%s: If-None-Match = %d", name, got.Code)
+		}
+	}
+}
+
+func TestChunkDownloadsSkipRateLimit(t *testing.T) {
+	t.Parallel()
+
+	api := newTestAPIWith(t, Options{APIRatePerMinute: 3, LoginRatePerMin: 100, LoginBurst: 10})
+	query := "?device_id=dev-1&device_token=" + api.deviceToken
+	for i := 0; i < 10; i++ {
+		chunk := httptest.NewRequest(http.MethodGet, "/api/v1/device/artifacts/"+api.artifact.ArtifactID+"/chunks/"+strconv.Itoa(i%2)+query, nil)
+		if got := api.do(chunk); got.Code != http.StatusOK {
+			t.Fatalf("chunk request %d = %d", i, got.Code)
+		}
+	}
+
+	limited := false
+	for i := 0; i < 5 && !limited; i++ {
+		limited = api.do(api.deviceDownload()).Code == http.StatusTooManyRequests
+	}
+	if !limited {
+		t.Fatal("full downloads must stay rate limited")
+	}
+}
@@ -0,0 +1,86 @@
+package httpapi
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleDeviceUploadResultBlob(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	offset, err := strconv.ParseInt(query.Get("offset"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid offset query parameter"))
+		return
+	}
+	total, err := strconv.ParseInt(query.Get("total"), 10, 64)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, errors.New("invalid total query parameter"))
+		return
+	}
+
+	defer r.Body.Close()
+	blob, err := h.svc.DeviceUploadResultBlob(service.DeviceResultBlobChunk{
+		DeviceID:    query.Get("device_id"),
+		DeviceToken: query.Get("device_token"),
+		CommandID:   r.PathValue("command_id"),
+		Offset:      offset,
+		TotalSize:   total,
+		ContentType: strings.TrimSpace(r.Header.Get("Content-Type")),
+		SHA256:      query.Get("sha256"),
+		Body:        http.MaxBytesReader(w, r.Body, h.maxArtifactBytes),
+	})
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			writeError(w, http.StatusRequestEntityTooLarge, errors.New("request body too large"))
+			return
+		}
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, blob)
+}
+
+func (h *Handler) handleDeviceResultBlobStatus(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	blob, err := h.svc.DeviceResultBlobStatus(query.Get("device_id"), query.Get("device_token"), r.PathValue("command_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, blob)
+}
+
+func (h *Handler) handleGetResultBlob(w http.ResponseWriter, r *http.Request) {
+	command, file, err := h.svc.OperatorOpenResultBlob(r.PathValue("command_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	defer file.Close()
+
+	name := command.CommandID + ".bin"
+	w.Header().Set("Content-Type", command.ResultBlob.ContentType)
+	w.Header().Set("Content-Disposition", "attachment; filename="+strconv.Quote(name))
+	serveResumable(w, r, name, command.ResultBlob.UpdatedAt, command.ResultBlob.SHA256, file)
+}
+
+func (h *Handler) handlePromoteResultBlob(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorPromoteBlobRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	artifact, err := h.svc.OperatorPromoteResultBlob(r.PathValue("command_id"), req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, artifactResponse(artifact))
+}
@@ -0,0 +1,78 @@
+package httpapi
+
+import (
+	"net/http"
+	"time"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleListSchedules(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": h.svc.OperatorListSchedules()})
+}
+
+func (h *Handler) handleCreateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorScheduleRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	schedule, err := h.svc.OperatorCreateSchedule(req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, schedule)
+}
+
+func (h *Handler) handleGetSchedule(w http.ResponseWriter, r *http.Request) {
+	schedule, err := h.svc.OperatorGetSchedule(r.PathValue("schedule_id"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+func (h *Handler) handleUpdateSchedule(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorScheduleUpdateRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	schedule, err := h.svc.OperatorUpdateSchedule(r.PathValue("schedule_id"), req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, schedule)
+}
+
+func (h *Handler) handleDeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.OperatorDeleteSchedule(r.PathValue("schedule_id")); err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *Handler) handlePreviewSchedule(w http.ResponseWriter, r *http.Request) {
+	count := parseIntOrDefault(r.URL.Query().Get("count"), 0)
+
+	var (
+		runs []time.Time
+		err  error
+	)
+	if scheduleID := r.PathValue("schedule_id"); scheduleID != "" {
+		runs, err = h.svc.OperatorPreviewSchedule(scheduleID, count)
+	} else {
+		runs, err = h.svc.PreviewCron(r.URL.Query().Get("cron"), count)
+	}
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{"next_runs": runs})
+}
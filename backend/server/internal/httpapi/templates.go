@@ -0,0 +1,58 @@
+package httpapi
+
+import (
+	"net/http"
+
+	"lte_swd/backend/server/internal/service"
+)
+
+func (h *Handler) handleListTemplates(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{"items": h.svc.OperatorListTemplates()})
+}
+
+func (h *Handler) handleCreateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorTemplateRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	template, err := h.svc.OperatorCreateTemplate(req, operatorFromRequest(r))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusCreated, template)
+}
+
+func (h *Handler) handleGetTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.svc.OperatorGetTemplate(r.PathValue("name"))
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+func (h *Handler) handleUpdateTemplate(w http.ResponseWriter, r *http.Request) {
+	var req service.OperatorTemplateRequest
+	if err := decodeJSON(r, &req, h.maxJSONBytes); err != nil {
+		writeError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	template, err := h.svc.OperatorUpdateTemplate(r.PathValue("name"), req)
+	if err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, template)
+}
+
+func (h *Handler) handleDeleteTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.svc.OperatorDeleteTemplate(r.PathValue("name")); err != nil {
+		writeErrorFromDomain(w, err)
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -19,16 +19,45 @@ const (
 type CommandStatus string
 
 const (
+	// CommandAwaitingApproval means command waits for a second operator.
+	CommandAwaitingApproval CommandStatus = "awaiting_approval"
+	// CommandRejected means second operator refused the command.
+	CommandRejected CommandStatus = "rejected"
 	// CommandQueued means backend accepted command.
 	CommandQueued CommandStatus = "queued"
 	// CommandDispatched means command was delivered to device.
 	CommandDispatched CommandStatus = "dispatched"
+	// CommandRunning means device reported intermediate progress.
+	CommandRunning CommandStatus = "running"
 	// CommandSuccess means execution finished successfully.
 	CommandSuccess CommandStatus = "success"
 	// CommandFailed means execution ended with error.
 	CommandFailed CommandStatus = "failed"
 )
 
+// commandTransitions lists allowed lifecycle moves; terminal states have none.
+var commandTransitions = map[CommandStatus][]CommandStatus{
+	CommandAwaitingApproval: {CommandQueued, CommandRejected},
+	CommandQueued:           {CommandDispatched},
+	CommandDispatched:       {CommandRunning, CommandSuccess, CommandFailed},
+	CommandRunning:          {CommandRunning, CommandSuccess, CommandFailed},
+}
+
+// CanTransitionTo reports whether command lifecycle allows moving to next.
+func (s CommandStatus) CanTransitionTo(next CommandStatus) bool {
+	for _, allowed := range commandTransitions[s] {
+		if allowed == next {
+			return true
+		}
+	}
+	return false
+}
+
+// IsTerminal reports whether command reached final state.
+func (s CommandStatus) IsTerminal() bool {
+	return s == CommandSuccess || s == CommandFailed || s == CommandRejected
+}
+
 // Device keeps metadata and last known state.
 type Device struct {
 	DeviceID        string       `json:"device_id"`
@@ -45,6 +74,48 @@ type Device struct {
 	LastTelemetry   *Telemetry   `json:"last_telemetry,omitempty"`
 	LastLocation    *Location    `json:"last_location,omitempty"`
 	Status          DeviceStatus `json:"status"`
+	// Capabilities is nil for probes that do not report them at register.
+	Capabilities *DeviceCapabilities `json:"capabilities,omitempty"`
+	// Targets lists MCUs wired to the probe through SWD mux, ordered by index.
+	Targets []*DeviceTarget `json:"targets,omitempty"`
+	// LastProgram is the latest successful swd_program without target, used
+	// by probes that declare no targets.
+	LastProgram *TargetProgram `json:"last_program,omitempty"`
+	// Labels group devices for schedules; set by operators.
+	Labels []string `json:"labels,omitempty"`
+}
+
+// DeviceTarget is one SWD target declared by probe plus its last known state.
+type DeviceTarget struct {
+	Index       int            `json:"index"`
+	Name        string         `json:"name"`
+	ExpectedMCU string         `json:"expected_mcu,omitempty"`
+	LastConnect *TargetConnect `json:"last_connect,omitempty"`
+	LastProgram *TargetProgram `json:"last_program,omitempty"`
+}
+
+// TargetConnect records outcome of the latest swd_connect on target.
+type TargetConnect struct {
+	CommandID string        `json:"command_id"`
+	Status    CommandStatus `json:"status"`
+	Message   string        `json:"message,omitempty"`
+	At        time.Time     `json:"at"`
+}
+
+// TargetProgram records the latest successful swd_program on target.
+type TargetProgram struct {
+	CommandID  string    `json:"command_id"`
+	ArtifactID string    `json:"artifact_id,omitempty"`
+	At         time.Time `json:"at"`
+}
+
+// DeviceCapabilities is what probe firmware reports it can do.
+type DeviceCapabilities struct {
+	// CommandTypes lists implemented command types; empty means not reported.
+	CommandTypes   []string  `json:"command_types,omitempty"`
+	MaxSWDClockHz  int       `json:"max_swd_clock_hz,omitempty"`
+	RAMBufferBytes int       `json:"ram_buffer_bytes,omitempty"`
+	ReportedAt     time.Time `json:"reported_at"`
 }
 
 // Telemetry stores periodic device metrics.
@@ -76,43 +147,401 @@ type TelemetryRecord struct {
 
 // CommandResult stores the device execution output.
 type CommandResult struct {
-	Status  CommandStatus          `json:"status"`
-	Message string                 `json:"message"`
-	Metrics map[string]interface{} `json:"metrics,omitempty"`
-	Data    map[string]interface{} `json:"data,omitempty"`
+	Status       CommandStatus          `json:"status"`
+	Message      string                 `json:"message"`
+	Metrics      map[string]interface{} `json:"metrics,omitempty"`
+	Data         map[string]interface{} `json:"data,omitempty"`
+	Verification *CommandVerification   `json:"verification,omitempty"`
+}
+
+// Verification methods for device-reported image digests.
+const (
+	VerificationSHA256 = "sha256"
+	VerificationCRC32  = "crc32"
+)
+
+// CommandVerification is server-side check of device-reported image digest.
+type CommandVerification struct {
+	Method     string `json:"method"`
+	ArtifactID string `json:"artifact_id,omitempty"`
+	// Segment is artifact segment index when digest covers one parsed segment.
+	Segment  *int   `json:"segment,omitempty"`
+	Offset   int64  `json:"offset,omitempty"`
+	Length   int64  `json:"length,omitempty"`
+	Expected string `json:"expected,omitempty"`
+	Reported string `json:"reported"`
+	Match    bool   `json:"match"`
+	// MatchedArtifactID names known artifact equal to read-back image (swd_copy_firmware).
+	MatchedArtifactID string `json:"matched_artifact_id,omitempty"`
+	// MatchedSegment is set when read-back equals one segment of MatchedArtifactID.
+	MatchedSegment *int `json:"matched_segment,omitempty"`
+}
+
+// CommandResultAttempt keeps a result report that conflicted with the accepted one.
+type CommandResultAttempt struct {
+	ReceivedAt time.Time     `json:"received_at"`
+	Result     CommandResult `json:"result"`
+}
+
+// Approval decisions for commands held in awaiting_approval.
+const (
+	ApprovalApproved = "approved"
+	ApprovalRejected = "rejected"
+)
+
+// CommandApproval records second-operator decision for destructive command.
+type CommandApproval struct {
+	Decision  string     `json:"decision,omitempty"`
+	DecidedBy string     `json:"decided_by,omitempty"`
+	DecidedAt *time.Time `json:"decided_at,omitempty"`
+	Reason    string     `json:"reason,omitempty"`
+}
+
+// CommandHold explains why a queued command is not dispatched yet.
+type CommandHold struct {
+	Reason    string    `json:"reason"`
+	Since     time.Time `json:"since"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// CommandOverride records operator decision to bypass dispatch interlocks.
+type CommandOverride struct {
+	By     string    `json:"by"`
+	At     time.Time `json:"at"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// CommandProgress stores latest intermediate report of a long-running command.
+type CommandProgress struct {
+	Percent      float64   `json:"percent"`
+	BytesWritten int64     `json:"bytes_written"`
+	BytesTotal   int64     `json:"bytes_total,omitempty"`
+	Phase        string    `json:"phase,omitempty"`
+	Message      string    `json:"message,omitempty"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// ResultBlob describes binary result payload uploaded by device in chunks.
+type ResultBlob struct {
+	Size        int64     `json:"size"`
+	TotalSize   int64     `json:"total_size"`
+	ContentType string    `json:"content_type"`
+	SHA256      string    `json:"sha256,omitempty"`
+	Complete    bool      `json:"complete"`
+	UpdatedAt   time.Time `json:"updated_at"`
+	ArtifactID  string    `json:"artifact_id,omitempty"`
 }
 
 // Command stores a queued SWD action.
 type Command struct {
-	CommandID    string          `json:"command_id"`
-	DeviceID     string          `json:"device_id"`
-	Type         string          `json:"type"`
-	Payload      json.RawMessage `json:"payload"`
-	CreatedBy    string          `json:"created_by"`
-	CreatedAt    time.Time       `json:"created_at"`
-	DispatchedAt *time.Time      `json:"dispatched_at,omitempty"`
-	CompletedAt  *time.Time      `json:"completed_at,omitempty"`
-	Status       CommandStatus   `json:"status"`
-	Result       *CommandResult  `json:"result,omitempty"`
-}
-
-// Artifact stores binary payload for program/copy operations.
+	CommandID    string           `json:"command_id"`
+	DeviceID     string           `json:"device_id"`
+	Type         string           `json:"type"`
+	Payload      json.RawMessage  `json:"payload"`
+	CreatedBy    string           `json:"created_by"`
+	CreatedAt    time.Time        `json:"created_at"`
+	DispatchedAt *time.Time       `json:"dispatched_at,omitempty"`
+	CompletedAt  *time.Time       `json:"completed_at,omitempty"`
+	Status       CommandStatus    `json:"status"`
+	Approval     *CommandApproval `json:"approval,omitempty"`
+	Hold         *CommandHold     `json:"hold,omitempty"`
+	// ScheduleID is set when command was spawned by recurring schedule;
+	// RequestedBy then names the schedule's author.
+	ScheduleID  string `json:"schedule_id,omitempty"`
+	RequestedBy string `json:"requested_by,omitempty"`
+	// Template names preset the payload was rendered from.
+	Template string `json:"template,omitempty"`
+	// Channel is set when payload named release channel instead of artifact_id.
+	Channel *CommandChannel `json:"channel,omitempty"`
+	// Target is SWD target index on multi-target probes.
+	Target *int `json:"target,omitempty"`
+	// InterlockOverride bypasses telemetry preflight rules at dispatch time.
+	InterlockOverride *CommandOverride `json:"interlock_override,omitempty"`
+	Progress          *CommandProgress `json:"progress,omitempty"`
+	Result            *CommandResult   `json:"result,omitempty"`
+	ResultBlob        *ResultBlob      `json:"result_blob,omitempty"`
+	// ResultAttempts records the latest late results that disagree with
+	// Result; ResultAttemptCount counts all of them.
+	ResultAttempts     []CommandResultAttempt `json:"result_attempts,omitempty"`
+	ResultAttemptCount int                    `json:"result_attempt_count,omitempty"`
+	// ReportedSHA256 digests the result as the device sent it, before server
+	// processing, so retries match even after artifacts changed.
+	ReportedSHA256 string `json:"reported_sha256,omitempty"`
+	// Stalled is computed on read: active command without recent progress.
+	Stalled bool `json:"stalled,omitempty"`
+}
+
+// Artifact is metadata of binary payload for program/copy operations; bytes
+// live in a file next to the state.
 type Artifact struct {
 	ArtifactID    string    `json:"artifact_id"`
 	Name          string    `json:"name"`
 	ContentType   string    `json:"content_type"`
 	CreatedBy     string    `json:"created_by"`
 	CreatedAt     time.Time `json:"created_at"`
-	Payload       []byte    `json:"payload"`
+	Size          int64     `json:"size"`
 	PayloadSHA256 string    `json:"payload_sha256"`
+	PayloadCRC32  string    `json:"payload_crc32"`
+	// Format is detected encoding: bin, ihex, elf, srec or uf2.
+	Format string `json:"format,omitempty"`
+	// EntryPoint is start address declared by HEX, S-record or ELF images.
+	EntryPoint *uint64 `json:"entry_point,omitempty"`
+	// Segments are memory regions decoded from addressed formats; each is
+	// stored as flat binary devices download separately.
+	Segments []ArtifactSegment `json:"segments,omitempty"`
+	// Version, TargetMCU, Description and Tags are catalogue metadata
+	// operators edit after upload.
+	Version     string     `json:"version,omitempty"`
+	TargetMCU   string     `json:"target_mcu,omitempty"`
+	Description string     `json:"description,omitempty"`
+	Tags        []string   `json:"tags,omitempty"`
+	UpdatedBy   string     `json:"updated_by,omitempty"`
+	UpdatedAt   *time.Time `json:"updated_at,omitempty"`
+	// Signature is verified release signature; nil for unsigned artifacts.
+	Signature *ArtifactSignature `json:"signature,omitempty"`
+	// Uploads lists every upload of these bytes. Identical re-uploads share
+	// the artifact, so their own name and uploader are kept here.
+	Uploads []ArtifactUpload `json:"uploads,omitempty"`
+	// Deltas are patches from older artifacts to this one, offered to probes
+	// whose last program used the base.
+	Deltas []ArtifactDelta `json:"deltas,omitempty"`
+	// Payload is only read from state files written before artifacts moved
+	// to blobs/artifacts/; load migrates it to disk and clears it.
+	Payload []byte `json:"payload,omitempty"`
+}
+
+// Artifact signature algorithms. SignatureEd25519 marks signatures verified
+// by earlier releases; new ones are Ed25519ph over the file's SHA-512.
+const (
+	SignatureEd25519   = "ed25519"
+	SignatureEd25519ph = "ed25519ph"
+)
+
+// ArtifactSignature is detached signature over the whole uploaded file, checked
+// against a configured release key before it is stored.
+type ArtifactSignature struct {
+	Algorithm string `json:"algorithm"`
+	KeyID     string `json:"key_id"`
+	// Signature is base64 of raw signature bytes.
+	Signature  string    `json:"signature"`
+	VerifiedAt time.Time `json:"verified_at"`
+	AttachedBy string    `json:"attached_by"`
+}
+
+// ArtifactDelta is binary patch turning base artifact into the one it is
+// stored on. Downloads and BytesSaved count non-ranged delta downloads.
+type ArtifactDelta struct {
+	BaseArtifactID string    `json:"base_artifact_id"`
+	Size           int64     `json:"size"`
+	SHA256         string    `json:"sha256"`
+	CreatedBy      string    `json:"created_by"`
+	CreatedAt      time.Time `json:"created_at"`
+	Downloads      int       `json:"downloads"`
+	BytesSaved     int64     `json:"bytes_saved"`
+}
+
+// Artifact reference kinds.
+const (
+	ArtifactRefCommand  = "command"
+	ArtifactRefSchedule = "schedule"
+	ArtifactRefTemplate = "template"
+	ArtifactRefDevice   = "device"
+	ArtifactRefChannel  = "channel"
+)
+
+// ArtifactReference is one place that names artifact. Active references
+// (unfinished commands, schedules, templates, channels, last program on a device)
+// keep artifact from garbage collection; finished commands are history only.
+type ArtifactReference struct {
+	Kind   string `json:"kind"`
+	ID     string `json:"id"`
+	Detail string `json:"detail,omitempty"`
+	Active bool   `json:"active"`
+}
+
+// ArtifactGCReport lists artifacts removed, or that would be with DryRun, by
+// one garbage collection pass.
+type ArtifactGCReport struct {
+	DryRun     bool             `json:"dry_run"`
+	RanAt      time.Time        `json:"ran_at"`
+	MinAge     string           `json:"min_age"`
+	Removed    []ArtifactGCItem `json:"removed"`
+	FreedBytes int64            `json:"freed_bytes"`
+	// Referenced counts artifacts old enough but kept by active references.
+	Referenced int   `json:"referenced"`
+	UsedBytes  int64 `json:"used_bytes"`
+}
+
+// ArtifactGCItem is one artifact selected by garbage collection.
+type ArtifactGCItem struct {
+	ArtifactID string    `json:"artifact_id"`
+	Name       string    `json:"name"`
+	Bytes      int64     `json:"bytes"`
+	LastUsedAt time.Time `json:"last_used_at"`
+}
+
+// ArtifactUpload is one upload that resolved to artifact.
+type ArtifactUpload struct {
+	Name      string    `json:"name"`
+	CreatedBy string    `json:"created_by"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// ArtifactSegment is one contiguous memory region of parsed artifact.
+type ArtifactSegment struct {
+	Index   int    `json:"index"`
+	Address uint64 `json:"address"`
+	Size    int64  `json:"size"`
+	SHA256  string `json:"sha256"`
+	CRC32   string `json:"crc32"`
+}
+
+// ArtifactManifest splits artifact, or one of its segments, into fixed-size
+// chunks so probes that cannot buffer the image verify and program piecewise.
+type ArtifactManifest struct {
+	ArtifactID string `json:"artifact_id"`
+	// Segment and Address are set when manifest covers one parsed segment.
+	Segment    *int    `json:"segment,omitempty"`
+	Address    *uint64 `json:"address,omitempty"`
+	Size       int64   `json:"size"`
+	SHA256     string  `json:"sha256"`
+	ChunkSize  int64   `json:"chunk_size"`
+	ChunkCount int     `json:"chunk_count"`
+	// First is index of Chunks[0]; long manifests are paged.
+	First  int             `json:"first"`
+	Chunks []ArtifactChunk `json:"chunks"`
+}
+
+// ArtifactChunk is one manifest entry.
+type ArtifactChunk struct {
+	Index  int    `json:"index"`
+	Offset int64  `json:"offset"`
+	Size   int64  `json:"size"`
+	CRC32  string `json:"crc32"`
+	SHA256 string `json:"sha256"`
+}
+
+// IdempotencyRecord remembers which resource a client Idempotency-Key created.
+type IdempotencyRecord struct {
+	Scope       string    `json:"scope"`
+	Key         string    `json:"key"`
+	Operator    string    `json:"operator"`
+	Fingerprint string    `json:"fingerprint"`
+	ResourceID  string    `json:"resource_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Device selectors in Schedule.DeviceIDs besides plain device ids:
+// ScheduleAllDevices targets every registered device and
+// ScheduleLabelPrefix+label every device carrying that label.
+const (
+	ScheduleAllDevices  = "*"
+	ScheduleLabelPrefix = "label:"
+)
+
+// ScheduleRun records one firing of recurring schedule.
+type ScheduleRun struct {
+	At         time.Time `json:"at"`
+	CommandIDs []string  `json:"command_ids"`
+	Errors     []string  `json:"errors,omitempty"`
+}
+
+// Schedule enqueues the same command for target devices at cron times (UTC).
+type Schedule struct {
+	ScheduleID string          `json:"schedule_id"`
+	Name       string          `json:"name"`
+	Cron       string          `json:"cron"`
+	DeviceIDs  []string        `json:"device_ids"`
+	Type       string          `json:"type"`
+	Payload    json.RawMessage `json:"payload"`
+	// Target applies to every device; needed for multi-target probes.
+	Target    *int       `json:"target,omitempty"`
+	Enabled   bool       `json:"enabled"`
+	CreatedBy string     `json:"created_by"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	NextRunAt *time.Time `json:"next_run_at,omitempty"`
+	LastRunAt *time.Time `json:"last_run_at,omitempty"`
+	// History keeps most recent runs, newest last.
+	History []ScheduleRun `json:"history,omitempty"`
+}
+
+// ReleaseChannel points named release track of a product (dev, beta,
+// stable, ...) at one artifact.
+type ReleaseChannel struct {
+	Product    string    `json:"product"`
+	Name       string    `json:"name"`
+	ArtifactID string    `json:"artifact_id"`
+	UpdatedBy  string    `json:"updated_by"`
+	UpdatedAt  time.Time `json:"updated_at"`
+	// History keeps most recent promotions, newest last.
+	History []ChannelPromotion `json:"history"`
+}
+
+// ChannelPromotion records one change of channel's artifact.
+type ChannelPromotion struct {
+	ArtifactID         string    `json:"artifact_id"`
+	PreviousArtifactID string    `json:"previous_artifact_id,omitempty"`
+	PromotedBy         string    `json:"promoted_by"`
+	PromotedAt         time.Time `json:"promoted_at"`
+	Reason             string    `json:"reason,omitempty"`
+}
+
+// CommandChannel records release channel command payload was resolved from.
+type CommandChannel struct {
+	Product    string `json:"product"`
+	Name       string `json:"name"`
+	ArtifactID string `json:"artifact_id"`
+}
+
+// TemplateParam declares one placeholder of command template.
+type TemplateParam struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	// Default is used when caller omits the param; nil makes it required.
+	Default json.RawMessage `json:"default,omitempty"`
+}
+
+// CommandTemplate is named command preset whose payload may contain
+// "{{param}}" placeholders filled in at instantiation.
+type CommandTemplate struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description,omitempty"`
+	Type        string          `json:"type"`
+	Payload     json.RawMessage `json:"payload"`
+	Params      []TemplateParam `json:"params,omitempty"`
+	CreatedBy   string          `json:"created_by"`
+	CreatedAt   time.Time       `json:"created_at"`
+	UpdatedAt   time.Time       `json:"updated_at"`
+}
+
+// CommandStats aggregates command outcomes for one group.
+type CommandStats struct {
+	Total    int                   `json:"total"`
+	ByStatus map[CommandStatus]int `json:"by_status"`
+	// SuccessRate is success / (success + failed); nil until something finished.
+	SuccessRate *float64 `json:"success_rate"`
+}
+
+// CommandSummary groups command outcomes fleet-wide.
+type CommandSummary struct {
+	Totals   CommandStats             `json:"totals"`
+	ByType   map[string]*CommandStats `json:"by_type"`
+	ByDevice map[string]*CommandStats `json:"by_device"`
 }
 
 // PersistedState keeps whole R1 server state snapshot.
 type PersistedState struct {
-	Devices       map[string]*Device           `json:"devices"`
-	TelemetryByID map[string][]TelemetryRecord `json:"telemetry_by_id"`
-	CommandsByID  map[string][]*Command        `json:"commands_by_id"`
-	Artifacts     map[string]*Artifact         `json:"artifacts"`
+	Devices         map[string]*Device            `json:"devices"`
+	TelemetryByID   map[string][]TelemetryRecord  `json:"telemetry_by_id"`
+	CommandsByID    map[string][]*Command         `json:"commands_by_id"`
+	Artifacts       map[string]*Artifact          `json:"artifacts"`
+	IdempotencyKeys map[string]*IdempotencyRecord `json:"idempotency_keys"`
+	Schedules       map[string]*Schedule          `json:"schedules"`
+	Templates       map[string]*CommandTemplate   `json:"templates"`
+	// Channels is keyed by "product/name".
+	Channels map[string]*ReleaseChannel `json:"channels"`
 }
 
 // CloneDevice creates copy that caller can mutate safely.
@@ -132,6 +561,39 @@ func CloneDevice(src *Device) *Device {
 		location := *src.LastLocation
 		out.LastLocation = &location
 	}
+	if src.Capabilities != nil {
+		capabilities := *src.Capabilities
+		capabilities.CommandTypes = append([]string(nil), src.Capabilities.CommandTypes...)
+		out.Capabilities = &capabilities
+	}
+	if src.LastProgram != nil {
+		program := *src.LastProgram
+		out.LastProgram = &program
+	}
+	out.Labels = append([]string(nil), src.Labels...)
+	if src.Targets != nil {
+		out.Targets = make([]*DeviceTarget, len(src.Targets))
+		for i, target := range src.Targets {
+			out.Targets[i] = CloneTarget(target)
+		}
+	}
+	return &out
+}
+
+// CloneTarget copies target together with its state records.
+func CloneTarget(src *DeviceTarget) *DeviceTarget {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	if src.LastConnect != nil {
+		connect := *src.LastConnect
+		out.LastConnect = &connect
+	}
+	if src.LastProgram != nil {
+		program := *src.LastProgram
+		out.LastProgram = &program
+	}
 	return &out
 }
 
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/store"
+)
+
+const (
+	defaultArtifactSearchLimit = 50
+	maxArtifactSearchLimit     = 200
+	maxArtifactTags            = 16
+	maxArtifactLabelLen        = 64
+	maxArtifactDescriptionLen  = 1024
+)
+
+// ArtifactSearchQuery filters artifact catalogue. Tags must all match; list
+// fields otherwise match any of their values.
+type ArtifactSearchQuery struct {
+	Text      string
+	Tags      []string
+	TargetMCU string
+	Version   string
+	Formats   []string
+	Operators []string
+	Cursor    string
+	Limit     int
+}
+
+// ArtifactSearchPage is one page of catalogue results.
+type ArtifactSearchPage struct {
+	Items      []*model.Artifact `json:"items"`
+	NextCursor string            `json:"next_cursor,omitempty"`
+}
+
+// OperatorArtifactMetadataRequest edits catalogue metadata; nil fields are kept.
+type OperatorArtifactMetadataRequest struct {
+	Version     *string   `json:"version"`
+	TargetMCU   *string   `json:"target_mcu"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+}
+
+// OperatorSearchArtifacts returns matching artifacts newest first.
+func (s *Service) OperatorSearchArtifacts(query ArtifactSearchQuery) (ArtifactSearchPage, error) {
+	filter := store.ArtifactFilter{
+		Text:      strings.TrimSpace(query.Text),
+		TargetMCU: strings.TrimSpace(query.TargetMCU),
+		Version:   strings.TrimSpace(query.Version),
+		Formats:   query.Formats,
+		CreatedBy: query.Operators,
+	}
+	for _, tag := range query.Tags {
+		filter.Tags = append(filter.Tags, strings.ToLower(strings.TrimSpace(tag)))
+	}
+
+	var after *store.ArtifactCursor
+	if query.Cursor != "" {
+		createdAt, artifactID, err := decodeCursor(query.Cursor)
+		if err != nil {
+			return ArtifactSearchPage{}, err
+		}
+		after = &store.ArtifactCursor{CreatedAt: createdAt, ArtifactID: artifactID}
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultArtifactSearchLimit
+	}
+	if limit > maxArtifactSearchLimit {
+		limit = maxArtifactSearchLimit
+	}
+
+	items, next := s.store.SearchArtifacts(filter, after, limit)
+	page := ArtifactSearchPage{Items: items}
+	if next != nil {
+		page.NextCursor = encodeCursor(next.CreatedAt, next.ArtifactID)
+	}
+	return page, nil
+}
+
+// OperatorUpdateArtifactMetadata edits version, target MCU, description and tags.
+func (s *Service) OperatorUpdateArtifactMetadata(artifactID string, req OperatorArtifactMetadataRequest, operator string) (*model.Artifact, error) {
+	return s.store.UpdateArtifact(strings.TrimSpace(artifactID), func(artifact *model.Artifact) error {
+		if req.Version != nil {
+			artifact.Version = strings.TrimSpace(*req.Version)
+		}
+		if req.TargetMCU != nil {
+			artifact.TargetMCU = strings.TrimSpace(*req.TargetMCU)
+		}
+		if req.Description != nil {
+			artifact.Description = strings.TrimSpace(*req.Description)
+		}
+		if req.Tags != nil {
+			tags, err := normalizeArtifactTags(*req.Tags)
+			if err != nil {
+				return err
+			}
+			artifact.Tags = tags
+		}
+
+		if len(artifact.Version) > maxArtifactLabelLen || len(artifact.TargetMCU) > maxArtifactLabelLen {
+			return fmt.Errorf("invalid metadata: version and target_mcu must be at most %d characters", maxArtifactLabelLen)
+		}
+		if len(artifact.Description) > maxArtifactDescriptionLen {
+			return fmt.Errorf("invalid metadata: description must be at most %d characters", maxArtifactDescriptionLen)
+		}
+		return nil
+	}, operator, s.nowFn().UTC())
+}
+
+// OperatorDeleteArtifact removes artifact unless an unfinished command uses it.
+func (s *Service) OperatorDeleteArtifact(artifactID string) error {
+	return s.store.DeleteArtifact(strings.TrimSpace(artifactID))
+}
+
+// normalizeArtifactTags trims, lowercases and deduplicates tags keeping order.
+func normalizeArtifactTags(raw []string) ([]string, error) {
+	var tags []string
+	for _, tag := range raw {
+		tag = strings.ToLower(strings.TrimSpace(tag))
+		if tag == "" || containsTag(tags, tag) {
+			continue
+		}
+		if len(tag) > maxArtifactLabelLen || strings.ContainsAny(tag, ", \t\n") {
+			return nil, fmt.Errorf("invalid tag %q: must be at most %d characters without spaces or commas", tag, maxArtifactLabelLen)
+		}
+		tags = append(tags, tag)
+	}
+	if len(tags) > maxArtifactTags {
+		return nil, fmt.Errorf("invalid tags: at most %d tags are supported", maxArtifactTags)
+	}
+	return tags, nil
+}
+
+func containsTag(tags []string, tag string) bool {
+	for _, existing := range tags {
+		if existing == tag {
+			return true
+		}
+	}
+	return false
+}
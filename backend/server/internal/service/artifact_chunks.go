@@ -0,0 +1,227 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+const (
+	defaultChunkSize = 4096
+	minChunkSize     = 256
+	maxChunkSize     = 64 * 1024
+	// defaultManifestPage keeps manifest JSON small enough for probe RAM.
+	defaultManifestPage = 256
+	maxManifestPage     = 1024
+	// maxCachedChunks bounds manifest entries kept by chunkDigests.
+	maxCachedChunks = 64 * 1024
+)
+
+// manifestKey identifies one chunk layout; segment is -1 for whole artifact.
+type manifestKey struct {
+	artifactID string
+	segment    int
+	chunkSize  int64
+}
+
+// DeviceChunkRequest selects artifact bytes for chunked download. Segment
+// narrows to one parsed segment; ChunkSize 0 derives size from device RAM.
+type DeviceChunkRequest struct {
+	DeviceID    string
+	DeviceToken string
+	ArtifactID  string
+	Segment     *int
+	ChunkSize   int64
+	// First and Count page manifest entries.
+	First int
+	Count int
+}
+
+// chunkSource is artifact or segment file being split into chunks.
+type chunkSource struct {
+	manifest model.ArtifactManifest
+	file     *os.File
+}
+
+// DeviceArtifactManifest returns chunk layout with CRC32 and SHA-256 per chunk.
+func (s *Service) DeviceArtifactManifest(req DeviceChunkRequest) (*model.ArtifactManifest, error) {
+	source, err := s.openChunkSource(req)
+	if err != nil {
+		return nil, err
+	}
+	defer source.file.Close()
+
+	manifest := source.manifest
+	first, count := req.First, req.Count
+	if count <= 0 {
+		count = defaultManifestPage
+	}
+	if count > maxManifestPage {
+		count = maxManifestPage
+	}
+	if first < 0 || (first >= manifest.ChunkCount && manifest.ChunkCount > 0) {
+		return nil, fmt.Errorf("invalid first: must be within 0..%d", manifest.ChunkCount-1)
+	}
+
+	chunks, err := s.chunkDigests(source)
+	if err != nil {
+		return nil, err
+	}
+	last := first + count
+	if last > len(chunks) {
+		last = len(chunks)
+	}
+	manifest.First = first
+	manifest.Chunks = append([]model.ArtifactChunk{}, chunks[first:last]...)
+	return &manifest, nil
+}
+
+// chunkDigests returns every manifest entry of source. Artifact bytes never
+// change, so entries are hashed once per layout and cached; the oldest
+// layouts are dropped past maxCachedChunks.
+func (s *Service) chunkDigests(source *chunkSource) ([]model.ArtifactChunk, error) {
+	key := manifestKey{artifactID: source.manifest.ArtifactID, segment: -1, chunkSize: source.manifest.ChunkSize}
+	if source.manifest.Segment != nil {
+		key.segment = *source.manifest.Segment
+	}
+	s.chunkMu.Lock()
+	chunks, ok := s.chunkCache[key]
+	s.chunkMu.Unlock()
+	if ok {
+		return chunks, nil
+	}
+
+	chunks = make([]model.ArtifactChunk, 0, source.manifest.ChunkCount)
+	for index := 0; index < source.manifest.ChunkCount; index++ {
+		chunk, _, err := readChunk(source, index)
+		if err != nil {
+			return nil, err
+		}
+		chunks = append(chunks, chunk)
+	}
+
+	s.chunkMu.Lock()
+	defer s.chunkMu.Unlock()
+	if _, ok := s.chunkCache[key]; ok || len(chunks) > maxCachedChunks {
+		return chunks, nil
+	}
+	for len(s.chunkOrder) > 0 && s.chunkCached+len(chunks) > maxCachedChunks {
+		oldest := s.chunkOrder[0]
+		s.chunkOrder = s.chunkOrder[1:]
+		s.chunkCached -= len(s.chunkCache[oldest])
+		delete(s.chunkCache, oldest)
+	}
+	s.chunkCache[key] = chunks
+	s.chunkOrder = append(s.chunkOrder, key)
+	s.chunkCached += len(chunks)
+	return chunks, nil
+}
+
+// DeviceArtifactChunk returns one chunk with its manifest entry.
+func (s *Service) DeviceArtifactChunk(req DeviceChunkRequest, index int) (*model.ArtifactChunk, []byte, error) {
+	source, err := s.openChunkSource(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer source.file.Close()
+
+	if index < 0 || index >= source.manifest.ChunkCount {
+		return nil, nil, fmt.Errorf("invalid chunk index: must be within 0..%d", source.manifest.ChunkCount-1)
+	}
+	chunk, data, err := readChunk(source, index)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &chunk, data, nil
+}
+
+func (s *Service) openChunkSource(req DeviceChunkRequest) (*chunkSource, error) {
+	device, err := s.authorizeArtifactRead(req.DeviceID, req.DeviceToken, req.ArtifactID)
+	if err != nil {
+		return nil, err
+	}
+	chunkSize, err := resolveChunkSize(req.ChunkSize, device)
+	if err != nil {
+		return nil, err
+	}
+
+	source := &chunkSource{manifest: model.ArtifactManifest{ChunkSize: chunkSize}}
+	if req.Segment != nil {
+		artifact, segment, file, err := s.store.OpenArtifactSegment(req.ArtifactID, *req.Segment)
+		if err != nil {
+			return nil, err
+		}
+		index, address := segment.Index, segment.Address
+		source.file = file
+		source.manifest.ArtifactID = artifact.ArtifactID
+		source.manifest.Segment = &index
+		source.manifest.Address = &address
+		source.manifest.Size = segment.Size
+		source.manifest.SHA256 = segment.SHA256
+	} else {
+		artifact, file, err := s.store.OpenArtifact(req.ArtifactID)
+		if err != nil {
+			return nil, err
+		}
+		source.file = file
+		source.manifest.ArtifactID = artifact.ArtifactID
+		source.manifest.Size = artifact.Size
+		source.manifest.SHA256 = artifact.PayloadSHA256
+	}
+	source.manifest.ChunkCount = int((source.manifest.Size + chunkSize - 1) / chunkSize)
+	return source, nil
+}
+
+// resolveChunkSize validates requested size; without one it picks the largest
+// power of two that fits the RAM buffer the probe reported, up to default.
+// Chunks never exceed that buffer.
+func resolveChunkSize(requested int64, device *model.Device) (int64, error) {
+	var buffer int64
+	if device.Capabilities != nil {
+		buffer = int64(device.Capabilities.RAMBufferBytes)
+	}
+	if buffer > 0 && buffer < minChunkSize {
+		return 0, fmt.Errorf("invalid chunk_size: device ram_buffer_bytes %d is below minimum chunk size %d", buffer, minChunkSize)
+	}
+	if requested != 0 {
+		if requested < minChunkSize || requested > maxChunkSize {
+			return 0, fmt.Errorf("invalid chunk_size: must be within %d..%d", minChunkSize, maxChunkSize)
+		}
+		if buffer > 0 && requested > buffer {
+			return 0, fmt.Errorf("invalid chunk_size: exceeds device ram_buffer_bytes %d", buffer)
+		}
+		return requested, nil
+	}
+
+	size := int64(defaultChunkSize)
+	for buffer > 0 && size > buffer {
+		size /= 2
+	}
+	return size, nil
+}
+
+func readChunk(source *chunkSource, index int) (model.ArtifactChunk, []byte, error) {
+	offset := int64(index) * source.manifest.ChunkSize
+	size := source.manifest.ChunkSize
+	if remaining := source.manifest.Size - offset; remaining < size {
+		size = remaining
+	}
+
+	data := make([]byte, size)
+	if _, err := io.ReadFull(io.NewSectionReader(source.file, offset, size), data); err != nil {
+		return model.ArtifactChunk{}, nil, fmt.Errorf("read chunk %d: %w", index, err)
+	}
+	digest := sha256.Sum256(data)
+	return model.ArtifactChunk{
+		Index:  index,
+		Offset: offset,
+		Size:   size,
+		CRC32:  fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)),
+		SHA256: hex.EncodeToString(digest[:]),
+	}, data, nil
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"lte_swd/backend/server/internal/delta"
+	"lte_swd/backend/server/internal/firmware"
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/store"
+)
+
+const (
+	// maxDeltaImageBytes bounds images diffed in memory.
+	maxDeltaImageBytes = 8 << 20
+	// maxPendingDeltas bounds patches queued by device requests.
+	maxPendingDeltas = 64
+)
+
+// deltaPair names patch from baseArtifactID to artifactID.
+type deltaPair struct {
+	artifactID     string
+	baseArtifactID string
+}
+
+// OperatorArtifactDeltaRequest asks for patch from base artifact.
+type OperatorArtifactDeltaRequest struct {
+	BaseArtifactID string `json:"base_artifact_id"`
+}
+
+// DeviceDeltaRequest asks for patch to artifact from what the probe last
+// programmed. Resume marks ranged continuation, which is not counted again.
+type DeviceDeltaRequest struct {
+	DeviceID    string
+	DeviceToken string
+	ArtifactID  string
+	Target      *int
+	Resume      bool
+}
+
+// OperatorCreateArtifactDelta builds, or returns already built, patch from
+// base artifact to artifactID.
+func (s *Service) OperatorCreateArtifactDelta(artifactID string, req OperatorArtifactDeltaRequest, operator string) (*model.ArtifactDelta, error) {
+	artifactID = strings.TrimSpace(artifactID)
+	baseArtifactID := strings.TrimSpace(req.BaseArtifactID)
+	if artifactID == "" || baseArtifactID == "" {
+		return nil, errors.New("artifact_id and base_artifact_id are required")
+	}
+	if artifactID == baseArtifactID {
+		return nil, errors.New("invalid base_artifact_id: must differ from artifact")
+	}
+	return s.buildArtifactDelta(deltaPair{artifactID: artifactID, baseArtifactID: baseArtifactID}, operator)
+}
+
+// DeviceArtifactDelta returns patch from the artifact the device (or its
+// target) last programmed successfully. ErrArtifactDeltaNotFound tells the
+// probe to download the full image: no base is known, base is gone, the
+// patch is not built yet or would not be smaller. A missing patch is queued
+// for runPendingDeltas rather than built on the request. Caller closes file.
+func (s *Service) DeviceArtifactDelta(req DeviceDeltaRequest) (*model.Artifact, *model.ArtifactDelta, *os.File, error) {
+	device, err := s.authorizeArtifactRead(req.DeviceID, req.DeviceToken, req.ArtifactID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	artifactID := strings.TrimSpace(req.ArtifactID)
+	baseArtifactID, err := lastProgrammedArtifact(device, req.Target)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if baseArtifactID == "" || baseArtifactID == artifactID {
+		return nil, nil, nil, store.ErrArtifactDeltaNotFound
+	}
+
+	artifact, patch, file, err := s.store.OpenArtifactDelta(artifactID, baseArtifactID)
+	if errors.Is(err, store.ErrArtifactDeltaNotFound) {
+		s.queueArtifactDelta(deltaPair{artifactID: artifactID, baseArtifactID: baseArtifactID}, "device:"+device.DeviceID)
+		return nil, nil, nil, err
+	}
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if patch.Size >= artifact.Size {
+		file.Close()
+		return nil, nil, nil, store.ErrArtifactDeltaNotFound
+	}
+	if !req.Resume {
+		if err := s.store.RecordArtifactDeltaDownload(artifactID, baseArtifactID); err != nil {
+			file.Close()
+			return nil, nil, nil, err
+		}
+	}
+	return artifact, patch, file, nil
+}
+
+// queueArtifactDelta remembers pair for the next runPendingDeltas when both
+// artifacts can be diffed; the queue is bounded and drops extra requests.
+func (s *Service) queueArtifactDelta(pair deltaPair, createdBy string) {
+	for _, artifactID := range []string{pair.artifactID, pair.baseArtifactID} {
+		artifact, err := s.store.GetArtifact(artifactID)
+		if err != nil || checkDeltaImage(artifact) != nil {
+			return
+		}
+	}
+
+	s.deltaMu.Lock()
+	defer s.deltaMu.Unlock()
+	if _, queued := s.deltaPending[pair]; !queued && len(s.deltaPending) < maxPendingDeltas {
+		s.deltaPending[pair] = createdBy
+	}
+}
+
+// runPendingDeltas builds patches devices asked for since the last pass.
+func (s *Service) runPendingDeltas() {
+	s.deltaMu.Lock()
+	pending := s.deltaPending
+	s.deltaPending = make(map[deltaPair]string)
+	s.deltaMu.Unlock()
+
+	for pair, createdBy := range pending {
+		if _, err := s.buildArtifactDelta(pair, createdBy); err != nil && !errors.Is(err, store.ErrArtifactNotFound) {
+			fmt.Fprintf(os.Stderr, "artifact delta %s from %s error: %v\n", pair.artifactID, pair.baseArtifactID, err)
+		}
+	}
+}
+
+// buildArtifactDelta diffs pair unless the patch exists. Builds run one at a
+// time, so concurrent requests for one pair produce a single patch.
+func (s *Service) buildArtifactDelta(pair deltaPair, createdBy string) (*model.ArtifactDelta, error) {
+	s.deltaBuildMu.Lock()
+	defer s.deltaBuildMu.Unlock()
+
+	artifact, err := s.store.GetArtifact(pair.artifactID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range artifact.Deltas {
+		if artifact.Deltas[i].BaseArtifactID == pair.baseArtifactID {
+			return &artifact.Deltas[i], nil
+		}
+	}
+	base, err := s.store.GetArtifact(pair.baseArtifactID)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range []*model.Artifact{base, artifact} {
+		if err := checkDeltaImage(item); err != nil {
+			return nil, err
+		}
+	}
+
+	baseData, err := s.readArtifact(pair.baseArtifactID)
+	if err != nil {
+		return nil, err
+	}
+	targetData, err := s.readArtifact(pair.artifactID)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.SaveArtifactDelta(pair.artifactID, pair.baseArtifactID, delta.Diff(baseData, targetData), createdBy, s.nowFn().UTC())
+}
+
+// checkDeltaImage allows raw bin images only: other formats store a
+// container, not the flash contents a probe patches, and whole images are
+// held in memory while diffing.
+func checkDeltaImage(artifact *model.Artifact) error {
+	if artifact.Format != "" && artifact.Format != string(firmware.FormatBinary) {
+		return fmt.Errorf("invalid artifact %s: deltas need %s images, got %s", artifact.ArtifactID, firmware.FormatBinary, artifact.Format)
+	}
+	if artifact.Size > maxDeltaImageBytes {
+		return fmt.Errorf("invalid artifact %s: %d bytes exceeds delta limit of %d", artifact.ArtifactID, artifact.Size, maxDeltaImageBytes)
+	}
+	return nil
+}
+
+func (s *Service) readArtifact(artifactID string) ([]byte, error) {
+	_, file, err := s.store.OpenArtifact(artifactID)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("read artifact %s: %w", artifactID, err)
+	}
+	return data, nil
+}
+
+// lastProgrammedArtifact returns artifact of the latest successful
+// swd_program on target, or on device when it declares no targets.
+func lastProgrammedArtifact(device *model.Device, target *int) (string, error) {
+	if target == nil {
+		if len(device.Targets) > 1 {
+			return "", errors.New("target is required for devices with several targets")
+		}
+		if len(device.Targets) == 1 {
+			target = &device.Targets[0].Index
+		} else if device.LastProgram != nil {
+			return device.LastProgram.ArtifactID, nil
+		} else {
+			return "", nil
+		}
+	}
+	for _, item := range device.Targets {
+		if item.Index == *target {
+			if item.LastProgram == nil {
+				return "", nil
+			}
+			return item.LastProgram.ArtifactID, nil
+		}
+	}
+	return "", fmt.Errorf("invalid target %d: not declared by device", *target)
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// ArtifactGCRequest runs one collection pass; MinAge overrides ARTIFACT_GC_MIN_AGE.
+type ArtifactGCRequest struct {
+	DryRun bool   `json:"dry_run"`
+	MinAge string `json:"min_age"`
+}
+
+// ArtifactStorage reports artifact bytes against quota and GC settings.
+type ArtifactStorage struct {
+	UsedBytes  int64  `json:"used_bytes"`
+	QuotaBytes int64  `json:"quota_bytes"`
+	GCMinAge   string `json:"gc_min_age"`
+	// GCInterval is empty when collection only runs on demand.
+	GCInterval string `json:"gc_interval,omitempty"`
+	// LastGC is latest pass that removed artifacts or ran on schedule.
+	LastGC *model.ArtifactGCReport `json:"last_gc,omitempty"`
+}
+
+// OperatorArtifactStorage returns storage usage and GC configuration.
+func (s *Service) OperatorArtifactStorage() ArtifactStorage {
+	storage := ArtifactStorage{
+		UsedBytes:  s.store.ArtifactUsedBytes(),
+		QuotaBytes: s.cfg.ArtifactQuotaBytes,
+		GCMinAge:   s.cfg.ArtifactGCMinAge.String(),
+	}
+	if s.cfg.ArtifactGCInterval > 0 {
+		storage.GCInterval = s.cfg.ArtifactGCInterval.String()
+	}
+	s.gcMu.Lock()
+	storage.LastGC = s.lastArtifactGC
+	s.gcMu.Unlock()
+	return storage
+}
+
+// OperatorArtifactReferences lists what names artifact and whether it pins it.
+func (s *Service) OperatorArtifactReferences(artifactID string) ([]model.ArtifactReference, error) {
+	return s.store.ArtifactReferences(strings.TrimSpace(artifactID))
+}
+
+// OperatorCollectArtifacts runs garbage collection now; dry run only reports.
+func (s *Service) OperatorCollectArtifacts(req ArtifactGCRequest) (*model.ArtifactGCReport, error) {
+	minAge := s.cfg.ArtifactGCMinAge
+	if raw := strings.TrimSpace(req.MinAge); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid min_age: must be positive duration such as 720h")
+		}
+		minAge = parsed
+	}
+	return s.collectArtifacts(minAge, req.DryRun)
+}
+
+func (s *Service) collectArtifacts(minAge time.Duration, dryRun bool) (*model.ArtifactGCReport, error) {
+	report, err := s.store.CollectArtifacts(minAge, dryRun, s.nowFn().UTC())
+	if err != nil {
+		return nil, err
+	}
+	if !dryRun {
+		s.gcMu.Lock()
+		s.lastArtifactGC = report
+		s.gcMu.Unlock()
+	}
+	return report, nil
+}
+
+// runArtifactGC collects artifacts when ARTIFACT_GC_INTERVAL has elapsed;
+// first pass runs one interval after start.
+func (s *Service) runArtifactGC(now time.Time) {
+	if s.cfg.ArtifactGCInterval <= 0 {
+		return
+	}
+	if s.nextArtifactGC.IsZero() {
+		s.nextArtifactGC = now.Add(s.cfg.ArtifactGCInterval)
+		return
+	}
+	if now.Before(s.nextArtifactGC) {
+		return
+	}
+	s.nextArtifactGC = now.Add(s.cfg.ArtifactGCInterval)
+
+	report, err := s.collectArtifacts(s.cfg.ArtifactGCMinAge, false)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "artifact gc error: %v\n", err)
+		return
+	}
+	if len(report.Removed) > 0 {
+		fmt.Fprintf(os.Stderr, "artifact gc removed %d artifacts, freed %d bytes\n", len(report.Removed), report.FreedBytes)
+	}
+}
@@ -0,0 +1,73 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// normalizeCapabilities trims reported command types and rejects negative limits.
+func normalizeCapabilities(capabilities *model.DeviceCapabilities) (*model.DeviceCapabilities, error) {
+	if capabilities == nil {
+		return nil, nil
+	}
+	if capabilities.MaxSWDClockHz < 0 || capabilities.RAMBufferBytes < 0 {
+		return nil, errors.New("invalid capabilities: limits must not be negative")
+	}
+
+	out := *capabilities
+	out.CommandTypes = nil
+	seen := make(map[string]struct{}, len(capabilities.CommandTypes))
+	for _, commandType := range capabilities.CommandTypes {
+		commandType = strings.TrimSpace(commandType)
+		if commandType == "" {
+			continue
+		}
+		if _, dup := seen[commandType]; dup {
+			continue
+		}
+		seen[commandType] = struct{}{}
+		out.CommandTypes = append(out.CommandTypes, commandType)
+	}
+	return &out, nil
+}
+
+// checkDeviceCapabilities rejects commands the probe said it cannot run.
+// Devices that reported no capabilities are not restricted.
+func checkDeviceCapabilities(device *model.Device, commandType string, payload json.RawMessage) error {
+	capabilities := device.Capabilities
+	if capabilities == nil {
+		return nil
+	}
+
+	if len(capabilities.CommandTypes) > 0 {
+		supported := false
+		for _, item := range capabilities.CommandTypes {
+			if item == commandType {
+				supported = true
+				break
+			}
+		}
+		if !supported {
+			return fmt.Errorf("unsupported command type %s on device %s", commandType, device.DeviceID)
+		}
+	}
+
+	var limits struct {
+		SWDClockHz *int `json:"swd_clock_hz"`
+		Length     *int `json:"length"`
+	}
+	if err := json.Unmarshal(payload, &limits); err != nil {
+		return nil
+	}
+	if capabilities.MaxSWDClockHz > 0 && limits.SWDClockHz != nil && *limits.SWDClockHz > capabilities.MaxSWDClockHz {
+		return fmt.Errorf("invalid payload: swd_clock_hz %d exceeds device maximum %d", *limits.SWDClockHz, capabilities.MaxSWDClockHz)
+	}
+	if capabilities.RAMBufferBytes > 0 && commandType == "swd_write_memory" && limits.Length != nil && *limits.Length > capabilities.RAMBufferBytes {
+		return fmt.Errorf("invalid payload: length %d exceeds device RAM buffer of %d bytes", *limits.Length, capabilities.RAMBufferBytes)
+	}
+	return nil
+}
@@ -0,0 +1,140 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/store"
+)
+
+const maxChannelLabelLen = 64
+
+// OperatorChannelPromotion points channel at artifact. ExpectedArtifactID
+// guards against concurrent promotion; "" means channel must be new.
+type OperatorChannelPromotion struct {
+	ArtifactID         string  `json:"artifact_id"`
+	ExpectedArtifactID *string `json:"expected_artifact_id"`
+	Reason             string  `json:"reason"`
+}
+
+// OperatorPromoteChannel atomically moves channel and records it in history.
+func (s *Service) OperatorPromoteChannel(product, name string, req OperatorChannelPromotion, operator string) (*model.ReleaseChannel, error) {
+	product, name, err := channelLabels(product, name)
+	if err != nil {
+		return nil, err
+	}
+	artifactID := strings.TrimSpace(req.ArtifactID)
+	if artifactID == "" {
+		return nil, errors.New("artifact_id is required")
+	}
+	var expected *string
+	if req.ExpectedArtifactID != nil {
+		trimmed := strings.TrimSpace(*req.ExpectedArtifactID)
+		expected = &trimmed
+	}
+	return s.store.PromoteChannel(product, name, artifactID, expected, operator, strings.TrimSpace(req.Reason), s.nowFn().UTC())
+}
+
+// OperatorGetChannel returns channel with its promotion history.
+func (s *Service) OperatorGetChannel(product, name string) (*model.ReleaseChannel, error) {
+	product, name, err := channelLabels(product, name)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.GetChannel(product, name)
+}
+
+// OperatorListChannels returns channels, optionally of one product.
+func (s *Service) OperatorListChannels(product string) []*model.ReleaseChannel {
+	return s.store.ListChannels(strings.ToLower(strings.TrimSpace(product)))
+}
+
+// OperatorDeleteChannel removes channel.
+func (s *Service) OperatorDeleteChannel(product, name string) error {
+	product, name, err := channelLabels(product, name)
+	if err != nil {
+		return err
+	}
+	return s.store.DeleteChannel(product, name)
+}
+
+// resolveChannelPayload replaces "channel" (and optional "product") in
+// payload with artifact_id the channel points at now. Without product the
+// channel name must be unique across products. Payloads without channel are
+// returned unchanged with nil channel.
+func (s *Service) resolveChannelPayload(payload json.RawMessage) (json.RawMessage, *model.CommandChannel, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil || fields == nil {
+		return payload, nil, nil
+	}
+	rawChannel, ok := fields["channel"]
+	if !ok {
+		return payload, nil, nil
+	}
+	if _, ok := fields["artifact_id"]; ok {
+		return nil, nil, errors.New("invalid payload: channel and artifact_id are mutually exclusive")
+	}
+
+	var name, product string
+	if err := json.Unmarshal(rawChannel, &name); err != nil {
+		return nil, nil, errors.New("invalid payload: channel must be a string")
+	}
+	if rawProduct, ok := fields["product"]; ok {
+		if err := json.Unmarshal(rawProduct, &product); err != nil {
+			return nil, nil, errors.New("invalid payload: product must be a string")
+		}
+	}
+
+	var channel *model.ReleaseChannel
+	if strings.TrimSpace(product) != "" {
+		product, name, err := channelLabels(product, name)
+		if err != nil {
+			return nil, nil, err
+		}
+		if channel, err = s.store.GetChannel(product, name); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		name = strings.ToLower(strings.TrimSpace(name))
+		matches := s.store.FindChannels(name)
+		switch len(matches) {
+		case 0:
+			return nil, nil, fmt.Errorf("%w: %s", store.ErrChannelNotFound, name)
+		case 1:
+			channel = matches[0]
+		default:
+			products := make([]string, 0, len(matches))
+			for _, match := range matches {
+				products = append(products, match.Product)
+			}
+			return nil, nil, fmt.Errorf("product is required: channel %s exists for %s", name, strings.Join(products, ", "))
+		}
+	}
+
+	delete(fields, "channel")
+	delete(fields, "product")
+	fields["artifact_id"], _ = json.Marshal(channel.ArtifactID)
+	resolved, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, fmt.Errorf("encode payload: %w", err)
+	}
+	return resolved, &model.CommandChannel{Product: channel.Product, Name: channel.Name, ArtifactID: channel.ArtifactID}, nil
+}
+
+// channelLabels normalizes product and channel names to lowercase labels.
+func channelLabels(product, name string) (string, string, error) {
+	product = strings.ToLower(strings.TrimSpace(product))
+	name = strings.ToLower(strings.TrimSpace(name))
+	if product == "" || name == "" {
+		return "", "", errors.New("product and channel are required")
+	}
+	for _, label := range []string{product, name} {
+		if len(label) > maxChannelLabelLen || strings.Trim(label, "abcdefghijklmnopqrstuvwxyz0123456789._-") != "" {
+			return "", "", fmt.Errorf("invalid label %q: use at most %d of a-z, 0-9, '.', '_', '-'", label, maxChannelLabelLen)
+		}
+	}
+	return product, name, nil
+}
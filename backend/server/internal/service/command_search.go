@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/store"
+)
+
+const (
+	defaultCommandSearchLimit = 100
+	maxCommandSearchLimit     = 500
+)
+
+var knownCommandStatuses = map[model.CommandStatus]struct{}{
+	model.CommandAwaitingApproval: {},
+	model.CommandRejected:         {},
+	model.CommandQueued:           {},
+	model.CommandDispatched:       {},
+	model.CommandRunning:          {},
+	model.CommandSuccess:          {},
+	model.CommandFailed:           {},
+}
+
+// CommandSearchQuery filters fleet-wide command history. List fields match any
+// of their values; DeviceIDs also takes "*" and "label:<name>" selectors.
+// Since/Until are RFC3339 bounds on created_at.
+type CommandSearchQuery struct {
+	DeviceIDs  []string
+	Statuses   []string
+	Types      []string
+	Operators  []string
+	ScheduleID string
+	Since      string
+	Until      string
+	Cursor     string
+	Limit      int
+}
+
+// CommandSearchPage is one page of search results.
+type CommandSearchPage struct {
+	Items      []*model.Command `json:"items"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
+
+// OperatorSearchCommands returns matching commands newest first.
+func (s *Service) OperatorSearchCommands(query CommandSearchQuery) (CommandSearchPage, error) {
+	filter, err := s.buildCommandFilter(query)
+	if err != nil {
+		return CommandSearchPage{}, err
+	}
+
+	var after *store.CommandCursor
+	if query.Cursor != "" {
+		cursor, err := decodeCommandCursor(query.Cursor)
+		if err != nil {
+			return CommandSearchPage{}, err
+		}
+		after = &cursor
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultCommandSearchLimit
+	}
+	if limit > maxCommandSearchLimit {
+		limit = maxCommandSearchLimit
+	}
+
+	items, next := s.store.SearchCommands(filter, after, limit)
+	s.markStalled(items)
+
+	page := CommandSearchPage{Items: items}
+	if next != nil {
+		page.NextCursor = encodeCommandCursor(*next)
+	}
+	return page, nil
+}
+
+// OperatorCommandSummary reports counts and success rate per type and device.
+func (s *Service) OperatorCommandSummary(query CommandSearchQuery) (model.CommandSummary, error) {
+	filter, err := s.buildCommandFilter(query)
+	if err != nil {
+		return model.CommandSummary{}, err
+	}
+	return s.store.SummarizeCommands(filter), nil
+}
+
+func (s *Service) buildCommandFilter(query CommandSearchQuery) (store.CommandFilter, error) {
+	filter := store.CommandFilter{
+		Types:      query.Types,
+		CreatedBy:  query.Operators,
+		ScheduleID: strings.TrimSpace(query.ScheduleID),
+	}
+
+	// "*" keeps commands of removed devices too; labels match current devices.
+	if len(query.DeviceIDs) > 0 && !containsTag(query.DeviceIDs, model.ScheduleAllDevices) {
+		for _, selector := range query.DeviceIDs {
+			if label, ok := strings.CutPrefix(selector, model.ScheduleLabelPrefix); ok {
+				if err := validateDeviceLabel(label); err != nil {
+					return filter, err
+				}
+			}
+		}
+		deviceIDs, err := s.resolveDeviceSelectors(query.DeviceIDs, s.nowFn().UTC())
+		if err != nil {
+			return filter, err
+		}
+		filter.DeviceIDs = deviceIDs
+	}
+
+	for _, raw := range query.Statuses {
+		status := model.CommandStatus(raw)
+		if _, ok := knownCommandStatuses[status]; !ok {
+			return filter, fmt.Errorf("invalid status filter: %s", raw)
+		}
+		filter.Statuses = append(filter.Statuses, status)
+	}
+
+	var err error
+	if filter.Since, err = parseTimeBound("since", query.Since); err != nil {
+		return filter, err
+	}
+	if filter.Until, err = parseTimeBound("until", query.Until); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseTimeBound(name, raw string) (time.Time, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s: expected RFC3339 time", name)
+	}
+	return parsed.UTC(), nil
+}
+
+func encodeCommandCursor(cursor store.CommandCursor) string {
+	return encodeCursor(cursor.CreatedAt, cursor.CommandID)
+}
+
+func decodeCommandCursor(encoded string) (store.CommandCursor, error) {
+	createdAt, commandID, err := decodeCursor(encoded)
+	return store.CommandCursor{CreatedAt: createdAt, CommandID: commandID}, err
+}
+
+// Cursors are opaque to clients: base64 of "<created_at unix nanos>:<id>".
+func encodeCursor(createdAt time.Time, id string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(encoded string) (time.Time, string, error) {
+	invalid := errors.New("invalid cursor")
+
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	nanosRaw, id, ok := strings.Cut(string(raw), ":")
+	if !ok || id == "" {
+		return time.Time{}, "", invalid
+	}
+	nanos, err := strconv.ParseInt(nanosRaw, 10, 64)
+	if err != nil {
+		return time.Time{}, "", invalid
+	}
+	return time.Unix(0, nanos).UTC(), id, nil
+}
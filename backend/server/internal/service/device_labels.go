@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+const (
+	maxDeviceLabels   = 16
+	maxDeviceLabelLen = 64
+)
+
+// OperatorDeviceLabelsRequest replaces labels of one device.
+type OperatorDeviceLabelsRequest struct {
+	Labels []string `json:"labels"`
+}
+
+// OperatorSetDeviceLabels replaces device labels; schedules target labelled
+// devices through "label:<name>" selectors.
+func (s *Service) OperatorSetDeviceLabels(deviceID string, req OperatorDeviceLabelsRequest) (*model.Device, error) {
+	labels, err := normalizeDeviceLabels(req.Labels)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.SetDeviceLabels(strings.TrimSpace(deviceID), labels)
+}
+
+// normalizeDeviceLabels lowercases, dedupes and sorts labels.
+func normalizeDeviceLabels(raw []string) ([]string, error) {
+	var labels []string
+	for _, label := range raw {
+		label = strings.ToLower(strings.TrimSpace(label))
+		if label == "" || containsTag(labels, label) {
+			continue
+		}
+		if err := validateDeviceLabel(label); err != nil {
+			return nil, err
+		}
+		labels = append(labels, label)
+	}
+	if len(labels) > maxDeviceLabels {
+		return nil, fmt.Errorf("invalid labels: at most %d labels are supported", maxDeviceLabels)
+	}
+	sort.Strings(labels)
+	return labels, nil
+}
+
+func validateDeviceLabel(label string) error {
+	if label == "" {
+		return fmt.Errorf("invalid label: must not be empty")
+	}
+	if len(label) > maxDeviceLabelLen {
+		return fmt.Errorf("invalid label %q: must be at most %d characters", label, maxDeviceLabelLen)
+	}
+	for _, r := range label {
+		if (r < 'a' || r > 'z') && (r < '0' || r > '9') && r != '-' && r != '_' && r != '.' {
+			return fmt.Errorf("invalid label %q: use lowercase letters, digits, '-', '_' or '.'", label)
+		}
+	}
+	return nil
+}
+
+func hasLabel(device *model.Device, label string) bool {
+	return containsTag(device.Labels, label)
+}
+
+// resolveDeviceSelectors expands "*" and "label:" selectors used by
+// schedules and command search into device ids, each device once, in
+// selector order. Plain ids pass through unchecked.
+func (s *Service) resolveDeviceSelectors(selectors []string, now time.Time) ([]string, error) {
+	var devices []*model.Device
+	out := make([]string, 0, len(selectors))
+	seen := make(map[string]struct{}, len(selectors))
+	add := func(deviceID string) {
+		if _, dup := seen[deviceID]; !dup {
+			seen[deviceID] = struct{}{}
+			out = append(out, deviceID)
+		}
+	}
+
+	for _, selector := range selectors {
+		label, byLabel := strings.CutPrefix(selector, model.ScheduleLabelPrefix)
+		if selector != model.ScheduleAllDevices && !byLabel {
+			add(selector)
+			continue
+		}
+		if devices == nil {
+			listed, err := s.store.ListDevices(now, s.cfg.DeviceOfflineAfter)
+			if err != nil {
+				return nil, err
+			}
+			devices = listed
+		}
+		for _, device := range devices {
+			if !byLabel || hasLabel(device, label) {
+				add(device.DeviceID)
+			}
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// interlocksEnabled reports whether at least one telemetry rule is configured.
+func (s *Service) interlocksEnabled() bool {
+	rules := s.cfg.Interlocks
+	return rules.MinBatteryMV > 0 || rules.MinSupplyMV > 0 || rules.MinRSSIDBM < 0 || rules.BlockRoaming
+}
+
+// dispatchGate holds risky commands while last telemetry violates preflight
+// rules. Operator override on the command skips all rules.
+func (s *Service) dispatchGate(device *model.Device, command *model.Command, now time.Time) string {
+	if command.InterlockOverride != nil || !s.interlocksEnabled() {
+		return ""
+	}
+	if _, ok := s.interlockTypes[command.Type]; !ok {
+		return ""
+	}
+
+	rules := s.cfg.Interlocks
+	telemetry := device.LastTelemetry
+	if telemetry == nil {
+		return "interlock: no telemetry received yet"
+	}
+	if rules.MaxTelemetryAge > 0 && now.Sub(device.LastTelemetryAt) > rules.MaxTelemetryAge {
+		return fmt.Sprintf("interlock: telemetry older than %s", rules.MaxTelemetryAge)
+	}
+	if rules.MinBatteryMV > 0 && telemetry.BatteryMV < rules.MinBatteryMV {
+		return fmt.Sprintf("interlock: battery_mv %d below %d", telemetry.BatteryMV, rules.MinBatteryMV)
+	}
+	if rules.MinSupplyMV > 0 && telemetry.SupplyMV < rules.MinSupplyMV {
+		return fmt.Sprintf("interlock: supply_mv %d below %d", telemetry.SupplyMV, rules.MinSupplyMV)
+	}
+	if rules.MinRSSIDBM < 0 && telemetry.RSSIDBM < rules.MinRSSIDBM {
+		return fmt.Sprintf("interlock: rssi_dbm %d below %d", telemetry.RSSIDBM, rules.MinRSSIDBM)
+	}
+	if rules.BlockRoaming && strings.Contains(strings.ToLower(telemetry.NetworkState), "roam") {
+		return fmt.Sprintf("interlock: network_state %q is roaming", telemetry.NetworkState)
+	}
+	return ""
+}
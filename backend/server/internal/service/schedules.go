@@ -0,0 +1,258 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/cron"
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/store"
+)
+
+const (
+	scheduleCreatorPrefix = "schedule:"
+	defaultPreviewRuns    = 5
+	maxPreviewRuns        = 50
+)
+
+// OperatorScheduleRequest describes recurring command schedule.
+type OperatorScheduleRequest struct {
+	Name      string          `json:"name"`
+	Cron      string          `json:"cron"`
+	DeviceIDs []string        `json:"device_ids"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Target    *int            `json:"target"`
+	Enabled   *bool           `json:"enabled"`
+}
+
+// OperatorScheduleUpdateRequest changes only the fields that are present.
+type OperatorScheduleUpdateRequest struct {
+	Name      *string         `json:"name"`
+	Cron      *string         `json:"cron"`
+	DeviceIDs []string        `json:"device_ids"`
+	Type      *string         `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Target    *int            `json:"target"`
+	Enabled   *bool           `json:"enabled"`
+}
+
+// OperatorCreateSchedule stores schedule; it is enabled unless told otherwise.
+func (s *Service) OperatorCreateSchedule(req OperatorScheduleRequest, operator string) (*model.Schedule, error) {
+	draft := model.Schedule{
+		Name:      strings.TrimSpace(req.Name),
+		Cron:      strings.TrimSpace(req.Cron),
+		DeviceIDs: req.DeviceIDs,
+		Type:      strings.TrimSpace(req.Type),
+		Payload:   req.Payload,
+		Target:    req.Target,
+		Enabled:   req.Enabled == nil || *req.Enabled,
+		CreatedBy: operator,
+	}
+
+	now := s.nowFn().UTC()
+	if err := s.prepareSchedule(&draft, now); err != nil {
+		return nil, err
+	}
+	return s.store.CreateSchedule(draft, now)
+}
+
+// OperatorUpdateSchedule edits schedule or toggles it on and off. Re-enabled
+// schedules start from the next slot after now; missed runs are not replayed.
+// The edit is validated outside the store lock and written back only if the
+// schedule was not changed meanwhile.
+func (s *Service) OperatorUpdateSchedule(scheduleID string, req OperatorScheduleUpdateRequest) (*model.Schedule, error) {
+	schedule, err := s.store.GetSchedule(strings.TrimSpace(scheduleID))
+	if err != nil {
+		return nil, err
+	}
+	expected := schedule.UpdatedAt
+
+	if req.Name != nil {
+		schedule.Name = strings.TrimSpace(*req.Name)
+	}
+	if req.Cron != nil {
+		schedule.Cron = strings.TrimSpace(*req.Cron)
+	}
+	if req.DeviceIDs != nil {
+		schedule.DeviceIDs = req.DeviceIDs
+	}
+	if req.Type != nil {
+		schedule.Type = strings.TrimSpace(*req.Type)
+	}
+	if req.Payload != nil {
+		schedule.Payload = req.Payload
+	}
+	if req.Target != nil {
+		schedule.Target = req.Target
+	}
+	if req.Enabled != nil {
+		schedule.Enabled = *req.Enabled
+	}
+
+	now := s.nowFn().UTC()
+	if err := s.prepareSchedule(schedule, now); err != nil {
+		return nil, err
+	}
+	return s.store.UpdateSchedule(*schedule, expected, now)
+}
+
+// OperatorDeleteSchedule removes schedule; spawned commands are kept.
+func (s *Service) OperatorDeleteSchedule(scheduleID string) error {
+	return s.store.DeleteSchedule(strings.TrimSpace(scheduleID))
+}
+
+// OperatorGetSchedule returns schedule with its run history.
+func (s *Service) OperatorGetSchedule(scheduleID string) (*model.Schedule, error) {
+	return s.store.GetSchedule(strings.TrimSpace(scheduleID))
+}
+
+// OperatorListSchedules returns all schedules.
+func (s *Service) OperatorListSchedules() []*model.Schedule {
+	return s.store.ListSchedules()
+}
+
+// PreviewCron returns next count activations of spec after now.
+func (s *Service) PreviewCron(spec string, count int) ([]time.Time, error) {
+	parsed, err := parseCron(spec)
+	if err != nil {
+		return nil, err
+	}
+	if count <= 0 {
+		count = defaultPreviewRuns
+	}
+	if count > maxPreviewRuns {
+		count = maxPreviewRuns
+	}
+	return parsed.NextN(s.nowFn().UTC(), count), nil
+}
+
+// OperatorPreviewSchedule returns upcoming activations of stored schedule.
+func (s *Service) OperatorPreviewSchedule(scheduleID string, count int) ([]time.Time, error) {
+	schedule, err := s.store.GetSchedule(strings.TrimSpace(scheduleID))
+	if err != nil {
+		return nil, err
+	}
+	return s.PreviewCron(schedule.Cron, count)
+}
+
+func (s *Service) prepareSchedule(schedule *model.Schedule, now time.Time) error {
+	if schedule.Cron == "" || schedule.Type == "" {
+		return errors.New("cron and type are required")
+	}
+	commandType, ok := s.types.Lookup(schedule.Type)
+	if !ok {
+		return fmt.Errorf("unsupported command type: %s", schedule.Type)
+	}
+	// Runs inherit the author for approval, see createCommand.
+	if _, gated := s.approvalTypes[schedule.Type]; gated && schedule.CreatedBy == auth.SharedOperatorName {
+		return store.ErrNamedOperatorRequired
+	}
+	if len(schedule.Payload) == 0 {
+		schedule.Payload = json.RawMessage(`{}`)
+	}
+	if !json.Valid(schedule.Payload) {
+		return errors.New("payload must be valid json")
+	}
+	// Channel stays in stored payload so every run takes the channel's
+	// current artifact; resolving now only rejects unknown channels early.
+	resolved, _, err := s.resolveChannelPayload(schedule.Payload)
+	if err != nil {
+		return err
+	}
+	if err := commandType.ValidatePayload(resolved); err != nil {
+		return err
+	}
+
+	deviceIDs := make([]string, 0, len(schedule.DeviceIDs))
+	seen := make(map[string]struct{}, len(schedule.DeviceIDs))
+	for _, deviceID := range schedule.DeviceIDs {
+		deviceID = strings.TrimSpace(deviceID)
+		if deviceID == "" {
+			continue
+		}
+		if _, dup := seen[deviceID]; dup {
+			continue
+		}
+		seen[deviceID] = struct{}{}
+		switch {
+		case deviceID == model.ScheduleAllDevices:
+		case strings.HasPrefix(deviceID, model.ScheduleLabelPrefix):
+			// Label may match no device yet; runs pick up devices labelled later.
+			if err := validateDeviceLabel(strings.TrimPrefix(deviceID, model.ScheduleLabelPrefix)); err != nil {
+				return err
+			}
+		default:
+			if _, err := s.store.GetDevice(deviceID, now, s.cfg.DeviceOfflineAfter); err != nil {
+				return fmt.Errorf("device %s: %w", deviceID, err)
+			}
+		}
+		deviceIDs = append(deviceIDs, deviceID)
+	}
+	if len(deviceIDs) == 0 {
+		return errors.New("device_ids are required")
+	}
+	schedule.DeviceIDs = deviceIDs
+
+	parsed, err := parseCron(schedule.Cron)
+	if err != nil {
+		return err
+	}
+	schedule.NextRunAt = nil
+	if schedule.Enabled {
+		next := parsed.Next(now)
+		if next.IsZero() {
+			return errors.New("invalid cron: expression never fires")
+		}
+		schedule.NextRunAt = &next
+	}
+	return nil
+}
+
+func parseCron(spec string) (*cron.Schedule, error) {
+	parsed, err := cron.Parse(spec)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cron: %w", err)
+	}
+	return parsed, nil
+}
+
+// runDueSchedules enqueues commands for schedules whose time has come. A run
+// missed while the server was down fires once; later slots are not backfilled.
+func (s *Service) runDueSchedules(now time.Time) {
+	for _, schedule := range s.store.DueSchedules(now) {
+		run := model.ScheduleRun{At: now}
+		targets, err := s.resolveDeviceSelectors(schedule.DeviceIDs, now)
+		if err != nil {
+			run.Errors = append(run.Errors, err.Error())
+		}
+		for _, deviceID := range targets {
+			command, err := s.createCommand(OperatorCommandRequest{
+				DeviceID: deviceID,
+				Type:     schedule.Type,
+				Payload:  schedule.Payload,
+				Target:   schedule.Target,
+			}, scheduleCreatorPrefix+schedule.ScheduleID, schedule)
+			if err != nil {
+				run.Errors = append(run.Errors, fmt.Sprintf("%s: %v", deviceID, err))
+				continue
+			}
+			run.CommandIDs = append(run.CommandIDs, command.CommandID)
+		}
+
+		var next *time.Time
+		if parsed, err := parseCron(schedule.Cron); err == nil {
+			if at := parsed.Next(now); !at.IsZero() {
+				next = &at
+			}
+		}
+		if _, err := s.store.RecordScheduleRun(schedule.ScheduleID, run, next); err != nil {
+			fmt.Fprintf(os.Stderr, "schedule %s run error: %v\n", schedule.ScheduleID, err)
+		}
+	}
+}
@@ -1,53 +1,139 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
-	"sort"
+	"io"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/commands"
 	"lte_swd/backend/server/internal/config"
+	"lte_swd/backend/server/internal/events"
 	"lte_swd/backend/server/internal/model"
 	"lte_swd/backend/server/internal/store"
 )
 
-var supportedCommandTypes = map[string]struct{}{
-	"swd_connect":       {},
-	"swd_read_memory":   {},
-	"swd_write_memory":  {},
-	"swd_erase":         {},
-	"swd_program":       {},
-	"swd_verify":        {},
-	"swd_copy_firmware": {},
-	"swd_reset":         {},
-}
+const (
+	maxIdempotencyKeyLen = 128
+	eventBacklogSize     = 1024
+	statusSweepInterval  = 5 * time.Second
+)
 
 // Service contains business rules for LTE_SWD R1 backend.
 type Service struct {
-	cfg   config.Config
-	store *store.StateStore
-	auth  *auth.OperatorAuth
-	nowFn func() time.Time
+	cfg    config.Config
+	store  *store.StateStore
+	auth   *auth.OperatorAuth
+	nowFn  func() time.Time
+	events *events.Hub
+	types  *commands.Registry
+	// approvalTypes lists command types that enter awaiting_approval.
+	approvalTypes map[string]struct{}
+	// interlockTypes lists command types checked by telemetry preflight.
+	interlockTypes map[string]struct{}
+
+	shutdownOnce sync.Once
+	shutdown     chan struct{}
+
+	// nextArtifactGC is only touched by Run; lastArtifactGC is guarded by gcMu.
+	nextArtifactGC time.Time
+	gcMu           sync.Mutex
+	lastArtifactGC *model.ArtifactGCReport
+
+	// deltaBuildMu serialises delta builds; deltaMu guards deltaPending,
+	// pairs devices asked for, mapped to the requester.
+	deltaBuildMu sync.Mutex
+	deltaMu      sync.Mutex
+	deltaPending map[deltaPair]string
+
+	// chunkMu guards chunkCache, its insertion order and total entry count.
+	chunkMu     sync.Mutex
+	chunkCache  map[manifestKey][]model.ArtifactChunk
+	chunkOrder  []manifestKey
+	chunkCached int
 }
 
 // New creates service layer over auth and state store.
-func New(cfg config.Config, st *store.StateStore, opAuth *auth.OperatorAuth) *Service {
+func New(cfg config.Config, st *store.StateStore, opAuth *auth.OperatorAuth, types *commands.Registry) *Service {
+	hub := events.NewHub(eventBacklogSize, time.Now())
+	st.SetEventHub(hub)
+	st.SetArtifactQuota(cfg.ArtifactQuotaBytes)
+
 	return &Service{
-		cfg:   cfg,
-		store: st,
-		auth:  opAuth,
-		nowFn: time.Now,
+		cfg:            cfg,
+		store:          st,
+		auth:           opAuth,
+		nowFn:          time.Now,
+		events:         hub,
+		types:          types,
+		approvalTypes:  stringSet(cfg.ApprovalRequiredTypes),
+		interlockTypes: stringSet(cfg.Interlocks.Types),
+		shutdown:       make(chan struct{}),
+		deltaPending:   make(map[deltaPair]string),
+		chunkCache:     make(map[manifestKey][]model.ArtifactChunk),
 	}
 }
 
-// LoginOperator validates password and returns bearer token.
-func (s *Service) LoginOperator(password string) (string, time.Time, error) {
-	return s.auth.Login(strings.TrimSpace(password), s.nowFn().UTC())
+func stringSet(items []string) map[string]struct{} {
+	out := make(map[string]struct{}, len(items))
+	for _, item := range items {
+		out[item] = struct{}{}
+	}
+	return out
+}
+
+// Shutdown releases held long-poll requests and event streams so HTTP server can drain.
+func (s *Service) Shutdown() {
+	s.shutdownOnce.Do(func() {
+		close(s.shutdown)
+		s.events.Close()
+	})
+}
+
+// Run executes periodic background work until ctx is cancelled.
+func (s *Service) Run(ctx context.Context) {
+	ticker := time.NewTicker(statusSweepInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-s.shutdown:
+			return
+		case <-ticker.C:
+			now := s.nowFn().UTC()
+			if err := s.store.RefreshDeviceStatuses(now, s.cfg.DeviceOfflineAfter); err != nil {
+				fmt.Fprintf(os.Stderr, "status sweep error: %v\n", err)
+			}
+			s.runDueSchedules(now)
+			s.runArtifactGC(now)
+			s.runPendingDeltas()
+		}
+	}
+}
+
+// SubscribeEvents opens operator live stream starting after lastEventID.
+func (s *Service) SubscribeEvents(lastEventID uint64) *events.Subscription {
+	return s.events.Subscribe(lastEventID)
+}
+
+// LoginOperator validates password and returns bearer token. Empty username
+// selects the shared operator password.
+func (s *Service) LoginOperator(username, password string) (string, time.Time, error) {
+	return s.auth.LoginAs(strings.TrimSpace(username), strings.TrimSpace(password), s.nowFn().UTC())
 }
 
 // RequireOperator checks bearer token.
@@ -55,6 +141,11 @@ func (s *Service) RequireOperator(token string) error {
 	return s.auth.Validate(token, s.nowFn().UTC())
 }
 
+// OperatorIdentity checks bearer token and returns operator name.
+func (s *Service) OperatorIdentity(token string) (string, error) {
+	return s.auth.Identify(token, s.nowFn().UTC())
+}
+
 // RegisterDeviceRequest describes first registration payload.
 type RegisterDeviceRequest struct {
 	EnrollKey       string `json:"enroll_key"`
@@ -63,6 +154,10 @@ type RegisterDeviceRequest struct {
 	ModemIMEI       string `json:"modem_imei"`
 	SimICCID        string `json:"sim_iccid"`
 	FirmwareVersion string `json:"firmware_version"`
+	// Capabilities is optional; probes that omit it accept every command type.
+	Capabilities *model.DeviceCapabilities `json:"capabilities"`
+	// Targets declares MCUs behind SWD mux; omitted keeps previous declaration.
+	Targets []model.DeviceTarget `json:"targets"`
 }
 
 // RegisterDeviceResponse includes issued token and poll timing.
@@ -70,6 +165,7 @@ type RegisterDeviceResponse struct {
 	DeviceToken          string `json:"device_token"`
 	PollIntervalSec      int    `json:"poll_interval_sec"`
 	HeartbeatIntervalSec int    `json:"heartbeat_interval_sec"`
+	MaxPullWaitSec       int    `json:"max_pull_wait_sec"`
 }
 
 // RegisterDevice performs enrollment validation and registration.
@@ -83,12 +179,23 @@ func (s *Service) RegisterDevice(req RegisterDeviceRequest) (RegisterDeviceRespo
 		return RegisterDeviceResponse{}, errors.New("device_id is required")
 	}
 
-	device, _, err := s.store.RegisterDevice(
+	capabilities, err := normalizeCapabilities(req.Capabilities)
+	if err != nil {
+		return RegisterDeviceResponse{}, err
+	}
+
+	targets, err := normalizeTargets(req.Targets)
+	if err != nil {
+		return RegisterDeviceResponse{}, err
+	}
+
+	device, _, err := s.store.RegisterDeviceWithProfile(
 		req.DeviceID,
 		strings.TrimSpace(req.HWUID),
 		strings.TrimSpace(req.ModemIMEI),
 		strings.TrimSpace(req.SimICCID),
 		strings.TrimSpace(req.FirmwareVersion),
+		store.DeviceProfile{Capabilities: capabilities, Targets: targets},
 		s.nowFn().UTC(),
 	)
 	if err != nil {
@@ -99,6 +206,7 @@ func (s *Service) RegisterDevice(req RegisterDeviceRequest) (RegisterDeviceRespo
 		DeviceToken:          device.DeviceToken,
 		PollIntervalSec:      3,
 		HeartbeatIntervalSec: 10,
+		MaxPullWaitSec:       int(s.cfg.MaxPullWait / time.Second),
 	}, nil
 }
 
@@ -148,19 +256,58 @@ func (s *Service) DeviceLocation(req DeviceLocationRequest) error {
 }
 
 // DevicePullRequest contains command pull auth payload.
+// WaitSec > 0 turns the pull into a long-poll capped by MAX_PULL_WAIT.
 type DevicePullRequest struct {
 	DeviceID    string `json:"device_id"`
 	DeviceToken string `json:"device_token"`
+	WaitSec     int    `json:"wait_sec"`
 }
 
-// DevicePullCommand returns next queued command for device.
-func (s *Service) DevicePullCommand(req DevicePullRequest) (*model.Command, error) {
+// DevicePullCommand returns next queued command for device. With wait_sec it
+// holds the request until a command is queued, the wait expires, the client
+// goes away or the server shuts down.
+func (s *Service) DevicePullCommand(ctx context.Context, req DevicePullRequest) (*model.Command, error) {
 	req.DeviceID = strings.TrimSpace(req.DeviceID)
 	req.DeviceToken = strings.TrimSpace(req.DeviceToken)
 	if req.DeviceID == "" || req.DeviceToken == "" {
 		return nil, errors.New("device_id and device_token are required")
 	}
-	return s.store.PullNextCommand(req.DeviceID, req.DeviceToken, s.nowFn().UTC())
+	if req.WaitSec < 0 {
+		return nil, errors.New("invalid wait_sec: must not be negative")
+	}
+
+	wait := time.Duration(req.WaitSec) * time.Second
+	if wait > s.cfg.MaxPullWait {
+		wait = s.cfg.MaxPullWait
+	}
+	if wait <= 0 {
+		return s.store.DispatchNextCommand(req.DeviceID, req.DeviceToken, s.dispatchGate, s.nowFn().UTC())
+	}
+
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+
+	for {
+		signal, err := s.store.CommandSignal(req.DeviceID)
+		if err != nil {
+			return nil, err
+		}
+
+		command, err := s.store.DispatchNextCommand(req.DeviceID, req.DeviceToken, s.dispatchGate, s.nowFn().UTC())
+		if err != nil || command != nil {
+			return command, err
+		}
+
+		select {
+		case <-signal:
+		case <-timer.C:
+			return nil, nil
+		case <-ctx.Done():
+			return nil, nil
+		case <-s.shutdown:
+			return nil, nil
+		}
+	}
 }
 
 // DeviceCommandResultRequest describes command completion payload.
@@ -189,46 +336,209 @@ func (s *Service) DeviceCommandResult(req DeviceCommandResultRequest) (*model.Co
 		resultStatus = model.CommandFailed
 	}
 
-	return s.store.CompleteCommand(req.DeviceID, req.DeviceToken, req.CommandID, model.CommandResult{
+	command, err := s.store.GetDeviceCommand(req.DeviceID, req.DeviceToken, req.CommandID)
+	if err != nil {
+		return nil, err
+	}
+
+	reported := model.CommandResult{
 		Status:  resultStatus,
 		Message: req.Message,
 		Metrics: req.Metrics,
 		Data:    req.Data,
+	}
+	// A retry of a completed command is matched on the raw report; processing
+	// it again against today's artifacts could turn it into a conflict.
+	result := reported
+	if commandType, ok := s.types.Lookup(command.Type); ok && !command.Status.IsTerminal() {
+		commandType.ProcessResult(s.store, command, &result)
+	}
+
+	return s.store.CompleteCommand(req.DeviceID, req.DeviceToken, req.CommandID, reported, result, s.nowFn().UTC())
+}
+
+// DeviceCommandProgressRequest describes intermediate progress report.
+type DeviceCommandProgressRequest struct {
+	DeviceID     string  `json:"device_id"`
+	DeviceToken  string  `json:"device_token"`
+	CommandID    string  `json:"command_id"`
+	Percent      float64 `json:"percent"`
+	BytesWritten int64   `json:"bytes_written"`
+	BytesTotal   int64   `json:"bytes_total"`
+	Phase        string  `json:"phase"`
+	Message      string  `json:"message"`
+}
+
+// DeviceCommandProgress stores intermediate progress of a dispatched command.
+func (s *Service) DeviceCommandProgress(req DeviceCommandProgressRequest) (*model.Command, error) {
+	req.DeviceID = strings.TrimSpace(req.DeviceID)
+	req.DeviceToken = strings.TrimSpace(req.DeviceToken)
+	req.CommandID = strings.TrimSpace(req.CommandID)
+
+	if req.DeviceID == "" || req.DeviceToken == "" || req.CommandID == "" {
+		return nil, errors.New("device_id, device_token and command_id are required")
+	}
+	if req.Percent < 0 || req.Percent > 100 {
+		return nil, errors.New("invalid percent: must be within 0..100")
+	}
+	if req.BytesWritten < 0 || req.BytesTotal < 0 {
+		return nil, errors.New("invalid byte counters: must not be negative")
+	}
+
+	return s.store.UpdateCommandProgress(req.DeviceID, req.DeviceToken, req.CommandID, model.CommandProgress{
+		Percent:      req.Percent,
+		BytesWritten: req.BytesWritten,
+		BytesTotal:   req.BytesTotal,
+		Phase:        strings.TrimSpace(req.Phase),
+		Message:      req.Message,
 	}, s.nowFn().UTC())
 }
 
-// DeviceGetArtifact validates device token and returns artifact.
-func (s *Service) DeviceGetArtifact(deviceID, deviceToken, artifactID string) (*model.Artifact, error) {
+// DeviceResultBlobChunk describes one raw chunk of a command result blob.
+type DeviceResultBlobChunk struct {
+	DeviceID    string
+	DeviceToken string
+	CommandID   string
+	Offset      int64
+	TotalSize   int64
+	ContentType string
+	SHA256      string
+	Body        io.Reader
+}
+
+// DeviceUploadResultBlob appends chunk to binary result of a dispatched command.
+func (s *Service) DeviceUploadResultBlob(req DeviceResultBlobChunk) (*model.ResultBlob, error) {
+	req.DeviceID = strings.TrimSpace(req.DeviceID)
+	req.DeviceToken = strings.TrimSpace(req.DeviceToken)
+	req.CommandID = strings.TrimSpace(req.CommandID)
+	req.SHA256 = strings.ToLower(strings.TrimSpace(req.SHA256))
+
+	if req.DeviceID == "" || req.DeviceToken == "" || req.CommandID == "" {
+		return nil, errors.New("device_id, device_token and command_id are required")
+	}
+	if req.TotalSize <= 0 || req.TotalSize > s.cfg.MaxArtifactBytes {
+		return nil, fmt.Errorf("invalid total: must be within 1..%d bytes", s.cfg.MaxArtifactBytes)
+	}
+	if req.Offset < 0 || req.Offset > req.TotalSize {
+		return nil, errors.New("invalid offset")
+	}
+	if req.SHA256 != "" {
+		if decoded, err := hex.DecodeString(req.SHA256); err != nil || len(decoded) != sha256.Size {
+			return nil, errors.New("invalid sha256: expected 64 hex characters")
+		}
+	}
+	if req.ContentType == "" {
+		req.ContentType = "application/octet-stream"
+	}
+
+	return s.store.AppendResultBlob(req.DeviceID, req.DeviceToken, req.CommandID, store.ResultBlobChunk{
+		Offset:      req.Offset,
+		TotalSize:   req.TotalSize,
+		ContentType: req.ContentType,
+		SHA256:      req.SHA256,
+		Body:        req.Body,
+	}, s.nowFn().UTC())
+}
+
+// DeviceResultBlobStatus reports received size so device can resume upload.
+func (s *Service) DeviceResultBlobStatus(deviceID, deviceToken, commandID string) (*model.ResultBlob, error) {
 	deviceID = strings.TrimSpace(deviceID)
 	deviceToken = strings.TrimSpace(deviceToken)
-	artifactID = strings.TrimSpace(artifactID)
+	commandID = strings.TrimSpace(commandID)
+	if deviceID == "" || deviceToken == "" || commandID == "" {
+		return nil, errors.New("device_id, device_token and command_id are required")
+	}
+	return s.store.ResultBlobStatus(deviceID, deviceToken, commandID)
+}
 
-	if deviceID == "" || deviceToken == "" || artifactID == "" {
-		return nil, errors.New("device_id, device_token and artifact_id are required")
+// DeviceOpenArtifact validates device token and returns artifact with its
+// payload file. The first of encodings whose cached variant is smaller than
+// the payload is used and returned; otherwise encoding is "" and the file is
+// the payload itself.
+func (s *Service) DeviceOpenArtifact(deviceID, deviceToken, artifactID string, encodings []string) (*model.Artifact, *os.File, string, error) {
+	if _, err := s.authorizeArtifactRead(deviceID, deviceToken, artifactID); err != nil {
+		return nil, nil, "", err
 	}
+	artifactID = strings.TrimSpace(artifactID)
+	for _, encoding := range encodings {
+		artifact, file, err := s.store.OpenArtifactEncoded(artifactID, encoding)
+		if err != nil {
+			return nil, nil, "", err
+		}
+		if info, err := file.Stat(); err == nil && info.Size() < artifact.Size {
+			return artifact, file, encoding, nil
+		}
+		file.Close()
+	}
+	artifact, file, err := s.store.OpenArtifact(artifactID)
+	return artifact, file, "", err
+}
 
-	if _, err := s.store.ValidateDeviceToken(deviceID, deviceToken, s.nowFn().UTC()); err != nil {
+// DeviceGetArtifact validates device token and returns artifact metadata, so
+// probe learns segment addresses before programming.
+func (s *Service) DeviceGetArtifact(deviceID, deviceToken, artifactID string) (*model.Artifact, error) {
+	if _, err := s.authorizeArtifactRead(deviceID, deviceToken, artifactID); err != nil {
 		return nil, err
 	}
-	return s.store.GetArtifact(artifactID)
+	return s.store.GetArtifact(strings.TrimSpace(artifactID))
+}
+
+// DeviceOpenArtifactSegment validates device token and returns flat binary of one segment.
+func (s *Service) DeviceOpenArtifactSegment(deviceID, deviceToken, artifactID string, index int) (*model.Artifact, *model.ArtifactSegment, *os.File, error) {
+	if _, err := s.authorizeArtifactRead(deviceID, deviceToken, artifactID); err != nil {
+		return nil, nil, nil, err
+	}
+	return s.store.OpenArtifactSegment(strings.TrimSpace(artifactID), index)
+}
+
+func (s *Service) authorizeArtifactRead(deviceID, deviceToken, artifactID string) (*model.Device, error) {
+	deviceID = strings.TrimSpace(deviceID)
+	deviceToken = strings.TrimSpace(deviceToken)
+	if deviceID == "" || deviceToken == "" || strings.TrimSpace(artifactID) == "" {
+		return nil, errors.New("device_id, device_token and artifact_id are required")
+	}
+	return s.store.ValidateDeviceToken(deviceID, deviceToken, s.nowFn().UTC())
 }
 
 // OperatorCommandRequest describes operator command payload.
 type OperatorCommandRequest struct {
-	DeviceID string          `json:"device_id"`
-	Type     string          `json:"type"`
-	Payload  json.RawMessage `json:"payload"`
+	DeviceID           string          `json:"device_id"`
+	Type               string          `json:"type"`
+	Payload            json.RawMessage `json:"payload"`
+	OverrideInterlocks bool            `json:"override_interlocks"`
+	OverrideReason     string          `json:"override_reason"`
+	// Target selects SWD target index on multi-target probes.
+	Target *int `json:"target"`
+	// Template instantiates named preset with Params instead of Type/Payload.
+	Template       string                     `json:"template"`
+	Params         map[string]json.RawMessage `json:"params"`
+	IdempotencyKey string                     `json:"-"`
 }
 
 // OperatorCreateCommand enqueues new command for one device.
 func (s *Service) OperatorCreateCommand(req OperatorCommandRequest, operator string) (*model.Command, error) {
+	if strings.TrimSpace(req.Template) != "" {
+		if err := s.applyTemplate(&req); err != nil {
+			return nil, err
+		}
+	}
+	return s.createCommand(req, operator, nil)
+}
+
+// createCommand validates request and queues command on behalf of createdBy.
+func (s *Service) createCommand(req OperatorCommandRequest, createdBy string, schedule *model.Schedule) (*model.Command, error) {
 	req.DeviceID = strings.TrimSpace(req.DeviceID)
 	req.Type = strings.TrimSpace(req.Type)
 
 	if req.DeviceID == "" || req.Type == "" {
 		return nil, errors.New("device_id and type are required")
 	}
-	if _, ok := supportedCommandTypes[req.Type]; !ok {
+	req.OverrideReason = strings.TrimSpace(req.OverrideReason)
+	if req.OverrideInterlocks && req.OverrideReason == "" {
+		return nil, errors.New("override_reason is required with override_interlocks")
+	}
+	commandType, ok := s.types.Lookup(req.Type)
+	if !ok {
 		return nil, fmt.Errorf("unsupported command type: %s", req.Type)
 	}
 
@@ -238,8 +548,118 @@ func (s *Service) OperatorCreateCommand(req OperatorCommandRequest, operator str
 	if !json.Valid(req.Payload) {
 		return nil, errors.New("payload must be valid json")
 	}
+	// Fingerprint keeps the requested payload, so a retry replays even after
+	// the channel moved.
+	requested := req.Payload
+	payload, channel, err := s.resolveChannelPayload(req.Payload)
+	if err != nil {
+		return nil, err
+	}
+	req.Payload = payload
+	if err := commandType.ValidatePayload(req.Payload); err != nil {
+		return nil, err
+	}
+	if err := s.checkArtifactSigned(req.Type, req.Payload); err != nil {
+		return nil, err
+	}
+
+	device, err := s.store.GetDevice(req.DeviceID, s.nowFn().UTC(), s.cfg.DeviceOfflineAfter)
+	if err != nil {
+		return nil, err
+	}
+	if minimum := commandType.Info().MinFirmware; !commands.FirmwareAtLeast(device.FirmwareVersion, minimum) {
+		return nil, fmt.Errorf("unsupported command type %s for device firmware %s (requires %s)", req.Type, device.FirmwareVersion, minimum)
+	}
+	if err := checkDeviceCapabilities(device, req.Type, req.Payload); err != nil {
+		return nil, err
+	}
+	target, err := resolveTarget(device, req.Target)
+	if err != nil {
+		return nil, err
+	}
+
+	fingerprint := []string{req.DeviceID, req.Type, compactJSON(requested), strconv.FormatBool(req.OverrideInterlocks)}
+	if target != nil {
+		fingerprint = append(fingerprint, "target="+strconv.Itoa(*target))
+	}
+	idem, err := s.idempotencyRequest(req.IdempotencyKey, fingerprint...)
+	if err != nil {
+		return nil, err
+	}
+
+	draft := model.Command{
+		DeviceID:  req.DeviceID,
+		Type:      req.Type,
+		Payload:   req.Payload,
+		CreatedBy: createdBy,
+		Template:  req.Template,
+		Channel:   channel,
+		Target:    target,
+	}
+	if schedule != nil {
+		draft.ScheduleID = schedule.ScheduleID
+		draft.RequestedBy = schedule.CreatedBy
+	}
+	if _, ok := s.approvalTypes[req.Type]; ok {
+		// Shared password hides who is behind the session, so it could be
+		// used to approve one's own command under a second identity.
+		if createdBy == auth.SharedOperatorName || draft.RequestedBy == auth.SharedOperatorName {
+			return nil, store.ErrNamedOperatorRequired
+		}
+		draft.Status = model.CommandAwaitingApproval
+	}
+	if req.OverrideInterlocks {
+		draft.InterlockOverride = &model.CommandOverride{
+			By:     createdBy,
+			At:     s.nowFn().UTC(),
+			Reason: req.OverrideReason,
+		}
+	}
 
-	return s.store.AddCommand(req.DeviceID, req.Type, req.Payload, operator, s.nowFn().UTC())
+	command, _, err := s.store.InsertCommand(draft, idem, s.nowFn().UTC())
+	return command, err
+}
+
+// OperatorOverrideRequest carries reason for bypassing interlocks.
+type OperatorOverrideRequest struct {
+	Reason string `json:"reason"`
+}
+
+// OperatorOverrideInterlocks lets held command dispatch despite failing preflight rules.
+func (s *Service) OperatorOverrideInterlocks(commandID string, req OperatorOverrideRequest, operator string) (*model.Command, error) {
+	commandID = strings.TrimSpace(commandID)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if commandID == "" || req.Reason == "" {
+		return nil, errors.New("command_id and reason are required")
+	}
+	return s.store.OverrideCommandInterlocks(commandID, operator, req.Reason, s.nowFn().UTC())
+}
+
+// OperatorApprovalRequest carries approval decision reason.
+type OperatorApprovalRequest struct {
+	Reason string `json:"reason"`
+}
+
+// OperatorApproveCommand releases command awaiting approval to the device queue.
+func (s *Service) OperatorApproveCommand(commandID string, req OperatorApprovalRequest, operator string) (*model.Command, error) {
+	commandID = strings.TrimSpace(commandID)
+	if commandID == "" {
+		return nil, errors.New("command_id is required")
+	}
+	if len(s.approvalTypes) > 0 && operator == auth.SharedOperatorName {
+		return nil, store.ErrNamedOperatorRequired
+	}
+	return s.store.DecideCommandApproval(commandID, true, operator, strings.TrimSpace(req.Reason), s.nowFn().UTC())
+}
+
+// OperatorRejectCommand refuses command awaiting approval; reason is mandatory.
+func (s *Service) OperatorRejectCommand(commandID string, req OperatorApprovalRequest, operator string) (*model.Command, error) {
+	commandID = strings.TrimSpace(commandID)
+	req.Reason = strings.TrimSpace(req.Reason)
+	if commandID == "" || req.Reason == "" {
+		return nil, errors.New("command_id and reason are required")
+	}
+	return s.store.DecideCommandApproval(commandID, false, operator, req.Reason, s.nowFn().UTC())
 }
 
 // OperatorArtifactRequest describes uploaded firmware payload.
@@ -247,6 +667,10 @@ type OperatorArtifactRequest struct {
 	Name        string `json:"name"`
 	ContentType string `json:"content_type"`
 	Base64Data  string `json:"base64_data"`
+	// Signature is optional base64 Ed25519 signature over decoded bytes.
+	Signature      string `json:"signature"`
+	SignatureKeyID string `json:"signature_key_id"`
+	IdempotencyKey string `json:"-"`
 }
 
 // OperatorUploadArtifact stores firmware artifact for swd_program operations.
@@ -275,10 +699,43 @@ func (s *Service) OperatorUploadArtifact(req OperatorArtifactRequest, operator s
 		contentType = "application/octet-stream"
 	}
 
-	return s.store.SaveArtifact(req.Name, contentType, data, operator, s.nowFn().UTC())
+	staged, err := s.store.StageArtifact(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	return s.commitUpload(staged, req.Name, contentType, req.Signature, req.SignatureKeyID, req.IdempotencyKey, operator)
 }
 
-// OperatorGetArtifact returns stored artifact.
+// commitUpload verifies optional signature of staged upload and registers it.
+func (s *Service) commitUpload(staged *store.StagedArtifact, name, contentType, signature, keyID, idempotencyKey, operator string) (*model.Artifact, error) {
+	fingerprint := []string{name, contentType, staged.SHA256}
+	if signature = strings.TrimSpace(signature); signature != "" {
+		fingerprint = append(fingerprint, "signature="+signature)
+	}
+	idem, err := s.idempotencyRequest(idempotencyKey, fingerprint...)
+	if err != nil {
+		staged.Discard()
+		return nil, err
+	}
+	if err := s.signStagedArtifact(staged, signature, keyID, operator); err != nil {
+		staged.Discard()
+		return nil, err
+	}
+
+	artifact, _, err := s.store.CommitArtifact(staged, name, contentType, operator, idem, s.nowFn().UTC())
+	return artifact, err
+}
+
+// OperatorOpenArtifact returns artifact metadata and payload file; caller closes file.
+func (s *Service) OperatorOpenArtifact(artifactID string) (*model.Artifact, *os.File, error) {
+	artifactID = strings.TrimSpace(artifactID)
+	if artifactID == "" {
+		return nil, nil, errors.New("artifact_id is required")
+	}
+	return s.store.OpenArtifact(artifactID)
+}
+
+// OperatorGetArtifact returns artifact metadata including parsed segments.
 func (s *Service) OperatorGetArtifact(artifactID string) (*model.Artifact, error) {
 	artifactID = strings.TrimSpace(artifactID)
 	if artifactID == "" {
@@ -287,6 +744,81 @@ func (s *Service) OperatorGetArtifact(artifactID string) (*model.Artifact, error
 	return s.store.GetArtifact(artifactID)
 }
 
+// OperatorOpenArtifactSegment returns flat binary of one artifact segment; caller closes file.
+func (s *Service) OperatorOpenArtifactSegment(artifactID string, index int) (*model.Artifact, *model.ArtifactSegment, *os.File, error) {
+	artifactID = strings.TrimSpace(artifactID)
+	if artifactID == "" {
+		return nil, nil, nil, errors.New("artifact_id is required")
+	}
+	return s.store.OpenArtifactSegment(artifactID, index)
+}
+
+// OperatorArtifactStream is raw artifact upload read straight from request body.
+type OperatorArtifactStream struct {
+	Name        string
+	ContentType string
+	// ContentLength is declared body size, or -1 when unknown.
+	ContentLength  int64
+	Body           io.Reader
+	Signature      string
+	SignatureKeyID string
+	IdempotencyKey string
+}
+
+// OperatorStreamArtifact stores raw upload without buffering it in memory:
+// body goes to a temp file while being hashed, bounded by MAX_ARTIFACT_BYTES.
+func (s *Service) OperatorStreamArtifact(req OperatorArtifactStream, operator string) (*model.Artifact, error) {
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Name == "" {
+		return nil, errors.New("name is required")
+	}
+	if req.ContentLength > s.cfg.MaxArtifactBytes {
+		return nil, store.ErrArtifactTooLarge
+	}
+	if req.ContentLength == 0 {
+		return nil, errors.New("invalid artifact: payload must not be empty")
+	}
+
+	contentType := strings.TrimSpace(req.ContentType)
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	staged, err := s.store.StageArtifact(req.Body, s.cfg.MaxArtifactBytes)
+	if err != nil {
+		return nil, err
+	}
+	return s.commitUpload(staged, req.Name, contentType, req.Signature, req.SignatureKeyID, req.IdempotencyKey, operator)
+}
+
+// OperatorOpenResultBlob opens complete result blob for download; caller closes file.
+func (s *Service) OperatorOpenResultBlob(commandID string) (*model.Command, *os.File, error) {
+	commandID = strings.TrimSpace(commandID)
+	if commandID == "" {
+		return nil, nil, errors.New("command_id is required")
+	}
+	return s.store.OpenResultBlob(commandID)
+}
+
+// OperatorPromoteBlobRequest names artifact created from a result blob.
+type OperatorPromoteBlobRequest struct {
+	Name        string `json:"name"`
+	ContentType string `json:"content_type"`
+}
+
+// OperatorPromoteResultBlob stores result blob as artifact, e.g. firmware read by swd_copy_firmware.
+func (s *Service) OperatorPromoteResultBlob(commandID string, req OperatorPromoteBlobRequest, operator string) (*model.Artifact, error) {
+	commandID = strings.TrimSpace(commandID)
+	req.Name = strings.TrimSpace(req.Name)
+	if commandID == "" {
+		return nil, errors.New("command_id is required")
+	}
+	if req.Name == "" {
+		req.Name = commandID + ".bin"
+	}
+	return s.store.PromoteResultBlob(commandID, req.Name, strings.TrimSpace(req.ContentType), operator, s.nowFn().UTC())
+}
+
 // OperatorListDevices returns fleet state.
 func (s *Service) OperatorListDevices() ([]*model.Device, error) {
 	return s.store.ListDevices(s.nowFn().UTC(), s.cfg.DeviceOfflineAfter)
@@ -308,15 +840,71 @@ func (s *Service) OperatorListTelemetry(deviceID string, limit int) ([]model.Tel
 
 // OperatorListCommands returns command history.
 func (s *Service) OperatorListCommands(deviceID string, limit int) ([]*model.Command, error) {
-	return s.store.ListCommands(strings.TrimSpace(deviceID), limit)
+	commands, err := s.store.ListCommands(strings.TrimSpace(deviceID), limit)
+	if err != nil {
+		return nil, err
+	}
+	s.markStalled(commands)
+	return commands, nil
 }
 
-// SupportedCommandTypes returns deterministic command type list.
-func SupportedCommandTypes() []string {
-	keys := make([]string, 0, len(supportedCommandTypes))
-	for key := range supportedCommandTypes {
-		keys = append(keys, key)
+// markStalled flags active commands that reported nothing for COMMAND_STALL_AFTER.
+func (s *Service) markStalled(commands []*model.Command) {
+	now := s.nowFn().UTC()
+	for _, command := range commands {
+		if command.Status != model.CommandDispatched && command.Status != model.CommandRunning {
+			continue
+		}
+
+		var lastActivity time.Time
+		if command.DispatchedAt != nil {
+			lastActivity = *command.DispatchedAt
+		}
+		if command.Progress != nil && command.Progress.UpdatedAt.After(lastActivity) {
+			lastActivity = command.Progress.UpdatedAt
+		}
+		command.Stalled = !lastActivity.IsZero() && now.Sub(lastActivity) > s.cfg.CommandStallAfter
 	}
-	sort.Strings(keys)
-	return keys
+}
+
+// idempotencyRequest validates client key and fingerprints request parts so
+// a replay with a different body is rejected instead of silently matched.
+func (s *Service) idempotencyRequest(key string, parts ...string) (store.IdempotencyRequest, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return store.IdempotencyRequest{}, nil
+	}
+	if len(key) > maxIdempotencyKeyLen {
+		return store.IdempotencyRequest{}, fmt.Errorf("invalid idempotency key: longer than %d characters", maxIdempotencyKeyLen)
+	}
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return store.IdempotencyRequest{}, errors.New("invalid idempotency key: only printable ascii is allowed")
+		}
+	}
+
+	hasher := sha256.New()
+	for _, part := range parts {
+		hasher.Write([]byte(part))
+		hasher.Write([]byte{0})
+	}
+
+	return store.IdempotencyRequest{
+		Key:         key,
+		Fingerprint: hex.EncodeToString(hasher.Sum(nil)),
+		Retention:   s.cfg.IdempotencyTTL,
+	}, nil
+}
+
+func compactJSON(raw json.RawMessage) string {
+	var buf bytes.Buffer
+	if err := json.Compact(&buf, raw); err != nil {
+		return string(raw)
+	}
+	return buf.String()
+}
+
+// CommandTypes returns metadata of registered command types.
+func (s *Service) CommandTypes() []commands.Info {
+	return s.types.Infos()
 }
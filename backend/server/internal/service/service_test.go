@@ -1,13 +1,29 @@
 package service
 
 import (
+	"bytes"
+	"context"
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
 	"testing"
 	"time"
 
 	"lte_swd/backend/server/internal/auth"
+	"lte_swd/backend/server/internal/commands"
 	"lte_swd/backend/server/internal/config"
+	"lte_swd/backend/server/internal/delta"
+	"lte_swd/backend/server/internal/model"
 	"lte_swd/backend/server/internal/store"
 )
 
@@ -24,7 +40,7 @@ func TestOperatorCreateCommandValidation(t *testing.T) {
 		DeviceOfflineAfter: 30 * time.Second,
 	}
 
-	svc := New(cfg, st, auth.NewOperatorAuth("pass", time.Hour))
+	svc := New(cfg, st, auth.NewOperatorAuth("pass", time.Hour), commands.Builtin())
 
 	_, err = svc.RegisterDevice(RegisterDeviceRequest{
 		EnrollKey:       "enroll",
@@ -47,3 +63,1059 @@ func TestOperatorCreateCommandValidation(t *testing.T) {
 		t.Fatalf("expected error for unsupported command")
 	}
 }
+
+func newTestService(t *testing.T, cfg config.Config) (*Service, string) {
+	t.Helper()
+
+	st, err := store.NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	cfg.DeviceEnrollKey = "enroll"
+	if cfg.DeviceOfflineAfter == 0 {
+		cfg.DeviceOfflineAfter = 30 * time.Second
+	}
+	svc := New(cfg, st, auth.NewOperatorAuth("pass", time.Hour), commands.Builtin())
+
+	resp, err := svc.RegisterDevice(RegisterDeviceRequest{
+		EnrollKey:       "enroll",
+		DeviceID:        "dev-1",
+		HWUID:           "uid",
+		ModemIMEI:       "imei",
+		FirmwareVersion: "r1",
+	})
+	if err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+	return svc, resp.DeviceToken
+}
+
+func TestDevicePullCommandLongPoll(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{MaxPullWait: 5 * time.Second})
+	pull := DevicePullRequest{DeviceID: "dev-1", DeviceToken: token, WaitSec: 5}
+
+	type pullResult struct {
+		commandID string
+		err       error
+	}
+	results := make(chan pullResult, 1)
+	go func() {
+		command, err := svc.DevicePullCommand(context.Background(), pull)
+		result := pullResult{err: err}
+		if command != nil {
+			result.commandID = command.CommandID
+		}
+		results <- result
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	queued, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_reset"}, "operator")
+	if err != nil {
+		t.Fatalf("create command: %v", err)
+	}
+
+	select {
+	case result := <-results:
+		if result.err != nil || result.commandID != queued.CommandID {
+			t.Fatalf("unexpected pull result: %+v", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("long-poll was not woken by queued command")
+	}
+
+	go func() {
+		command, err := svc.DevicePullCommand(context.Background(), pull)
+		result := pullResult{err: err}
+		if command != nil {
+			result.commandID = command.CommandID
+		}
+		results <- result
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	svc.Shutdown()
+
+	select {
+	case result := <-results:
+		if result.err != nil || result.commandID != "" {
+			t.Fatalf("expected empty pull on shutdown, got %+v", result)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatalf("shutdown did not release held pull")
+	}
+}
+
+func TestDeviceCommandProgressAndStall(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{CommandStallAfter: time.Minute})
+	now := time.Unix(1000, 0).UTC()
+	svc.nowFn = func() time.Time { return now }
+
+	queued, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_program"}, "operator")
+	if err != nil {
+		t.Fatalf("create command: %v", err)
+	}
+
+	progress := DeviceCommandProgressRequest{DeviceID: "dev-1", DeviceToken: token, CommandID: queued.CommandID, Percent: 10, Phase: "erase"}
+	if _, err := svc.DeviceCommandProgress(progress); !errors.Is(err, store.ErrInvalidCommandTransition) {
+		t.Fatalf("expected transition error for queued command, got %v", err)
+	}
+
+	if _, err := svc.DevicePullCommand(context.Background(), DevicePullRequest{DeviceID: "dev-1", DeviceToken: token}); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+
+	now = now.Add(30 * time.Second)
+	progress.Percent = 40
+	progress.BytesWritten = 4096
+	running, err := svc.DeviceCommandProgress(progress)
+	if err != nil {
+		t.Fatalf("progress: %v", err)
+	}
+	if running.Status != model.CommandRunning || running.Progress == nil || running.Progress.BytesWritten != 4096 {
+		t.Fatalf("unexpected running command: %#v", running)
+	}
+
+	now = now.Add(2 * time.Minute)
+	history, err := svc.OperatorListCommands("dev-1", 10)
+	if err != nil {
+		t.Fatalf("list commands: %v", err)
+	}
+	if len(history) != 1 || !history[0].Stalled || history[0].Progress.Phase != "erase" {
+		t.Fatalf("expected stalled running command in history: %#v", history)
+	}
+}
+
+func TestDestructiveCommandNeedsSecondOperator(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{ApprovalRequiredTypes: []string{"swd_erase"}})
+	pull := DevicePullRequest{DeviceID: "dev-1", DeviceToken: token}
+
+	erase, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_erase"}, "alice")
+	if err != nil {
+		t.Fatalf("create erase: %v", err)
+	}
+	if erase.Status != model.CommandAwaitingApproval {
+		t.Fatalf("expected awaiting_approval, got %s", erase.Status)
+	}
+	reset, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_reset"}, "alice")
+	if err != nil {
+		t.Fatalf("create reset: %v", err)
+	}
+
+	// The unapproved erase must not hold back the reset queued behind it.
+	if command, err := svc.DevicePullCommand(context.Background(), pull); err != nil || command == nil || command.CommandID != reset.CommandID {
+		t.Fatalf("expected reset to dispatch past pending approval, got %#v err=%v", command, err)
+	}
+	if command, err := svc.DevicePullCommand(context.Background(), pull); err != nil || command != nil {
+		t.Fatalf("expected nothing dispatchable before approval, got %#v err=%v", command, err)
+	}
+
+	if _, err := svc.OperatorApproveCommand(erase.CommandID, OperatorApprovalRequest{}, "alice"); !errors.Is(err, store.ErrSelfApproval) {
+		t.Fatalf("expected self approval error, got %v", err)
+	}
+
+	approved, err := svc.OperatorApproveCommand(erase.CommandID, OperatorApprovalRequest{Reason: "checked target"}, "bob")
+	if err != nil {
+		t.Fatalf("approve: %v", err)
+	}
+	if approved.Status != model.CommandQueued || approved.Approval.DecidedBy != "bob" {
+		t.Fatalf("unexpected approved command: %#v", approved)
+	}
+
+	command, err := svc.DevicePullCommand(context.Background(), pull)
+	if err != nil || command == nil || command.CommandID != erase.CommandID {
+		t.Fatalf("expected approved erase to dispatch, got %#v err=%v", command, err)
+	}
+
+	if _, err := svc.OperatorRejectCommand(erase.CommandID, OperatorApprovalRequest{Reason: "late"}, "bob"); !errors.Is(err, store.ErrInvalidCommandTransition) {
+		t.Fatalf("expected transition error when rejecting dispatched command, got %v", err)
+	}
+}
+
+func TestSharedOperatorCannotBypassApproval(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{ApprovalRequiredTypes: []string{"swd_erase"}})
+
+	erase, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_erase"}, "alice")
+	if err != nil {
+		t.Fatalf("create erase: %v", err)
+	}
+	// alice logs in again with the shared password to act as a second operator.
+	if _, err := svc.OperatorApproveCommand(erase.CommandID, OperatorApprovalRequest{}, auth.SharedOperatorName); !errors.Is(err, store.ErrNamedOperatorRequired) {
+		t.Fatalf("expected named operator error on approve, got %v", err)
+	}
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_erase"}, auth.SharedOperatorName); !errors.Is(err, store.ErrNamedOperatorRequired) {
+		t.Fatalf("expected named operator error on create, got %v", err)
+	}
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_reset"}, auth.SharedOperatorName); err != nil {
+		t.Fatalf("ungated command from shared session: %v", err)
+	}
+
+	history, err := svc.OperatorListCommands("dev-1", 10)
+	if err != nil {
+		t.Fatalf("list commands: %v", err)
+	}
+	for _, command := range history {
+		if command.CommandID == erase.CommandID && command.Status != model.CommandAwaitingApproval {
+			t.Fatalf("erase must still await approval, got %s", command.Status)
+		}
+	}
+}
+
+func TestScheduledCommandApprovalExcludesAuthor(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{ApprovalRequiredTypes: []string{"swd_erase"}})
+	now := time.Date(2026, time.March, 2, 9, 58, 0, 0, time.UTC)
+	svc.nowFn = func() time.Time { return now }
+
+	request := OperatorScheduleRequest{Name: "nightly erase", Cron: "0 10 * * *", DeviceIDs: []string{"dev-1"}, Type: "swd_erase"}
+	if _, err := svc.OperatorCreateSchedule(request, auth.SharedOperatorName); !errors.Is(err, store.ErrNamedOperatorRequired) {
+		t.Fatalf("expected named operator error, got %v", err)
+	}
+	schedule, err := svc.OperatorCreateSchedule(request, "alice")
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	svc.runDueSchedules(now)
+	stored, err := svc.OperatorGetSchedule(schedule.ScheduleID)
+	if err != nil || len(stored.History) != 1 || len(stored.History[0].CommandIDs) != 1 {
+		t.Fatalf("unexpected schedule run: %v (%+v)", err, stored)
+	}
+	commandID := stored.History[0].CommandIDs[0]
+
+	if _, err := svc.OperatorApproveCommand(commandID, OperatorApprovalRequest{}, "alice"); !errors.Is(err, store.ErrSelfApproval) {
+		t.Fatalf("expected self approval error, got %v", err)
+	}
+	approved, err := svc.OperatorApproveCommand(commandID, OperatorApprovalRequest{}, "bob")
+	if err != nil {
+		t.Fatalf("approve as bob: %v", err)
+	}
+	if approved.RequestedBy != "alice" || approved.Status != model.CommandQueued {
+		t.Fatalf("unexpected approved command: %+v", approved)
+	}
+}
+
+func TestDispatchInterlockHoldsRiskyCommand(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{Interlocks: config.Interlocks{
+		Types:        []string{"swd_program"},
+		MinBatteryMV: 3500,
+		BlockRoaming: true,
+	}})
+	pull := DevicePullRequest{DeviceID: "dev-1", DeviceToken: token}
+
+	err := svc.DeviceTelemetry(DeviceTelemetryRequest{DeviceID: "dev-1", DeviceToken: token, Data: model.Telemetry{BatteryMV: 3300, NetworkState: "home"}})
+	if err != nil {
+		t.Fatalf("telemetry: %v", err)
+	}
+
+	program, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_program"}, "operator")
+	if err != nil {
+		t.Fatalf("create program: %v", err)
+	}
+
+	if command, err := svc.DevicePullCommand(context.Background(), pull); err != nil || command != nil {
+		t.Fatalf("expected held command, got %#v err=%v", command, err)
+	}
+	history, err := svc.OperatorListCommands("dev-1", 1)
+	if err != nil {
+		t.Fatalf("list commands: %v", err)
+	}
+	if history[0].Hold == nil || !strings.Contains(history[0].Hold.Reason, "battery_mv") {
+		t.Fatalf("expected battery hold reason, got %#v", history[0].Hold)
+	}
+
+	reset, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_reset"}, "operator")
+	if err != nil {
+		t.Fatalf("create reset: %v", err)
+	}
+	if command, err := svc.DevicePullCommand(context.Background(), pull); err != nil || command == nil || command.CommandID != reset.CommandID {
+		t.Fatalf("expected reset to dispatch past held program, got %#v err=%v", command, err)
+	}
+
+	if _, err := svc.OperatorOverrideInterlocks(program.CommandID, OperatorOverrideRequest{Reason: "bench supply attached"}, "operator"); err != nil {
+		t.Fatalf("override: %v", err)
+	}
+	command, err := svc.DevicePullCommand(context.Background(), pull)
+	if err != nil || command == nil || command.CommandID != program.CommandID || command.Hold != nil {
+		t.Fatalf("expected overridden command to dispatch, got %#v err=%v", command, err)
+	}
+
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_program", OverrideInterlocks: true, OverrideReason: "  "}, "operator"); err == nil {
+		t.Fatal("expected override without reason to be rejected")
+	}
+	overridden, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_program", OverrideInterlocks: true, OverrideReason: " bench supply "}, "operator")
+	if err != nil || overridden.InterlockOverride == nil || overridden.InterlockOverride.Reason != "bench supply" {
+		t.Fatalf("create with override: %v %#v", err, overridden)
+	}
+}
+
+func TestVerifyResultAgainstArtifact(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{})
+	image := []byte("firmware-image-bytes")
+
+	artifact, err := svc.OperatorUploadArtifact(OperatorArtifactRequest{
+		Name:       "fw.bin",
+		Base64Data: base64.StdEncoding.EncodeToString(image),
+	}, "operator")
+	if err != nil {
+		t.Fatalf("upload artifact: %v", err)
+	}
+
+	complete := func(commandType string, data map[string]interface{}) *model.Command {
+		t.Helper()
+		queued, err := svc.OperatorCreateCommand(OperatorCommandRequest{
+			DeviceID: "dev-1",
+			Type:     commandType,
+			Payload:  json.RawMessage(`{"artifact_id":"` + artifact.ArtifactID + `"}`),
+		}, "operator")
+		if err != nil {
+			t.Fatalf("create %s: %v", commandType, err)
+		}
+		if _, err := svc.DevicePullCommand(context.Background(), DevicePullRequest{DeviceID: "dev-1", DeviceToken: token}); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+		done, err := svc.DeviceCommandResult(DeviceCommandResultRequest{
+			DeviceID:    "dev-1",
+			DeviceToken: token,
+			CommandID:   queued.CommandID,
+			Status:      model.CommandSuccess,
+			Message:     "ok",
+			Data:        data,
+		})
+		if err != nil {
+			t.Fatalf("result %s: %v", commandType, err)
+		}
+		return done
+	}
+
+	mismatch := complete("swd_verify", map[string]interface{}{"sha256": strings.Repeat("0", 64)})
+	if mismatch.Status != model.CommandFailed || mismatch.Result.Verification == nil || mismatch.Result.Verification.Match {
+		t.Fatalf("expected failed verification, got %#v", mismatch.Result)
+	}
+
+	crc := float64(crc32.ChecksumIEEE(image))
+	matched := complete("swd_verify", map[string]interface{}{"crc32": crc})
+	if matched.Status != model.CommandSuccess || !matched.Result.Verification.Match {
+		t.Fatalf("expected crc32 match, got %#v", matched.Result)
+	}
+
+	readBack := complete("swd_copy_firmware", map[string]interface{}{"sha256": artifact.PayloadSHA256})
+	if readBack.Result.Verification == nil || readBack.Result.Verification.MatchedArtifactID != artifact.ArtifactID {
+		t.Fatalf("expected read-back to match artifact, got %#v", readBack.Result)
+	}
+}
+
+func TestSignedArtifactPolicy(t *testing.T) {
+	t.Parallel()
+
+	publicKey, privateKey, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	svc, _ := newTestService(t, config.Config{
+		ArtifactSigningKeys:    map[string]ed25519.PublicKey{"release-2026": publicKey},
+		RequireSignedArtifacts: true,
+		MaxArtifactBytes:       1024,
+	})
+
+	image := []byte("signed-firmware-image")
+	digest := sha512.Sum512(image)
+	prehashed, err := privateKey.Sign(nil, digest[:], &ed25519.Options{Hash: crypto.SHA512})
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	signature := base64.StdEncoding.EncodeToString(prehashed)
+	upload := func(name string, data []byte, signature string) (*model.Artifact, error) {
+		return svc.OperatorStreamArtifact(OperatorArtifactStream{
+			Name:          name,
+			ContentLength: int64(len(data)),
+			Body:          bytes.NewReader(data),
+			Signature:     signature,
+		}, "operator")
+	}
+	program := func(artifactID string) error {
+		_, err := svc.OperatorCreateCommand(OperatorCommandRequest{
+			DeviceID: "dev-1",
+			Type:     "swd_program",
+			Payload:  json.RawMessage(`{"artifact_id":"` + artifactID + `"}`),
+		}, "operator")
+		return err
+	}
+
+	if _, err := upload("tampered.bin", []byte("tampered-firmware-image"), signature); !errors.Is(err, store.ErrArtifactSignature) {
+		t.Fatalf("expected signature failure for tampered bytes, got %v", err)
+	}
+
+	// Signatures are Ed25519ph, so the server hashes uploads while streaming.
+	pure := base64.StdEncoding.EncodeToString(ed25519.Sign(privateKey, image))
+	if _, err := upload("pure.bin", image, pure); !errors.Is(err, store.ErrArtifactSignature) {
+		t.Fatalf("expected pure Ed25519 signature to be refused, got %v", err)
+	}
+
+	unsigned, err := upload("fw.bin", image, "")
+	if err != nil {
+		t.Fatalf("upload unsigned: %v", err)
+	}
+	if err := program(unsigned.ArtifactID); !errors.Is(err, store.ErrArtifactUnsigned) {
+		t.Fatalf("expected unsigned artifact to be refused, got %v", err)
+	}
+
+	signed, err := upload("fw-release.bin", image, signature)
+	if err != nil {
+		t.Fatalf("upload signed: %v", err)
+	}
+	if signed.ArtifactID != unsigned.ArtifactID || signed.Signature == nil || signed.Signature.KeyID != "release-2026" || signed.Signature.Algorithm != model.SignatureEd25519ph {
+		t.Fatalf("re-upload with signature must sign existing artifact: %+v", signed.Signature)
+	}
+	if err := program(signed.ArtifactID); err != nil {
+		t.Fatalf("program signed artifact: %v", err)
+	}
+}
+
+func TestScheduleSpawnsCommands(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	now := time.Date(2026, time.March, 2, 9, 58, 0, 0, time.UTC)
+	svc.nowFn = func() time.Time { return now }
+
+	schedule, err := svc.OperatorCreateSchedule(OperatorScheduleRequest{
+		Name:      "nightly reset",
+		Cron:      "0 10 * * *",
+		DeviceIDs: []string{"*"},
+		Type:      "swd_reset",
+	}, "alice")
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+	if want := time.Date(2026, time.March, 2, 10, 0, 0, 0, time.UTC); !schedule.NextRunAt.Equal(want) {
+		t.Fatalf("next run = %s, want %s", schedule.NextRunAt, want)
+	}
+
+	svc.runDueSchedules(now)
+	if commands, _ := svc.OperatorListCommands("dev-1", 10); len(commands) != 0 {
+		t.Fatalf("schedule fired early: %d commands", len(commands))
+	}
+
+	now = now.Add(2 * time.Minute)
+	svc.runDueSchedules(now)
+	commands, err := svc.OperatorListCommands("dev-1", 10)
+	if err != nil || len(commands) != 1 {
+		t.Fatalf("list commands: %v (%d)", err, len(commands))
+	}
+	if commands[0].ScheduleID != schedule.ScheduleID || commands[0].CreatedBy != "schedule:"+schedule.ScheduleID {
+		t.Fatalf("unexpected spawned command: %+v", commands[0])
+	}
+
+	stored, err := svc.OperatorGetSchedule(schedule.ScheduleID)
+	if err != nil {
+		t.Fatalf("get schedule: %v", err)
+	}
+	if len(stored.History) != 1 || stored.History[0].CommandIDs[0] != commands[0].CommandID {
+		t.Fatalf("unexpected history: %+v", stored.History)
+	}
+	if want := time.Date(2026, time.March, 3, 10, 0, 0, 0, time.UTC); !stored.NextRunAt.Equal(want) {
+		t.Fatalf("next run after firing = %s, want %s", stored.NextRunAt, want)
+	}
+
+	disabled := false
+	stored, err = svc.OperatorUpdateSchedule(schedule.ScheduleID, OperatorScheduleUpdateRequest{Enabled: &disabled})
+	if err != nil || stored.NextRunAt != nil {
+		t.Fatalf("disable schedule: %v (next %v)", err, stored.NextRunAt)
+	}
+	svc.runDueSchedules(now.Add(48 * time.Hour))
+	if commands, _ := svc.OperatorListCommands("dev-1", 10); len(commands) != 1 {
+		t.Fatalf("disabled schedule fired: %d commands", len(commands))
+	}
+}
+
+func TestUpdateScheduleTargetingDevice(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	now := time.Date(2026, time.March, 2, 9, 0, 0, 0, time.UTC)
+	svc.nowFn = func() time.Time { return now }
+	schedule, err := svc.OperatorCreateSchedule(OperatorScheduleRequest{
+		Name:      "dev-1 reset",
+		Cron:      "0 10 * * *",
+		DeviceIDs: []string{"dev-1"},
+		Type:      "swd_reset",
+	}, "alice")
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	now = now.Add(time.Minute)
+	cron := "30 2 * * *"
+	updated, err := svc.OperatorUpdateSchedule(schedule.ScheduleID, OperatorScheduleUpdateRequest{Cron: &cron})
+	if err != nil {
+		t.Fatalf("update schedule: %v", err)
+	}
+	if updated.Cron != cron || len(updated.DeviceIDs) != 1 || updated.DeviceIDs[0] != "dev-1" {
+		t.Fatalf("unexpected updated schedule: %+v", updated)
+	}
+
+	if _, err := svc.OperatorUpdateSchedule(schedule.ScheduleID, OperatorScheduleUpdateRequest{DeviceIDs: []string{"dev-missing"}}); !errors.Is(err, store.ErrDeviceNotFound) {
+		t.Fatalf("expected device not found, got %v", err)
+	}
+
+	stale := *updated
+	stale.Name = "stale edit"
+	if _, err := svc.store.UpdateSchedule(stale, schedule.UpdatedAt, now); !errors.Is(err, store.ErrScheduleConflict) {
+		t.Fatalf("expected schedule conflict, got %v", err)
+	}
+}
+
+func TestSearchCommandsByDeviceSelector(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	for _, deviceID := range []string{"dev-2", "dev-3"} {
+		if _, err := svc.RegisterDevice(RegisterDeviceRequest{
+			EnrollKey:       "enroll",
+			DeviceID:        deviceID,
+			HWUID:           "uid-" + deviceID,
+			ModemIMEI:       "imei-" + deviceID,
+			FirmwareVersion: "r1",
+		}); err != nil {
+			t.Fatalf("register %s: %v", deviceID, err)
+		}
+		if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: deviceID, Type: "swd_reset"}, "alice"); err != nil {
+			t.Fatalf("create command for %s: %v", deviceID, err)
+		}
+	}
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_reset"}, "alice"); err != nil {
+		t.Fatalf("create command for dev-1: %v", err)
+	}
+	if _, err := svc.OperatorSetDeviceLabels("dev-2", OperatorDeviceLabelsRequest{Labels: []string{"lab"}}); err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+
+	devicesOf := func(selectors ...string) []string {
+		t.Helper()
+		page, err := svc.OperatorSearchCommands(CommandSearchQuery{DeviceIDs: selectors})
+		if err != nil {
+			t.Fatalf("search %v: %v", selectors, err)
+		}
+		out := []string{}
+		for _, command := range page.Items {
+			out = append(out, command.DeviceID)
+		}
+		sort.Strings(out)
+		return out
+	}
+	for selectors, want := range map[string][]string{
+		"label:lab":       {"dev-2"},
+		"label:unused":    {},
+		"*":               {"dev-1", "dev-2", "dev-3"},
+		"dev-1,label:lab": {"dev-1", "dev-2"},
+		"dev-3,*":         {"dev-1", "dev-2", "dev-3"},
+	} {
+		if got := devicesOf(strings.Split(selectors, ",")...); !reflect.DeepEqual(got, want) {
+			t.Fatalf("devices for %s = %v, want %v", selectors, got, want)
+		}
+	}
+
+	if _, err := svc.OperatorSearchCommands(CommandSearchQuery{DeviceIDs: []string{"label:Not Valid"}}); err == nil {
+		t.Fatal("expected invalid label selector error")
+	}
+	summary, err := svc.OperatorCommandSummary(CommandSearchQuery{DeviceIDs: []string{"label:lab"}})
+	if err != nil || summary.Totals.Total != 1 || summary.ByDevice["dev-2"] == nil {
+		t.Fatalf("unexpected summary for label: %+v, %v", summary, err)
+	}
+}
+
+func TestScheduleTargetsLabelledDevices(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	for _, deviceID := range []string{"dev-2", "dev-3"} {
+		if _, err := svc.RegisterDevice(RegisterDeviceRequest{
+			EnrollKey:       "enroll",
+			DeviceID:        deviceID,
+			HWUID:           "uid-" + deviceID,
+			ModemIMEI:       "imei-" + deviceID,
+			FirmwareVersion: "r1",
+		}); err != nil {
+			t.Fatalf("register %s: %v", deviceID, err)
+		}
+	}
+
+	device, err := svc.OperatorSetDeviceLabels("dev-1", OperatorDeviceLabelsRequest{Labels: []string{" Lab ", "nightly", "lab"}})
+	if err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+	if strings.Join(device.Labels, ",") != "lab,nightly" {
+		t.Fatalf("labels = %v", device.Labels)
+	}
+	if _, err := svc.OperatorSetDeviceLabels("dev-3", OperatorDeviceLabelsRequest{Labels: []string{"nightly"}}); err != nil {
+		t.Fatalf("set labels: %v", err)
+	}
+	if _, err := svc.OperatorSetDeviceLabels("dev-2", OperatorDeviceLabelsRequest{Labels: []string{"bad label"}}); err == nil {
+		t.Fatal("expected invalid label error")
+	}
+
+	now := time.Date(2026, time.March, 2, 9, 58, 0, 0, time.UTC)
+	svc.nowFn = func() time.Time { return now }
+	if _, err := svc.OperatorCreateSchedule(OperatorScheduleRequest{
+		Name:      "bad selector",
+		Cron:      "0 10 * * *",
+		DeviceIDs: []string{"label:Not Valid"},
+		Type:      "swd_reset",
+	}, "alice"); err == nil {
+		t.Fatal("expected invalid label selector error")
+	}
+	if _, err := svc.OperatorCreateSchedule(OperatorScheduleRequest{
+		Name:      "empty selector",
+		Cron:      "0 10 * * *",
+		DeviceIDs: []string{"label:"},
+		Type:      "swd_reset",
+	}, "alice"); err == nil || !strings.Contains(err.Error(), "must not be empty") {
+		t.Fatalf("expected empty label selector error, got %v", err)
+	}
+	schedule, err := svc.OperatorCreateSchedule(OperatorScheduleRequest{
+		Name:      "nightly reset",
+		Cron:      "0 10 * * *",
+		DeviceIDs: []string{"label:nightly", "dev-1"},
+		Type:      "swd_reset",
+	}, "alice")
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	now = now.Add(2 * time.Minute)
+	svc.runDueSchedules(now)
+	stored, err := svc.OperatorGetSchedule(schedule.ScheduleID)
+	if err != nil || len(stored.History) != 1 {
+		t.Fatalf("get schedule: %v (%+v)", err, stored)
+	}
+	if run := stored.History[0]; len(run.CommandIDs) != 2 || len(run.Errors) != 0 {
+		t.Fatalf("unexpected run: %+v", run)
+	}
+	for deviceID, want := range map[string]int{"dev-1": 1, "dev-2": 0, "dev-3": 1} {
+		if commands, _ := svc.OperatorListCommands(deviceID, 10); len(commands) != want {
+			t.Fatalf("%s got %d commands, want %d", deviceID, len(commands), want)
+		}
+	}
+}
+
+func TestCreateCommandFromTemplate(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	_, err := svc.OperatorCreateTemplate(OperatorTemplateRequest{
+		Name:    "read_fault_regs",
+		Type:    "swd_read_memory",
+		Payload: json.RawMessage(`{"address":"{{base}}","length":"{{length}}","label":"faults-{{device_id}}"}`),
+		Params: []model.TemplateParam{
+			{Name: "base"},
+			{Name: "length", Default: json.RawMessage(`20`)},
+		},
+	}, "alice")
+	if err != nil {
+		t.Fatalf("create template: %v", err)
+	}
+
+	command, err := svc.OperatorCreateCommand(OperatorCommandRequest{
+		DeviceID: "dev-1",
+		Template: "read_fault_regs",
+		Params:   map[string]json.RawMessage{"base": json.RawMessage(`"0xE000ED28"`)},
+	}, "alice")
+	if err != nil {
+		t.Fatalf("create command from template: %v", err)
+	}
+	if command.Type != "swd_read_memory" || command.Template != "read_fault_regs" {
+		t.Fatalf("unexpected command: %+v", command)
+	}
+	if got, want := string(command.Payload), `{"address":"0xE000ED28","label":"faults-dev-1","length":20}`; got != want {
+		t.Fatalf("payload = %s, want %s", got, want)
+	}
+
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Template: "read_fault_regs"}, "alice"); err == nil {
+		t.Fatal("expected missing param error")
+	}
+	if _, err := svc.OperatorCreateTemplate(OperatorTemplateRequest{
+		Name:    "broken",
+		Type:    "swd_reset",
+		Payload: json.RawMessage(`{"mode":"{{mode}}"}`),
+	}, "alice"); err == nil {
+		t.Fatal("expected undeclared placeholder error")
+	}
+}
+
+func TestCreateCommandRespectsDeviceCapabilities(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	_, err := svc.RegisterDevice(RegisterDeviceRequest{
+		EnrollKey:       "enroll",
+		DeviceID:        "dev-2",
+		HWUID:           "uid-2",
+		ModemIMEI:       "imei-2",
+		FirmwareVersion: "r1",
+		Capabilities: &model.DeviceCapabilities{
+			CommandTypes:  []string{"swd_connect", "swd_reset", "swd_reset"},
+			MaxSWDClockHz: 4000000,
+		},
+	})
+	if err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+
+	device, err := svc.OperatorGetDevice("dev-2")
+	if err != nil || device.Capabilities == nil || len(device.Capabilities.CommandTypes) != 2 {
+		t.Fatalf("unexpected capabilities: %v %+v", err, device)
+	}
+
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-2", Type: "swd_erase"}, "alice"); err == nil {
+		t.Fatal("expected unsupported command error")
+	}
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-2", Type: "swd_connect", Payload: json.RawMessage(`{"swd_clock_hz":8000000}`)}, "alice"); err == nil {
+		t.Fatal("expected clock limit error")
+	}
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-2", Type: "swd_connect", Payload: json.RawMessage(`{"swd_clock_hz":1000000}`)}, "alice"); err != nil {
+		t.Fatalf("create supported command: %v", err)
+	}
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_erase"}, "alice"); err != nil {
+		t.Fatalf("device without capabilities must accept every type: %v", err)
+	}
+}
+
+func TestMultiTargetCommands(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{})
+	resp, err := svc.RegisterDevice(RegisterDeviceRequest{
+		EnrollKey:       "enroll",
+		DeviceID:        "rig-1",
+		HWUID:           "uid-rig",
+		ModemIMEI:       "imei-rig",
+		FirmwareVersion: "r1",
+		Targets: []model.DeviceTarget{
+			{Index: 0, Name: "main", ExpectedMCU: "STM32F4"},
+			{Index: 1, Name: "radio", ExpectedMCU: "nRF52840"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("register rig: %v", err)
+	}
+
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "rig-1", Type: "swd_connect"}, "alice"); err == nil {
+		t.Fatal("expected target required error")
+	}
+	missing := 2
+	if _, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "rig-1", Type: "swd_connect", Target: &missing}, "alice"); err == nil {
+		t.Fatal("expected unknown target error")
+	}
+
+	radio := 1
+	command, err := svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "rig-1", Type: "swd_connect", Target: &radio}, "alice")
+	if err != nil {
+		t.Fatalf("create command: %v", err)
+	}
+	if _, err := svc.DevicePullCommand(context.Background(), DevicePullRequest{DeviceID: "rig-1", DeviceToken: resp.DeviceToken}); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if _, err := svc.DeviceCommandResult(DeviceCommandResultRequest{
+		DeviceID:    "rig-1",
+		DeviceToken: resp.DeviceToken,
+		CommandID:   command.CommandID,
+		Status:      model.CommandSuccess,
+		Message:     "idcode 0x2ba01477",
+	}); err != nil {
+		t.Fatalf("result: %v", err)
+	}
+
+	device, err := svc.OperatorGetDevice("rig-1")
+	if err != nil {
+		t.Fatalf("get device: %v", err)
+	}
+	if device.Targets[0].LastConnect != nil {
+		t.Fatalf("target 0 must not change: %+v", device.Targets[0].LastConnect)
+	}
+	if connect := device.Targets[1].LastConnect; connect == nil || connect.CommandID != command.CommandID || connect.Status != model.CommandSuccess {
+		t.Fatalf("unexpected target 1 state: %+v", connect)
+	}
+}
+
+func TestDeviceArtifactChunks(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{MaxArtifactBytes: 16 * 1024})
+	image := bytes.Repeat([]byte("0123456789"), 1000)
+	artifact, err := svc.OperatorStreamArtifact(OperatorArtifactStream{
+		Name:          "fw.bin",
+		ContentLength: int64(len(image)),
+		Body:          bytes.NewReader(image),
+	}, "operator")
+	if err != nil {
+		t.Fatalf("upload: %v", err)
+	}
+
+	req := DeviceChunkRequest{DeviceID: "dev-1", DeviceToken: token, ArtifactID: artifact.ArtifactID, ChunkSize: 4096}
+	manifest, err := svc.DeviceArtifactManifest(req)
+	if err != nil {
+		t.Fatalf("manifest: %v", err)
+	}
+	if manifest.ChunkCount != 3 || len(manifest.Chunks) != 3 || manifest.SHA256 != artifact.PayloadSHA256 {
+		t.Fatalf("unexpected manifest: %+v", manifest)
+	}
+	if last := manifest.Chunks[2]; last.Offset != 8192 || last.Size != 10000-8192 {
+		t.Fatalf("unexpected last chunk: %+v", last)
+	}
+
+	for _, entry := range manifest.Chunks {
+		chunk, data, err := svc.DeviceArtifactChunk(req, entry.Index)
+		if err != nil {
+			t.Fatalf("chunk %d: %v", entry.Index, err)
+		}
+		if *chunk != entry || !bytes.Equal(data, image[entry.Offset:entry.Offset+entry.Size]) {
+			t.Fatalf("chunk %d does not match manifest", entry.Index)
+		}
+		if want := fmt.Sprintf("%08x", crc32.ChecksumIEEE(data)); chunk.CRC32 != want {
+			t.Fatalf("chunk %d crc32 = %s, want %s", entry.Index, chunk.CRC32, want)
+		}
+	}
+
+	if _, _, err := svc.DeviceArtifactChunk(req, 3); err == nil || !strings.Contains(err.Error(), "invalid chunk index") {
+		t.Fatalf("expected invalid chunk index, got %v", err)
+	}
+
+	req.First, req.Count = 1, 1
+	page, err := svc.DeviceArtifactManifest(req)
+	if err != nil || page.First != 1 || len(page.Chunks) != 1 || page.Chunks[0] != manifest.Chunks[1] {
+		t.Fatalf("unexpected manifest page: %+v, %v", page, err)
+	}
+
+	req.ChunkSize = 100
+	if _, err := svc.DeviceArtifactManifest(req); err == nil || !strings.Contains(err.Error(), "invalid chunk_size") {
+		t.Fatalf("expected invalid chunk_size, got %v", err)
+	}
+	if len(svc.chunkCache) != 1 || svc.chunkCached != 3 {
+		t.Fatalf("manifest digests not cached once: %d layouts, %d chunks", len(svc.chunkCache), svc.chunkCached)
+	}
+}
+
+func TestResolveChunkSize(t *testing.T) {
+	t.Parallel()
+
+	withBuffer := func(bytes int) *model.Device {
+		return &model.Device{Capabilities: &model.DeviceCapabilities{RAMBufferBytes: bytes}}
+	}
+	for _, tc := range []struct {
+		requested int64
+		device    *model.Device
+		want      int64
+		err       string
+	}{
+		{device: &model.Device{}, want: defaultChunkSize},
+		{device: withBuffer(3000), want: 2048},
+		{device: withBuffer(256), want: 256},
+		{requested: 1024, device: withBuffer(3000), want: 1024},
+		{requested: 4096, device: withBuffer(3000), err: "exceeds device ram_buffer_bytes"},
+		{device: withBuffer(100), err: "below minimum chunk size"},
+		{requested: 100, device: &model.Device{}, err: "must be within"},
+	} {
+		got, err := resolveChunkSize(tc.requested, tc.device)
+		if tc.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tc.err) {
+				t.Fatalf("resolveChunkSize(%d, %+v) error = %v, want %q", tc.requested, tc.device.Capabilities, err, tc.err)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("resolveChunkSize(%d, %+v) = %d, %v, want %d", tc.requested, tc.device.Capabilities, got, err, tc.want)
+		}
+	}
+}
+
+func TestDeviceArtifactDelta(t *testing.T) {
+	t.Parallel()
+
+	svc, token := newTestService(t, config.Config{MaxArtifactBytes: 64 * 1024})
+	upload := func(name string, data []byte) *model.Artifact {
+		t.Helper()
+		artifact, err := svc.OperatorStreamArtifact(OperatorArtifactStream{
+			Name:          name,
+			ContentLength: int64(len(data)),
+			Body:          bytes.NewReader(data),
+		}, "operator")
+		if err != nil {
+			t.Fatalf("upload %s: %v", name, err)
+		}
+		return artifact
+	}
+
+	base := make([]byte, 32*1024)
+	for i := range base {
+		base[i] = byte(i*7 + i/251)
+	}
+	fixed := append([]byte(nil), base...)
+	copy(fixed[20000:], []byte("bugfix"))
+	v1 := upload("fw-v1.bin", base)
+	v2 := upload("fw-v2.bin", fixed)
+
+	req := DeviceDeltaRequest{DeviceID: "dev-1", DeviceToken: token, ArtifactID: v2.ArtifactID}
+	if _, _, _, err := svc.DeviceArtifactDelta(req); !errors.Is(err, store.ErrArtifactDeltaNotFound) {
+		t.Fatalf("expected no delta before base is programmed, got %v", err)
+	}
+
+	command, err := svc.OperatorCreateCommand(OperatorCommandRequest{
+		DeviceID: "dev-1",
+		Type:     "swd_program",
+		Payload:  json.RawMessage(`{"artifact_id":"` + v1.ArtifactID + `"}`),
+	}, "operator")
+	if err != nil {
+		t.Fatalf("create command: %v", err)
+	}
+	if _, err := svc.DevicePullCommand(context.Background(), DevicePullRequest{DeviceID: "dev-1", DeviceToken: token}); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	if _, err := svc.DeviceCommandResult(DeviceCommandResultRequest{DeviceID: "dev-1", DeviceToken: token, CommandID: command.CommandID, Status: model.CommandSuccess}); err != nil {
+		t.Fatalf("result: %v", err)
+	}
+
+	// Devices never wait for a diff; the miss queues one for the background pass.
+	if _, _, _, err := svc.DeviceArtifactDelta(req); !errors.Is(err, store.ErrArtifactDeltaNotFound) {
+		t.Fatalf("expected no delta before it is built, got %v", err)
+	}
+	svc.runPendingDeltas()
+	artifact, patch, file, err := svc.DeviceArtifactDelta(req)
+	if err != nil {
+		t.Fatalf("delta: %v", err)
+	}
+	data, err := io.ReadAll(file)
+	file.Close()
+	if err != nil {
+		t.Fatalf("read delta: %v", err)
+	}
+	if patch.BaseArtifactID != v1.ArtifactID || int64(len(data)) != patch.Size || patch.Size >= artifact.Size {
+		t.Fatalf("unexpected delta: %+v (%d bytes)", patch, len(data))
+	}
+	rebuilt, err := delta.Apply(base, data)
+	if err != nil || !bytes.Equal(rebuilt, fixed) {
+		t.Fatalf("delta does not rebuild target: %v", err)
+	}
+
+	req.Resume = true
+	if _, _, file, err := svc.DeviceArtifactDelta(req); err != nil {
+		t.Fatalf("resume delta: %v", err)
+	} else {
+		file.Close()
+	}
+	stored, err := svc.OperatorGetArtifact(v2.ArtifactID)
+	if err != nil {
+		t.Fatalf("get artifact: %v", err)
+	}
+	if len(stored.Deltas) != 1 || stored.Deltas[0].Downloads != 1 || stored.Deltas[0].BytesSaved != v2.Size-patch.Size {
+		t.Fatalf("unexpected delta accounting: %+v", stored.Deltas)
+	}
+
+	hex := upload("fw-v3.hex", []byte(":0400000001020304F2\n:00000001FF\n"))
+	if _, err := svc.OperatorCreateArtifactDelta(hex.ArtifactID, OperatorArtifactDeltaRequest{BaseArtifactID: v1.ArtifactID}, "operator"); err == nil || !strings.Contains(err.Error(), "invalid artifact") {
+		t.Fatalf("expected ihex artifact to be refused, got %v", err)
+	}
+	req.ArtifactID = hex.ArtifactID
+	if _, _, _, err := svc.DeviceArtifactDelta(req); !errors.Is(err, store.ErrArtifactDeltaNotFound) {
+		t.Fatalf("expected no delta for ihex artifact, got %v", err)
+	}
+	if len(svc.deltaPending) != 0 {
+		t.Fatalf("ihex delta must not be queued: %v", svc.deltaPending)
+	}
+	req.ArtifactID = v2.ArtifactID
+
+	if err := svc.OperatorDeleteArtifact(v1.ArtifactID); err != nil {
+		t.Fatalf("delete base: %v", err)
+	}
+	if _, _, _, err := svc.DeviceArtifactDelta(req); !errors.Is(err, store.ErrArtifactDeltaNotFound) {
+		t.Fatalf("expected no delta after base deletion, got %v", err)
+	}
+}
+
+func TestReleaseChannels(t *testing.T) {
+	t.Parallel()
+
+	svc, _ := newTestService(t, config.Config{MaxArtifactBytes: 1024})
+	upload := func(data string) *model.Artifact {
+		t.Helper()
+		artifact, err := svc.OperatorStreamArtifact(OperatorArtifactStream{
+			Name:          "fw.bin",
+			ContentLength: int64(len(data)),
+			Body:          strings.NewReader(data),
+		}, "operator")
+		if err != nil {
+			t.Fatalf("upload: %v", err)
+		}
+		return artifact
+	}
+	v1, v2 := upload("firmware v1"), upload("firmware v2")
+	empty := ""
+
+	if _, err := svc.OperatorPromoteChannel("Widget", "stable", OperatorChannelPromotion{ArtifactID: v1.ArtifactID, ExpectedArtifactID: &empty}, "alice"); err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	if _, err := svc.OperatorPromoteChannel("widget", "stable", OperatorChannelPromotion{ArtifactID: v2.ArtifactID, ExpectedArtifactID: &empty}, "bob"); !errors.Is(err, store.ErrChannelConflict) {
+		t.Fatalf("expected conflict for stale expected artifact, got %v", err)
+	}
+	channel, err := svc.OperatorPromoteChannel("widget", "stable", OperatorChannelPromotion{ArtifactID: v2.ArtifactID, ExpectedArtifactID: &v1.ArtifactID, Reason: "fixes watchdog"}, "bob")
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if channel.ArtifactID != v2.ArtifactID || len(channel.History) != 2 || channel.History[1].PreviousArtifactID != v1.ArtifactID || channel.History[1].PromotedBy != "bob" {
+		t.Fatalf("unexpected channel: %+v", channel)
+	}
+
+	command, err := svc.OperatorCreateCommand(OperatorCommandRequest{
+		DeviceID: "dev-1",
+		Type:     "swd_program",
+		Payload:  json.RawMessage(`{"channel":"stable","verify":true}`),
+	}, "operator")
+	if err != nil {
+		t.Fatalf("create command: %v", err)
+	}
+	if payloadArtifact(t, command.Payload) != v2.ArtifactID || command.Channel == nil || command.Channel.Product != "widget" || command.Channel.ArtifactID != v2.ArtifactID {
+		t.Fatalf("channel not resolved: payload %s, channel %+v", command.Payload, command.Channel)
+	}
+
+	if _, err := svc.OperatorPromoteChannel("gadget", "stable", OperatorChannelPromotion{ArtifactID: v1.ArtifactID}, "alice"); err != nil {
+		t.Fatalf("create second product channel: %v", err)
+	}
+	_, err = svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_program", Payload: json.RawMessage(`{"channel":"stable"}`)}, "operator")
+	if err == nil || !strings.Contains(err.Error(), "product is required") {
+		t.Fatalf("expected ambiguous channel error, got %v", err)
+	}
+	command, err = svc.OperatorCreateCommand(OperatorCommandRequest{DeviceID: "dev-1", Type: "swd_program", Payload: json.RawMessage(`{"channel":"stable","product":"gadget"}`)}, "operator")
+	if err != nil || payloadArtifact(t, command.Payload) != v1.ArtifactID {
+		t.Fatalf("expected gadget channel to resolve to v1: %v", err)
+	}
+
+	if err := svc.OperatorDeleteArtifact(v2.ArtifactID); !errors.Is(err, store.ErrArtifactInUse) {
+		t.Fatalf("expected artifact behind channel to be in use, got %v", err)
+	}
+}
+
+func payloadArtifact(t *testing.T, payload json.RawMessage) string {
+	t.Helper()
+	var ref struct {
+		ArtifactID string `json:"artifact_id"`
+		Channel    string `json:"channel"`
+	}
+	if err := json.Unmarshal(payload, &ref); err != nil || ref.Channel != "" {
+		t.Fatalf("unexpected payload %s: %v", payload, err)
+	}
+	return ref.ArtifactID
+}
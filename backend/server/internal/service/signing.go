@@ -0,0 +1,157 @@
+package service
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/store"
+)
+
+// ArtifactSigningPolicy tells operators which release keys are trusted and
+// whether command types declaring RequiresSignature insist on a signature.
+type ArtifactSigningPolicy struct {
+	KeyIDs   []string `json:"key_ids"`
+	Required bool     `json:"required"`
+}
+
+// ArtifactSigningPolicy returns configured signing policy.
+func (s *Service) ArtifactSigningPolicy() ArtifactSigningPolicy {
+	return ArtifactSigningPolicy{KeyIDs: s.trustedKeyIDs(), Required: s.cfg.RequireSignedArtifacts}
+}
+
+// OperatorArtifactSignatureRequest attaches detached signature to stored artifact.
+type OperatorArtifactSignatureRequest struct {
+	Signature string `json:"signature"`
+	KeyID     string `json:"key_id"`
+}
+
+// OperatorSignArtifact verifies signature over stored artifact bytes and
+// attaches it, e.g. for artifacts uploaded before release signing.
+func (s *Service) OperatorSignArtifact(artifactID string, req OperatorArtifactSignatureRequest, operator string) (*model.Artifact, error) {
+	artifactID = strings.TrimSpace(artifactID)
+	if artifactID == "" {
+		return nil, errors.New("artifact_id is required")
+	}
+	if strings.TrimSpace(req.Signature) == "" {
+		return nil, errors.New("signature is required")
+	}
+
+	_, file, err := s.store.OpenArtifact(artifactID)
+	if err != nil {
+		return nil, err
+	}
+	signature, err := s.verifyArtifactSignature(file, req.Signature, req.KeyID, operator)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	return s.store.SetArtifactSignature(artifactID, *signature)
+}
+
+// signStagedArtifact verifies optional upload signature and records it on staged artifact.
+func (s *Service) signStagedArtifact(staged *store.StagedArtifact, rawSignature, keyID, operator string) error {
+	if strings.TrimSpace(rawSignature) == "" {
+		return nil
+	}
+	file, err := staged.Open()
+	if err != nil {
+		return fmt.Errorf("open staged artifact: %w", err)
+	}
+	defer file.Close()
+
+	signature, err := s.verifyArtifactSignature(file, rawSignature, keyID, operator)
+	if err != nil {
+		return err
+	}
+	staged.Signature = signature
+	return nil
+}
+
+// verifyArtifactSignature checks base64 Ed25519ph signature over payload's
+// SHA-512, hashed while streaming. With keyID empty every trusted key is
+// tried and the matching one is recorded.
+func (s *Service) verifyArtifactSignature(payload io.Reader, rawSignature, keyID, operator string) (*model.ArtifactSignature, error) {
+	rawSignature = strings.TrimSpace(rawSignature)
+	signature, err := base64.StdEncoding.DecodeString(rawSignature)
+	if err != nil || len(signature) != ed25519.SignatureSize {
+		return nil, fmt.Errorf("invalid signature: must be base64 of %d-byte Ed25519 signature", ed25519.SignatureSize)
+	}
+
+	keyIDs := s.trustedKeyIDs()
+	if keyID = strings.TrimSpace(keyID); keyID != "" {
+		if _, ok := s.cfg.ArtifactSigningKeys[keyID]; !ok {
+			return nil, fmt.Errorf("%w: unknown key_id %q", store.ErrArtifactSignature, keyID)
+		}
+		keyIDs = []string{keyID}
+	}
+	if len(keyIDs) == 0 {
+		return nil, fmt.Errorf("%w: no trusted signing keys configured", store.ErrArtifactSignature)
+	}
+
+	hash := sha512.New()
+	if _, err := io.Copy(hash, payload); err != nil {
+		return nil, fmt.Errorf("read artifact: %w", err)
+	}
+	digest := hash.Sum(nil)
+	options := &ed25519.Options{Hash: crypto.SHA512}
+	for _, id := range keyIDs {
+		if ed25519.VerifyWithOptions(s.cfg.ArtifactSigningKeys[id], digest, signature, options) == nil {
+			return &model.ArtifactSignature{
+				Algorithm:  model.SignatureEd25519ph,
+				KeyID:      id,
+				Signature:  rawSignature,
+				VerifiedAt: s.nowFn().UTC(),
+				AttachedBy: operator,
+			}, nil
+		}
+	}
+	return nil, store.ErrArtifactSignature
+}
+
+func (s *Service) trustedKeyIDs() []string {
+	ids := make([]string, 0, len(s.cfg.ArtifactSigningKeys))
+	for id := range s.cfg.ArtifactSigningKeys {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// checkArtifactSigned enforces REQUIRE_SIGNED_ARTIFACTS for command payloads
+// naming an artifact. The key must still be configured, so removing a key
+// from ARTIFACT_SIGNING_KEYS revokes artifacts it signed.
+func (s *Service) checkArtifactSigned(commandType string, payload json.RawMessage) error {
+	if !s.cfg.RequireSignedArtifacts {
+		return nil
+	}
+	if registered, ok := s.types.Lookup(commandType); !ok || !registered.Info().RequiresSignature {
+		return nil
+	}
+
+	var ref struct {
+		ArtifactID string `json:"artifact_id"`
+	}
+	if err := json.Unmarshal(payload, &ref); err != nil || strings.TrimSpace(ref.ArtifactID) == "" {
+		return fmt.Errorf("%w: %s requires artifact_id", store.ErrArtifactUnsigned, commandType)
+	}
+	artifact, err := s.store.GetArtifact(strings.TrimSpace(ref.ArtifactID))
+	if err != nil {
+		return err
+	}
+	if artifact.Signature == nil {
+		return fmt.Errorf("%w: %s", store.ErrArtifactUnsigned, artifact.ArtifactID)
+	}
+	if _, ok := s.cfg.ArtifactSigningKeys[artifact.Signature.KeyID]; !ok {
+		return fmt.Errorf("%w: %s is signed by untrusted key %q", store.ErrArtifactUnsigned, artifact.ArtifactID, artifact.Signature.KeyID)
+	}
+	return nil
+}
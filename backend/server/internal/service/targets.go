@@ -0,0 +1,72 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// maxDeviceTargets bounds targets one probe may declare behind its SWD mux.
+const maxDeviceTargets = 8
+
+// normalizeTargets validates declared targets; nil means not reported.
+func normalizeTargets(targets []model.DeviceTarget) ([]model.DeviceTarget, error) {
+	if targets == nil {
+		return nil, nil
+	}
+	if len(targets) > maxDeviceTargets {
+		return nil, fmt.Errorf("invalid targets: at most %d targets are supported", maxDeviceTargets)
+	}
+
+	out := make([]model.DeviceTarget, 0, len(targets))
+	seen := make(map[int]struct{}, len(targets))
+	for _, target := range targets {
+		if target.Index < 0 {
+			return nil, errors.New("invalid targets: index must not be negative")
+		}
+		if _, dup := seen[target.Index]; dup {
+			return nil, fmt.Errorf("invalid targets: duplicate index %d", target.Index)
+		}
+		seen[target.Index] = struct{}{}
+
+		name := strings.TrimSpace(target.Name)
+		if name == "" {
+			name = fmt.Sprintf("target%d", target.Index)
+		}
+		out = append(out, model.DeviceTarget{
+			Index:       target.Index,
+			Name:        name,
+			ExpectedMCU: strings.TrimSpace(target.ExpectedMCU),
+		})
+	}
+	return out, nil
+}
+
+// resolveTarget checks requested target against device declaration. A probe
+// with zero or one declared target may omit it; multi-target probes must not.
+func resolveTarget(device *model.Device, requested *int) (*int, error) {
+	if len(device.Targets) == 0 {
+		if requested != nil && *requested != 0 {
+			return nil, fmt.Errorf("invalid target %d: device %s declares no targets", *requested, device.DeviceID)
+		}
+		return nil, nil
+	}
+
+	if requested == nil {
+		if len(device.Targets) > 1 {
+			return nil, fmt.Errorf("target is required: device %s has %d targets", device.DeviceID, len(device.Targets))
+		}
+		index := device.Targets[0].Index
+		return &index, nil
+	}
+
+	for _, target := range device.Targets {
+		if target.Index == *requested {
+			index := target.Index
+			return &index, nil
+		}
+	}
+	return nil, fmt.Errorf("invalid target %d for device %s", *requested, device.DeviceID)
+}
@@ -0,0 +1,229 @@
+package service
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// templateDeviceParam is always available to templates and holds target device id.
+const templateDeviceParam = "device_id"
+
+var (
+	templateNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9_.-]{0,63}$`)
+	templateParamPattern = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]{0,63}$`)
+	placeholderPattern   = regexp.MustCompile(`\{\{\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*\}\}`)
+)
+
+// OperatorTemplateRequest describes named command preset.
+type OperatorTemplateRequest struct {
+	Name        string                `json:"name"`
+	Description string                `json:"description"`
+	Type        string                `json:"type"`
+	Payload     json.RawMessage       `json:"payload"`
+	Params      []model.TemplateParam `json:"params"`
+}
+
+// OperatorCreateTemplate stores new command template.
+func (s *Service) OperatorCreateTemplate(req OperatorTemplateRequest, operator string) (*model.CommandTemplate, error) {
+	template, err := s.buildTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+	template.CreatedBy = operator
+	return s.store.SaveTemplate(template, true, s.nowFn().UTC())
+}
+
+// OperatorUpdateTemplate replaces existing template definition.
+func (s *Service) OperatorUpdateTemplate(name string, req OperatorTemplateRequest) (*model.CommandTemplate, error) {
+	req.Name = strings.TrimSpace(name)
+	template, err := s.buildTemplate(req)
+	if err != nil {
+		return nil, err
+	}
+	return s.store.SaveTemplate(template, false, s.nowFn().UTC())
+}
+
+// OperatorDeleteTemplate removes template; commands created from it are kept.
+func (s *Service) OperatorDeleteTemplate(name string) error {
+	return s.store.DeleteTemplate(strings.TrimSpace(name))
+}
+
+// OperatorGetTemplate returns template by name.
+func (s *Service) OperatorGetTemplate(name string) (*model.CommandTemplate, error) {
+	return s.store.GetTemplate(strings.TrimSpace(name))
+}
+
+// OperatorListTemplates returns all templates.
+func (s *Service) OperatorListTemplates() []*model.CommandTemplate {
+	return s.store.ListTemplates()
+}
+
+func (s *Service) buildTemplate(req OperatorTemplateRequest) (model.CommandTemplate, error) {
+	template := model.CommandTemplate{
+		Name:        strings.TrimSpace(req.Name),
+		Description: strings.TrimSpace(req.Description),
+		Type:        strings.TrimSpace(req.Type),
+		Payload:     req.Payload,
+		Params:      req.Params,
+	}
+
+	if template.Name == "" || template.Type == "" {
+		return template, errors.New("name and type are required")
+	}
+	if !templateNamePattern.MatchString(template.Name) {
+		return template, errors.New("invalid template name: use lowercase letters, digits, '_', '-' or '.'")
+	}
+	if _, ok := s.types.Lookup(template.Type); !ok {
+		return template, fmt.Errorf("unsupported command type: %s", template.Type)
+	}
+	if len(template.Payload) == 0 {
+		template.Payload = json.RawMessage(`{}`)
+	}
+	if !json.Valid(template.Payload) {
+		return template, errors.New("payload must be valid json")
+	}
+
+	declared := map[string]struct{}{templateDeviceParam: {}}
+	for i, param := range template.Params {
+		param.Name = strings.TrimSpace(param.Name)
+		if !templateParamPattern.MatchString(param.Name) {
+			return template, fmt.Errorf("invalid template param name %q", param.Name)
+		}
+		if _, dup := declared[param.Name]; dup {
+			return template, fmt.Errorf("invalid template param %q: duplicate or reserved", param.Name)
+		}
+		if param.Default != nil && !json.Valid(param.Default) {
+			return template, fmt.Errorf("invalid default for template param %q", param.Name)
+		}
+		declared[param.Name] = struct{}{}
+		template.Params[i] = param
+	}
+
+	for _, match := range placeholderPattern.FindAllStringSubmatch(string(template.Payload), -1) {
+		if _, ok := declared[match[1]]; !ok {
+			return template, fmt.Errorf("invalid template payload: placeholder {{%s}} is not declared in params", match[1])
+		}
+	}
+	return template, nil
+}
+
+// applyTemplate fills req.Type and req.Payload from named template.
+func (s *Service) applyTemplate(req *OperatorCommandRequest) error {
+	template, err := s.store.GetTemplate(strings.TrimSpace(req.Template))
+	if err != nil {
+		return err
+	}
+	if len(req.Payload) > 0 {
+		return errors.New("invalid command: send either payload or template params")
+	}
+	if req.Type != "" && strings.TrimSpace(req.Type) != template.Type {
+		return fmt.Errorf("invalid command: template %s has type %s", template.Name, template.Type)
+	}
+
+	payload, err := renderTemplate(template, req.Params, strings.TrimSpace(req.DeviceID))
+	if err != nil {
+		return err
+	}
+	req.Template = template.Name
+	req.Type = template.Type
+	req.Payload = payload
+	return nil
+}
+
+// renderTemplate substitutes placeholders. A string that is exactly one
+// placeholder takes the param's JSON value (so numbers stay numbers); a
+// placeholder inside longer text is replaced by the value's text form.
+func renderTemplate(template *model.CommandTemplate, params map[string]json.RawMessage, deviceID string) (json.RawMessage, error) {
+	values := make(map[string]json.RawMessage, len(template.Params)+1)
+	for name := range params {
+		if name == templateDeviceParam {
+			return nil, fmt.Errorf("invalid template params: %s comes from the command", templateDeviceParam)
+		}
+	}
+	for _, param := range template.Params {
+		value, ok := params[param.Name]
+		if !ok {
+			value = param.Default
+		}
+		if value == nil {
+			return nil, fmt.Errorf("template param %s is required", param.Name)
+		}
+		if !json.Valid(value) {
+			return nil, fmt.Errorf("invalid value for template param %s", param.Name)
+		}
+		values[param.Name] = value
+	}
+	for name := range params {
+		if _, ok := values[name]; !ok {
+			return nil, fmt.Errorf("invalid template params: unknown param %s", name)
+		}
+	}
+	deviceValue, _ := json.Marshal(deviceID)
+	values[templateDeviceParam] = deviceValue
+
+	decoder := json.NewDecoder(bytes.NewReader(template.Payload))
+	decoder.UseNumber()
+	var payload interface{}
+	if err := decoder.Decode(&payload); err != nil {
+		return nil, fmt.Errorf("invalid template payload: %w", err)
+	}
+
+	rendered, err := substitutePlaceholders(payload, values)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(rendered)
+}
+
+func substitutePlaceholders(node interface{}, values map[string]json.RawMessage) (interface{}, error) {
+	switch typed := node.(type) {
+	case map[string]interface{}:
+		for key, child := range typed {
+			replaced, err := substitutePlaceholders(child, values)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = replaced
+		}
+		return typed, nil
+	case []interface{}:
+		for i, child := range typed {
+			replaced, err := substitutePlaceholders(child, values)
+			if err != nil {
+				return nil, err
+			}
+			typed[i] = replaced
+		}
+		return typed, nil
+	case string:
+		if match := placeholderPattern.FindStringSubmatch(typed); match != nil && match[0] == typed {
+			return decodeParamValue(values[match[1]])
+		}
+		return placeholderPattern.ReplaceAllStringFunc(typed, func(placeholder string) string {
+			name := placeholderPattern.FindStringSubmatch(placeholder)[1]
+			var text string
+			if err := json.Unmarshal(values[name], &text); err == nil {
+				return text
+			}
+			return compactJSON(values[name])
+		}), nil
+	default:
+		return node, nil
+	}
+}
+
+func decodeParamValue(raw json.RawMessage) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, fmt.Errorf("invalid template param value: %w", err)
+	}
+	return value, nil
+}
@@ -0,0 +1,129 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+func (s *StateStore) artifactDeltaPath(artifactID, baseArtifactID string) string {
+	return filepath.Join(s.artifactDir(), fmt.Sprintf("%s.from-%s.delta", artifactID, baseArtifactID))
+}
+
+// SaveArtifactDelta stores patch from baseArtifactID to artifactID, replacing
+// an earlier patch between the same pair.
+func (s *StateStore) SaveArtifactDelta(artifactID, baseArtifactID string, patch []byte, createdBy string, now time.Time) (*model.ArtifactDelta, error) {
+	if err := os.MkdirAll(s.artifactDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("create artifact dir: %w", err)
+	}
+	tempPath, err := writeTempBlob(s.artifactDir(), "delta-*.tmp", patch)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.state.Artifacts[artifactID]
+	if !ok {
+		_ = os.Remove(tempPath)
+		return nil, ErrArtifactNotFound
+	}
+	if _, ok := s.state.Artifacts[baseArtifactID]; !ok {
+		_ = os.Remove(tempPath)
+		return nil, ErrArtifactNotFound
+	}
+	// Deltas count toward the quota; a replaced patch frees its own bytes.
+	growth := int64(len(patch))
+	for _, previous := range current.Deltas {
+		if previous.BaseArtifactID == baseArtifactID {
+			growth -= previous.Size
+		}
+	}
+	if err := s.checkArtifactQuotaLocked(growth); err != nil {
+		_ = os.Remove(tempPath)
+		return nil, err
+	}
+	if err := os.Rename(tempPath, s.artifactDeltaPath(artifactID, baseArtifactID)); err != nil {
+		_ = os.Remove(tempPath)
+		return nil, fmt.Errorf("store artifact delta: %w", err)
+	}
+
+	sum := sha256.Sum256(patch)
+	delta := model.ArtifactDelta{
+		BaseArtifactID: baseArtifactID,
+		Size:           int64(len(patch)),
+		SHA256:         hex.EncodeToString(sum[:]),
+		CreatedBy:      createdBy,
+		CreatedAt:      now,
+	}
+	previous := current.Deltas
+	current.Deltas = append(withoutDelta(previous, baseArtifactID), delta)
+	if err := s.persistLocked(); err != nil {
+		current.Deltas = previous
+		return nil, err
+	}
+	return &delta, nil
+}
+
+// OpenArtifactDelta returns artifact, its delta from baseArtifactID and the
+// patch file. Caller closes file.
+func (s *StateStore) OpenArtifactDelta(artifactID, baseArtifactID string) (*model.Artifact, *model.ArtifactDelta, *os.File, error) {
+	artifact, err := s.GetArtifact(artifactID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	for i := range artifact.Deltas {
+		if artifact.Deltas[i].BaseArtifactID != baseArtifactID {
+			continue
+		}
+		file, err := os.Open(s.artifactDeltaPath(artifactID, baseArtifactID))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("open artifact delta: %w", err)
+		}
+		return artifact, &artifact.Deltas[i], file, nil
+	}
+	return nil, nil, nil, ErrArtifactDeltaNotFound
+}
+
+// RecordArtifactDeltaDownload counts one delta download and bytes it saved
+// compared to the full image.
+func (s *StateStore) RecordArtifactDeltaDownload(artifactID, baseArtifactID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifact, ok := s.state.Artifacts[artifactID]
+	if !ok {
+		return ErrArtifactNotFound
+	}
+	for i := range artifact.Deltas {
+		delta := &artifact.Deltas[i]
+		if delta.BaseArtifactID != baseArtifactID {
+			continue
+		}
+		previous := *delta
+		delta.Downloads++
+		delta.BytesSaved += artifact.Size - delta.Size
+		if err := s.persistLocked(); err != nil {
+			*delta = previous
+			return err
+		}
+		return nil
+	}
+	return ErrArtifactDeltaNotFound
+}
+
+func withoutDelta(deltas []model.ArtifactDelta, baseArtifactID string) []model.ArtifactDelta {
+	out := make([]model.ArtifactDelta, 0, len(deltas))
+	for _, delta := range deltas {
+		if delta.BaseArtifactID != baseArtifactID {
+			out = append(out, delta)
+		}
+	}
+	return out
+}
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// Content codings artifacts are precompressed with.
+const (
+	EncodingGzip    = "gzip"
+	EncodingDeflate = "deflate"
+)
+
+// artifactEncodingSuffix maps content coding to cached variant suffix. HTTP
+// "deflate" is zlib-wrapped, hence .zz.
+var artifactEncodingSuffix = map[string]string{
+	EncodingGzip:    ".gz",
+	EncodingDeflate: ".zz",
+}
+
+func (s *StateStore) artifactEncodedPath(artifactID, encoding string) string {
+	return s.artifactPath(artifactID) + artifactEncodingSuffix[encoding]
+}
+
+// OpenArtifactEncoded returns artifact metadata and its payload compressed
+// with encoding. The variant is built on first use and cached next to the
+// blob. Caller closes file.
+func (s *StateStore) OpenArtifactEncoded(artifactID, encoding string) (*model.Artifact, *os.File, error) {
+	if _, ok := artifactEncodingSuffix[encoding]; !ok {
+		return nil, nil, fmt.Errorf("unsupported encoding %q", encoding)
+	}
+	artifact, err := s.GetArtifact(artifactID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	path := s.artifactEncodedPath(artifactID, encoding)
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		// Built outside the lock: DeleteArtifact or GC may drop the artifact
+		// meanwhile, and a variant renamed after their cleanup is orphaned.
+		if err := s.compressArtifact(artifactID, encoding, path); err != nil {
+			if !s.hasArtifact(artifactID) {
+				return nil, nil, ErrArtifactNotFound
+			}
+			return nil, nil, err
+		}
+		if err := s.keepEncodedVariant(artifactID, path); err != nil {
+			return nil, nil, err
+		}
+		file, err = os.Open(path)
+	}
+	if err != nil {
+		if !s.hasArtifact(artifactID) {
+			return nil, nil, ErrArtifactNotFound
+		}
+		return nil, nil, fmt.Errorf("open encoded artifact: %w", err)
+	}
+	return artifact, file, nil
+}
+
+// keepEncodedVariant removes freshly built variant at path when artifact is
+// no longer in state, so a build racing deletion leaves no orphan behind.
+func (s *StateStore) keepEncodedVariant(artifactID, path string) error {
+	if s.hasArtifact(artifactID) {
+		return nil
+	}
+	_ = os.Remove(path)
+	return ErrArtifactNotFound
+}
+
+func (s *StateStore) hasArtifact(artifactID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.state.Artifacts[artifactID]
+	return ok
+}
+
+// compressArtifact writes variant to temp file and renames it into place, so
+// concurrent first requests never serve a partial variant.
+func (s *StateStore) compressArtifact(artifactID, encoding, path string) error {
+	source, err := os.Open(s.artifactPath(artifactID))
+	if err != nil {
+		return fmt.Errorf("open artifact: %w", err)
+	}
+	defer source.Close()
+
+	temp, err := os.CreateTemp(s.artifactDir(), "encoded-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp file: %w", err)
+	}
+	defer os.Remove(temp.Name())
+
+	var writer io.WriteCloser
+	switch encoding {
+	case EncodingGzip:
+		writer, err = gzip.NewWriterLevel(temp, flate.BestCompression)
+	default:
+		writer, err = zlib.NewWriterLevel(temp, flate.BestCompression)
+	}
+	if err == nil {
+		_, err = io.Copy(writer, source)
+		if closeErr := writer.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("compress artifact: %w", err)
+	}
+	if err := os.Rename(temp.Name(), path); err != nil {
+		return fmt.Errorf("store encoded artifact: %w", err)
+	}
+	return nil
+}
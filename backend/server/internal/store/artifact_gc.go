@@ -0,0 +1,216 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// artifactUsage is what state knows about one artifact's users.
+type artifactUsage struct {
+	refs     []model.ArtifactReference
+	active   bool
+	lastUsed time.Time
+}
+
+// SetArtifactQuota bounds bytes stored for artifacts; 0 disables the quota.
+func (s *StateStore) SetArtifactQuota(quotaBytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.artifactQuota = quotaBytes
+}
+
+// ArtifactUsedBytes returns bytes stored for artifacts, segments and deltas.
+func (s *StateStore) ArtifactUsedBytes() int64 {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.artifactUsedBytesLocked()
+}
+
+func (s *StateStore) artifactUsedBytesLocked() int64 {
+	var total int64
+	for _, artifact := range s.state.Artifacts {
+		total += artifactStoredBytes(artifact)
+	}
+	return total
+}
+
+// artifactStoredBytes counts payload, segment files and deltas. Compressed
+// variants are a cache and not counted.
+func artifactStoredBytes(artifact *model.Artifact) int64 {
+	total := artifact.Size
+	for _, segment := range artifact.Segments {
+		total += segment.Size
+	}
+	for _, delta := range artifact.Deltas {
+		total += delta.Size
+	}
+	return total
+}
+
+// checkArtifactQuotaLocked refuses storing size more bytes over quota.
+func (s *StateStore) checkArtifactQuotaLocked(size int64) error {
+	if s.artifactQuota <= 0 {
+		return nil
+	}
+	if used := s.artifactUsedBytesLocked(); used+size > s.artifactQuota {
+		return fmt.Errorf("%w: %d of %d bytes used, upload needs %d", ErrArtifactQuotaExceeded, used, s.artifactQuota, size)
+	}
+	return nil
+}
+
+// ArtifactReferences lists commands, schedules, templates, channels and devices naming artifact.
+func (s *StateStore) ArtifactReferences(artifactID string) ([]model.ArtifactReference, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.state.Artifacts[artifactID]; !ok {
+		return nil, ErrArtifactNotFound
+	}
+	refs := s.artifactUsageLocked()[artifactID].refs
+	if refs == nil {
+		refs = []model.ArtifactReference{}
+	}
+	return refs, nil
+}
+
+func (s *StateStore) artifactUsageLocked() map[string]*artifactUsage {
+	usage := make(map[string]*artifactUsage, len(s.state.Artifacts))
+	for id, artifact := range s.state.Artifacts {
+		item := &artifactUsage{lastUsed: artifact.CreatedAt}
+		for _, upload := range artifact.Uploads {
+			if upload.CreatedAt.After(item.lastUsed) {
+				item.lastUsed = upload.CreatedAt
+			}
+		}
+		usage[id] = item
+	}
+	add := func(artifactID string, ref model.ArtifactReference, at time.Time) {
+		item, ok := usage[artifactID]
+		if !ok {
+			return
+		}
+		item.refs = append(item.refs, ref)
+		item.active = item.active || ref.Active
+		if at.After(item.lastUsed) {
+			item.lastUsed = at
+		}
+	}
+
+	for _, commands := range s.state.CommandsByID {
+		for _, command := range commands {
+			add(payloadArtifactID(command.Payload), model.ArtifactReference{
+				Kind:   model.ArtifactRefCommand,
+				ID:     command.CommandID,
+				Detail: string(command.Status),
+				Active: !command.Status.IsTerminal(),
+			}, command.CreatedAt)
+		}
+	}
+	for _, schedule := range s.state.Schedules {
+		add(payloadArtifactID(schedule.Payload), model.ArtifactReference{
+			Kind:   model.ArtifactRefSchedule,
+			ID:     schedule.ScheduleID,
+			Detail: schedule.Name,
+			Active: true,
+		}, schedule.UpdatedAt)
+	}
+	for _, template := range s.state.Templates {
+		add(payloadArtifactID(template.Payload), model.ArtifactReference{
+			Kind:   model.ArtifactRefTemplate,
+			ID:     template.Name,
+			Active: true,
+		}, template.UpdatedAt)
+	}
+	for key, channel := range s.state.Channels {
+		add(channel.ArtifactID, model.ArtifactReference{
+			Kind:   model.ArtifactRefChannel,
+			ID:     key,
+			Active: true,
+		}, channel.UpdatedAt)
+	}
+	for _, device := range s.state.Devices {
+		if program := device.LastProgram; program != nil {
+			add(program.ArtifactID, model.ArtifactReference{
+				Kind:   model.ArtifactRefDevice,
+				ID:     device.DeviceID,
+				Detail: "last_program",
+				Active: true,
+			}, program.At)
+		}
+		for _, target := range device.Targets {
+			if program := target.LastProgram; program != nil {
+				add(program.ArtifactID, model.ArtifactReference{
+					Kind:   model.ArtifactRefDevice,
+					ID:     device.DeviceID,
+					Detail: fmt.Sprintf("target %d last_program", target.Index),
+					Active: true,
+				}, program.At)
+			}
+		}
+	}
+
+	for _, item := range usage {
+		sort.Slice(item.refs, func(i, j int) bool {
+			if item.refs[i].Kind != item.refs[j].Kind {
+				return item.refs[i].Kind < item.refs[j].Kind
+			}
+			return item.refs[i].ID < item.refs[j].ID
+		})
+	}
+	return usage
+}
+
+// CollectArtifacts removes artifacts without active references whose last
+// use (upload or referencing command) is older than minAge. With dryRun the
+// report lists what would go and nothing changes.
+func (s *StateStore) CollectArtifacts(minAge time.Duration, dryRun bool, now time.Time) (*model.ArtifactGCReport, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	report := &model.ArtifactGCReport{
+		DryRun:  dryRun,
+		RanAt:   now,
+		MinAge:  minAge.String(),
+		Removed: []model.ArtifactGCItem{},
+	}
+	cutoff := now.Add(-minAge)
+	var victims []string
+	for id, item := range s.artifactUsageLocked() {
+		if item.lastUsed.After(cutoff) {
+			continue
+		}
+		if item.active {
+			report.Referenced++
+			continue
+		}
+		artifact := s.state.Artifacts[id]
+		bytes := artifactStoredBytes(artifact)
+		report.Removed = append(report.Removed, model.ArtifactGCItem{
+			ArtifactID: id,
+			Name:       artifact.Name,
+			Bytes:      bytes,
+			LastUsedAt: item.lastUsed,
+		})
+		report.FreedBytes += bytes
+		victims = append(victims, id)
+	}
+	sort.Slice(report.Removed, func(i, j int) bool {
+		return report.Removed[i].LastUsedAt.Before(report.Removed[j].LastUsedAt)
+	})
+
+	if dryRun || len(victims) == 0 {
+		report.UsedBytes = s.artifactUsedBytesLocked()
+		return report, nil
+	}
+	restore, paths := s.dropArtifactsLocked(victims)
+	if err := s.persistLocked(); err != nil {
+		restore()
+		return nil, err
+	}
+	removeFiles(paths)
+	report.UsedBytes = s.artifactUsedBytesLocked()
+	return report, nil
+}
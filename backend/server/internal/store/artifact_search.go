@@ -0,0 +1,122 @@
+package store
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// ArtifactFilter selects catalogue entries. Empty fields match all.
+type ArtifactFilter struct {
+	// Text is case-insensitive substring of id, any upload name, version or description.
+	Text string
+	// Tags must all be present on artifact.
+	Tags      []string
+	TargetMCU string
+	Version   string
+	Formats   []string
+	// CreatedBy matches uploader of any upload of the artifact.
+	CreatedBy []string
+}
+
+// ArtifactCursor marks position in newest-first artifact order.
+type ArtifactCursor struct {
+	CreatedAt  time.Time
+	ArtifactID string
+}
+
+func (f ArtifactFilter) matches(artifact *model.Artifact) bool {
+	if f.TargetMCU != "" && !strings.EqualFold(artifact.TargetMCU, f.TargetMCU) {
+		return false
+	}
+	if f.Version != "" && artifact.Version != f.Version {
+		return false
+	}
+	if len(f.Formats) > 0 && !containsString(f.Formats, artifact.Format) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !containsString(artifact.Tags, tag) {
+			return false
+		}
+	}
+	if len(f.CreatedBy) > 0 && !artifactUploadedBy(artifact, f.CreatedBy) {
+		return false
+	}
+	if f.Text != "" && !artifactContainsText(artifact, strings.ToLower(f.Text)) {
+		return false
+	}
+	return true
+}
+
+func artifactUploadedBy(artifact *model.Artifact, operators []string) bool {
+	if containsString(operators, artifact.CreatedBy) {
+		return true
+	}
+	for _, upload := range artifact.Uploads {
+		if containsString(operators, upload.CreatedBy) {
+			return true
+		}
+	}
+	return false
+}
+
+func artifactContainsText(artifact *model.Artifact, text string) bool {
+	fields := []string{artifact.ArtifactID, artifact.Name, artifact.Version, artifact.Description}
+	for _, upload := range artifact.Uploads {
+		fields = append(fields, upload.Name)
+	}
+	for _, field := range fields {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+// before reports whether artifact sorts after cursor in newest-first order.
+func (c ArtifactCursor) before(artifact *model.Artifact) bool {
+	if artifact.CreatedAt.Equal(c.CreatedAt) {
+		return artifact.ArtifactID < c.ArtifactID
+	}
+	return artifact.CreatedAt.Before(c.CreatedAt)
+}
+
+// SearchArtifacts returns up to limit matching artifacts newest first,
+// starting after cursor. next is non-nil when more results remain.
+func (s *StateStore) SearchArtifacts(filter ArtifactFilter, after *ArtifactCursor, limit int) ([]*model.Artifact, *ArtifactCursor) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var matched []*model.Artifact
+	for _, artifact := range s.state.Artifacts {
+		if filter.matches(artifact) {
+			matched = append(matched, artifact)
+		}
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].ArtifactID > matched[j].ArtifactID
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool { return after.before(matched[i]) })
+	}
+
+	out := make([]*model.Artifact, 0, limit)
+	for i := start; i < len(matched) && len(out) < limit; i++ {
+		out = append(out, cloneArtifact(matched[i]))
+	}
+
+	var next *ArtifactCursor
+	if start+len(out) < len(matched) && len(out) > 0 {
+		last := out[len(out)-1]
+		next = &ArtifactCursor{CreatedAt: last.CreatedAt, ArtifactID: last.ArtifactID}
+	}
+	return out, next
+}
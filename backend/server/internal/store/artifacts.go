@@ -0,0 +1,505 @@
+package store
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"lte_swd/backend/server/internal/firmware"
+	"lte_swd/backend/server/internal/model"
+)
+
+// maxArtifactUploads bounds upload history kept per artifact; Name and
+// CreatedBy always keep the first upload.
+const maxArtifactUploads = 50
+
+// StagedArtifact is uploaded payload written to a temp file but not yet
+// registered. Callers either commit it or call Discard.
+type StagedArtifact struct {
+	path         string
+	segmentPaths []string
+	Size         int64
+	SHA256       string
+	CRC32        string
+	// Format, EntryPoint and Segments are filled by parseStagedArtifact.
+	Format     string
+	EntryPoint *uint64
+	Segments   []model.ArtifactSegment
+	// Signature is set by caller after verifying it over staged bytes.
+	Signature *model.ArtifactSignature
+}
+
+// Open returns staged payload for reading; caller closes file.
+func (a *StagedArtifact) Open() (*os.File, error) {
+	return os.Open(a.path)
+}
+
+// Discard removes staged temp files.
+func (a *StagedArtifact) Discard() {
+	if a == nil {
+		return
+	}
+	if a.path != "" {
+		_ = os.Remove(a.path)
+	}
+	for _, path := range a.segmentPaths {
+		_ = os.Remove(path)
+	}
+}
+
+func (s *StateStore) artifactDir() string {
+	return filepath.Join(s.blobDir, "artifacts")
+}
+
+func (s *StateStore) artifactPath(artifactID string) string {
+	return filepath.Join(s.artifactDir(), artifactID+".bin")
+}
+
+func (s *StateStore) artifactSegmentPath(artifactID string, index int) string {
+	return filepath.Join(s.artifactDir(), fmt.Sprintf("%s.seg%d.bin", artifactID, index))
+}
+
+// StageArtifact streams body to a temp file while hashing it. Reading stops
+// with ErrArtifactTooLarge once more than maxBytes arrive.
+func (s *StateStore) StageArtifact(body io.Reader, maxBytes int64) (*StagedArtifact, error) {
+	if err := os.MkdirAll(s.artifactDir(), 0o755); err != nil {
+		return nil, fmt.Errorf("create artifact dir: %w", err)
+	}
+	file, err := os.CreateTemp(s.artifactDir(), "upload-*.tmp")
+	if err != nil {
+		return nil, fmt.Errorf("create artifact temp file: %w", err)
+	}
+	staged := &StagedArtifact{path: file.Name()}
+
+	shaHash := sha256.New()
+	crcHash := crc32.NewIEEE()
+	written, err := io.Copy(io.MultiWriter(file, shaHash, crcHash), io.LimitReader(body, maxBytes+1))
+	closeErr := file.Close()
+	switch {
+	case err != nil:
+		staged.Discard()
+		return nil, fmt.Errorf("write artifact: %w", err)
+	case closeErr != nil:
+		staged.Discard()
+		return nil, fmt.Errorf("close artifact: %w", closeErr)
+	case written > maxBytes:
+		staged.Discard()
+		return nil, ErrArtifactTooLarge
+	case written == 0:
+		staged.Discard()
+		return nil, fmt.Errorf("invalid artifact: payload must not be empty")
+	}
+
+	staged.Size = written
+	staged.SHA256 = hex.EncodeToString(shaHash.Sum(nil))
+	staged.CRC32 = fmt.Sprintf("%08x", crcHash.Sum32())
+	return staged, nil
+}
+
+// parseStagedArtifact detects format of staged payload and, for addressed
+// formats, writes every memory segment to its own temp file.
+func (s *StateStore) parseStagedArtifact(staged *StagedArtifact, name string) error {
+	file, err := os.Open(staged.path)
+	if err != nil {
+		return fmt.Errorf("open staged artifact: %w", err)
+	}
+	defer file.Close()
+
+	head := make([]byte, 512)
+	n, err := file.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return fmt.Errorf("read staged artifact: %w", err)
+	}
+	image, err := firmware.Parse(firmware.Detect(name, head[:n]), file, staged.Size)
+	if err != nil {
+		return fmt.Errorf("invalid artifact: %w", err)
+	}
+
+	staged.Format = string(image.Format)
+	staged.EntryPoint = image.Entry
+	for index, segment := range image.Segments {
+		path, err := writeTempBlob(s.artifactDir(), "segment-*.tmp", segment.Data)
+		if err != nil {
+			return err
+		}
+		staged.segmentPaths = append(staged.segmentPaths, path)
+		staged.Segments = append(staged.Segments, model.ArtifactSegment{
+			Index:   index,
+			Address: segment.Address,
+			Size:    int64(len(segment.Data)),
+			SHA256:  fmt.Sprintf("%x", sha256.Sum256(segment.Data)),
+			CRC32:   fmt.Sprintf("%08x", crc32.ChecksumIEEE(segment.Data)),
+		})
+	}
+	return nil
+}
+
+func writeTempBlob(dir, pattern string, data []byte) (string, error) {
+	file, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("create temp file: %w", err)
+	}
+	_, err = file.Write(data)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(file.Name())
+		return "", fmt.Errorf("write temp file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// CommitArtifact registers staged payload under content-derived id. Identical
+// bytes reuse the existing artifact and the staged copy is dropped. With idem
+// key already used, the originally created artifact is returned with replayed=true.
+func (s *StateStore) CommitArtifact(staged *StagedArtifact, name, contentType, createdBy string, idem IdempotencyRequest, now time.Time) (*model.Artifact, bool, error) {
+	if err := s.parseStagedArtifact(staged, name); err != nil {
+		staged.Discard()
+		return nil, false, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	resourceID, found, err := s.lookupIdempotencyLocked(idempotencyScopeArtifact, createdBy, idem, now)
+	if err != nil {
+		staged.Discard()
+		return nil, false, err
+	}
+	if found {
+		if existing, ok := s.state.Artifacts[resourceID]; ok {
+			staged.Discard()
+			return cloneArtifact(existing), true, nil
+		}
+	}
+
+	artifact, restore, err := s.commitArtifactLocked(staged, name, contentType, createdBy, now)
+	if err != nil {
+		return nil, false, err
+	}
+	s.rememberIdempotencyLocked(idempotencyScopeArtifact, createdBy, idem, artifact.ArtifactID, now)
+	if err := s.persistLocked(); err != nil {
+		restore()
+		return nil, false, err
+	}
+	return cloneArtifact(artifact), false, nil
+}
+
+// commitArtifactLocked moves staged files into place and adds artifact to
+// state, or records another upload of known content. Returned restore undoes
+// both when the caller fails to persist.
+func (s *StateStore) commitArtifactLocked(staged *StagedArtifact, name, contentType, createdBy string, now time.Time) (*model.Artifact, func(), error) {
+	artifactID := "art_" + staged.SHA256[:24]
+	upload := model.ArtifactUpload{Name: name, CreatedBy: createdBy, CreatedAt: now}
+	if existing, ok := s.state.Artifacts[artifactID]; ok {
+		staged.Discard()
+		signature, uploads := existing.Signature, existing.Uploads
+		if existing.Signature == nil && staged.Signature != nil {
+			existing.Signature = staged.Signature
+		}
+		existing.Uploads = append(append([]model.ArtifactUpload(nil), existing.Uploads...), upload)
+		if len(existing.Uploads) > maxArtifactUploads {
+			existing.Uploads = existing.Uploads[len(existing.Uploads)-maxArtifactUploads:]
+		}
+		return existing, func() {
+			existing.Signature = signature
+			existing.Uploads = uploads
+		}, nil
+	}
+
+	size := staged.Size
+	for _, segment := range staged.Segments {
+		size += segment.Size
+	}
+	if err := s.checkArtifactQuotaLocked(size); err != nil {
+		staged.Discard()
+		return nil, nil, err
+	}
+
+	var placed []string
+	for index, path := range staged.segmentPaths {
+		target := s.artifactSegmentPath(artifactID, index)
+		if err := os.Rename(path, target); err != nil {
+			removeFiles(placed)
+			staged.Discard()
+			return nil, nil, fmt.Errorf("store artifact segment: %w", err)
+		}
+		placed = append(placed, target)
+	}
+	if err := os.Rename(staged.path, s.artifactPath(artifactID)); err != nil {
+		removeFiles(placed)
+		staged.Discard()
+		return nil, nil, fmt.Errorf("store artifact: %w", err)
+	}
+	placed = append(placed, s.artifactPath(artifactID))
+
+	artifact := &model.Artifact{
+		ArtifactID:    artifactID,
+		Name:          name,
+		ContentType:   contentType,
+		CreatedBy:     createdBy,
+		CreatedAt:     now,
+		Size:          staged.Size,
+		PayloadSHA256: staged.SHA256,
+		PayloadCRC32:  staged.CRC32,
+		Format:        staged.Format,
+		EntryPoint:    staged.EntryPoint,
+		Segments:      staged.Segments,
+		Signature:     staged.Signature,
+		Uploads:       []model.ArtifactUpload{upload},
+	}
+	s.state.Artifacts[artifactID] = artifact
+	return artifact, func() {
+		delete(s.state.Artifacts, artifactID)
+		removeFiles(placed)
+	}, nil
+}
+
+// SaveArtifact stores in-memory payload as artifact.
+func (s *StateStore) SaveArtifact(name, contentType string, payload []byte, createdBy string, now time.Time) (*model.Artifact, error) {
+	artifact, _, err := s.SaveArtifactIdempotent(name, contentType, payload, createdBy, IdempotencyRequest{}, now)
+	return artifact, err
+}
+
+// SaveArtifactIdempotent stores in-memory payload, replaying the artifact
+// created earlier when idem carries a key that was already used.
+func (s *StateStore) SaveArtifactIdempotent(name, contentType string, payload []byte, createdBy string, idem IdempotencyRequest, now time.Time) (*model.Artifact, bool, error) {
+	staged, err := s.StageArtifact(bytes.NewReader(payload), int64(len(payload)))
+	if err != nil {
+		return nil, false, err
+	}
+	return s.CommitArtifact(staged, name, contentType, createdBy, idem, now)
+}
+
+// GetArtifact returns artifact metadata.
+func (s *StateStore) GetArtifact(artifactID string) (*model.Artifact, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	artifact, ok := s.state.Artifacts[artifactID]
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+	return cloneArtifact(artifact), nil
+}
+
+// OpenArtifact returns artifact metadata and its payload file. Caller closes file.
+func (s *StateStore) OpenArtifact(artifactID string) (*model.Artifact, *os.File, error) {
+	artifact, err := s.GetArtifact(artifactID)
+	if err != nil {
+		return nil, nil, err
+	}
+	file, err := os.Open(s.artifactPath(artifactID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("open artifact: %w", err)
+	}
+	return artifact, file, nil
+}
+
+// OpenArtifactSegment returns artifact metadata, requested segment and its
+// flat binary file. Caller closes file.
+func (s *StateStore) OpenArtifactSegment(artifactID string, index int) (*model.Artifact, *model.ArtifactSegment, *os.File, error) {
+	artifact, err := s.GetArtifact(artifactID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if index < 0 || index >= len(artifact.Segments) {
+		return nil, nil, nil, ErrArtifactSegmentNotFound
+	}
+	file, err := os.Open(s.artifactSegmentPath(artifactID, index))
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("open artifact segment: %w", err)
+	}
+	return artifact, &artifact.Segments[index], file, nil
+}
+
+// UpdateArtifact applies mutate to artifact metadata and persists it. Content
+// and upload fields are kept even if mutate touches them.
+func (s *StateStore) UpdateArtifact(artifactID string, mutate func(*model.Artifact) error, updatedBy string, now time.Time) (*model.Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.state.Artifacts[artifactID]
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+
+	updated := cloneArtifact(current)
+	if err := mutate(updated); err != nil {
+		return nil, err
+	}
+	kept := cloneArtifact(current)
+	kept.Version = updated.Version
+	kept.TargetMCU = updated.TargetMCU
+	kept.Description = updated.Description
+	kept.Tags = updated.Tags
+	kept.UpdatedBy = updatedBy
+	kept.UpdatedAt = &now
+
+	s.state.Artifacts[artifactID] = kept
+	if err := s.persistLocked(); err != nil {
+		s.state.Artifacts[artifactID] = current
+		return nil, err
+	}
+	return cloneArtifact(kept), nil
+}
+
+// SetArtifactSignature stores signature the caller already verified against
+// artifact bytes, replacing any previous one.
+func (s *StateStore) SetArtifactSignature(artifactID string, signature model.ArtifactSignature) (*model.Artifact, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.state.Artifacts[artifactID]
+	if !ok {
+		return nil, ErrArtifactNotFound
+	}
+	previous := current.Signature
+	current.Signature = &signature
+	if err := s.persistLocked(); err != nil {
+		current.Signature = previous
+		return nil, err
+	}
+	return cloneArtifact(current), nil
+}
+
+// DeleteArtifact removes artifact, its files and deltas using it as base.
+// Artifacts a release channel points at, or named by a command that has not
+// reached a terminal status, are refused with ErrArtifactInUse.
+func (s *StateStore) DeleteArtifact(artifactID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Artifacts[artifactID]; !ok {
+		return ErrArtifactNotFound
+	}
+	if channels := s.channelsPointingAtLocked(artifactID); len(channels) > 0 {
+		return fmt.Errorf("%w: release channel %s", ErrArtifactInUse, strings.Join(channels, ", "))
+	}
+	for _, commands := range s.state.CommandsByID {
+		for _, command := range commands {
+			if !command.Status.IsTerminal() && payloadArtifactID(command.Payload) == artifactID {
+				return fmt.Errorf("%w: %s (%s)", ErrArtifactInUse, command.CommandID, command.Status)
+			}
+		}
+	}
+
+	restore, paths := s.dropArtifactsLocked([]string{artifactID})
+	if err := s.persistLocked(); err != nil {
+		restore()
+		return err
+	}
+	removeFiles(paths)
+	return nil
+}
+
+// dropArtifactsLocked removes artifacts from state together with deltas that
+// use them as base, which can no longer be offered. It returns undo for a
+// failed persist and files to remove once persisted.
+func (s *StateStore) dropArtifactsLocked(artifactIDs []string) (func(), []string) {
+	removed := make(map[string]*model.Artifact, len(artifactIDs))
+	var paths []string
+	for _, artifactID := range artifactIDs {
+		if artifact, ok := s.state.Artifacts[artifactID]; ok {
+			removed[artifactID] = artifact
+			paths = append(paths, s.artifactFiles(artifact)...)
+			delete(s.state.Artifacts, artifactID)
+		}
+	}
+
+	dependents := make(map[string][]model.ArtifactDelta)
+	for id, artifact := range s.state.Artifacts {
+		kept := make([]model.ArtifactDelta, 0, len(artifact.Deltas))
+		for _, delta := range artifact.Deltas {
+			if _, gone := removed[delta.BaseArtifactID]; gone {
+				paths = append(paths, s.artifactDeltaPath(id, delta.BaseArtifactID))
+				continue
+			}
+			kept = append(kept, delta)
+		}
+		if len(kept) != len(artifact.Deltas) {
+			dependents[id] = artifact.Deltas
+			artifact.Deltas = kept
+		}
+	}
+
+	restore := func() {
+		for id, artifact := range removed {
+			s.state.Artifacts[id] = artifact
+		}
+		for id, deltas := range dependents {
+			s.state.Artifacts[id].Deltas = deltas
+		}
+	}
+	return restore, paths
+}
+
+// artifactFiles lists every blob stored for artifact, cached variants included.
+func (s *StateStore) artifactFiles(artifact *model.Artifact) []string {
+	paths := []string{s.artifactPath(artifact.ArtifactID)}
+	for encoding := range artifactEncodingSuffix {
+		paths = append(paths, s.artifactEncodedPath(artifact.ArtifactID, encoding))
+	}
+	for index := range artifact.Segments {
+		paths = append(paths, s.artifactSegmentPath(artifact.ArtifactID, index))
+	}
+	for _, delta := range artifact.Deltas {
+		paths = append(paths, s.artifactDeltaPath(artifact.ArtifactID, delta.BaseArtifactID))
+	}
+	return paths
+}
+
+func removeFiles(paths []string) {
+	for _, path := range paths {
+		_ = os.Remove(path)
+	}
+}
+
+func payloadArtifactID(payload []byte) string {
+	var ref struct {
+		ArtifactID string `json:"artifact_id"`
+	}
+	if len(payload) == 0 || json.Unmarshal(payload, &ref) != nil {
+		return ""
+	}
+	return strings.TrimSpace(ref.ArtifactID)
+}
+
+// migrateArtifactPayloadsLocked moves payloads kept inline by older state
+// files to blobs/artifacts/ and rewrites the state without them.
+func (s *StateStore) migrateArtifactPayloadsLocked() error {
+	changed := false
+	for artifactID, artifact := range s.state.Artifacts {
+		if len(artifact.Payload) == 0 {
+			continue
+		}
+		if err := os.MkdirAll(s.artifactDir(), 0o755); err != nil {
+			return fmt.Errorf("create artifact dir: %w", err)
+		}
+		tempFile := s.artifactPath(artifactID) + ".tmp"
+		if err := os.WriteFile(tempFile, artifact.Payload, 0o644); err != nil {
+			return fmt.Errorf("migrate artifact %s: %w", artifactID, err)
+		}
+		if err := os.Rename(tempFile, s.artifactPath(artifactID)); err != nil {
+			return fmt.Errorf("migrate artifact %s: %w", artifactID, err)
+		}
+
+		artifact.Size = int64(len(artifact.Payload))
+		artifact.PayloadCRC32 = fmt.Sprintf("%08x", crc32.ChecksumIEEE(artifact.Payload))
+		artifact.Payload = nil
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return s.writeStateLocked()
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// maxChannelHistory bounds promotions kept per channel.
+const maxChannelHistory = 100
+
+func channelKey(product, name string) string {
+	return product + "/" + name
+}
+
+// PromoteChannel points channel at artifact, creating channel on first use.
+// With expected set, promotion only happens while channel still points there
+// ("" for a channel that must not exist yet); otherwise ErrChannelConflict.
+func (s *StateStore) PromoteChannel(product, name, artifactID string, expected *string, promotedBy, reason string, now time.Time) (*model.ReleaseChannel, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Artifacts[artifactID]; !ok {
+		return nil, ErrArtifactNotFound
+	}
+	key := channelKey(product, name)
+	current, exists := s.state.Channels[key]
+	previous := ""
+	if exists {
+		previous = current.ArtifactID
+	}
+	if expected != nil && *expected != previous {
+		return nil, fmt.Errorf("%w: %s is at %q", ErrChannelConflict, key, previous)
+	}
+	if exists && previous == artifactID {
+		return cloneChannel(current), nil
+	}
+
+	promoted := &model.ReleaseChannel{Product: product, Name: name}
+	if exists {
+		promoted = cloneChannel(current)
+	}
+	promoted.ArtifactID = artifactID
+	promoted.UpdatedBy = promotedBy
+	promoted.UpdatedAt = now
+	promoted.History = append(promoted.History, model.ChannelPromotion{
+		ArtifactID:         artifactID,
+		PreviousArtifactID: previous,
+		PromotedBy:         promotedBy,
+		PromotedAt:         now,
+		Reason:             reason,
+	})
+	if len(promoted.History) > maxChannelHistory {
+		promoted.History = promoted.History[len(promoted.History)-maxChannelHistory:]
+	}
+
+	s.state.Channels[key] = promoted
+	if err := s.persistLocked(); err != nil {
+		if exists {
+			s.state.Channels[key] = current
+		} else {
+			delete(s.state.Channels, key)
+		}
+		return nil, err
+	}
+	return cloneChannel(promoted), nil
+}
+
+// GetChannel returns channel of product.
+func (s *StateStore) GetChannel(product, name string) (*model.ReleaseChannel, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channel, ok := s.state.Channels[channelKey(product, name)]
+	if !ok {
+		return nil, ErrChannelNotFound
+	}
+	return cloneChannel(channel), nil
+}
+
+// FindChannels returns channels called name across products, ordered by product.
+func (s *StateStore) FindChannels(name string) []*model.ReleaseChannel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*model.ReleaseChannel
+	for _, channel := range s.state.Channels {
+		if channel.Name == name {
+			out = append(out, cloneChannel(channel))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Product < out[j].Product })
+	return out
+}
+
+// ListChannels returns channels ordered by product and name; empty product lists all.
+func (s *StateStore) ListChannels(product string) []*model.ReleaseChannel {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*model.ReleaseChannel, 0, len(s.state.Channels))
+	for _, channel := range s.state.Channels {
+		if product == "" || channel.Product == product {
+			out = append(out, cloneChannel(channel))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return channelKey(out[i].Product, out[i].Name) < channelKey(out[j].Product, out[j].Name)
+	})
+	return out
+}
+
+// DeleteChannel removes channel; commands already resolved from it keep their artifact.
+func (s *StateStore) DeleteChannel(product, name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := channelKey(product, name)
+	current, ok := s.state.Channels[key]
+	if !ok {
+		return ErrChannelNotFound
+	}
+	delete(s.state.Channels, key)
+	if err := s.persistLocked(); err != nil {
+		s.state.Channels[key] = current
+		return err
+	}
+	return nil
+}
+
+// channelsPointingAtLocked lists keys of channels currently at artifact.
+func (s *StateStore) channelsPointingAtLocked(artifactID string) []string {
+	var keys []string
+	for key, channel := range s.state.Channels {
+		if channel.ArtifactID == artifactID {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func cloneChannel(src *model.ReleaseChannel) *model.ReleaseChannel {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	out.History = append([]model.ChannelPromotion(nil), src.History...)
+	return &out
+}
@@ -0,0 +1,170 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// CommandFilter selects commands across the fleet. Empty fields match all,
+// except DeviceIDs: nil matches every device, an empty slice none.
+type CommandFilter struct {
+	DeviceIDs  []string
+	Statuses   []model.CommandStatus
+	Types      []string
+	CreatedBy  []string
+	ScheduleID string
+	Since      time.Time
+	Until      time.Time
+}
+
+// CommandCursor marks position in newest-first command order.
+type CommandCursor struct {
+	CreatedAt time.Time
+	CommandID string
+}
+
+func (f CommandFilter) matches(command *model.Command) bool {
+	if f.DeviceIDs != nil && !containsString(f.DeviceIDs, command.DeviceID) {
+		return false
+	}
+	if len(f.Types) > 0 && !containsString(f.Types, command.Type) {
+		return false
+	}
+	if len(f.CreatedBy) > 0 && !containsString(f.CreatedBy, command.CreatedBy) {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		found := false
+		for _, status := range f.Statuses {
+			if command.Status == status {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.ScheduleID != "" && command.ScheduleID != f.ScheduleID {
+		return false
+	}
+	if !f.Since.IsZero() && command.CreatedAt.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && !command.CreatedAt.Before(f.Until) {
+		return false
+	}
+	return true
+}
+
+// before reports whether command sorts after cursor in newest-first order.
+func (c CommandCursor) before(command *model.Command) bool {
+	if command.CreatedAt.Equal(c.CreatedAt) {
+		return command.CommandID < c.CommandID
+	}
+	return command.CreatedAt.Before(c.CreatedAt)
+}
+
+// SearchCommands returns up to limit matching commands newest first, starting
+// after cursor. next is non-nil when more results remain.
+func (s *StateStore) SearchCommands(filter CommandFilter, after *CommandCursor, limit int) ([]*model.Command, *CommandCursor) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	matched := s.matchCommandsLocked(filter)
+	sort.Slice(matched, func(i, j int) bool {
+		if matched[i].CreatedAt.Equal(matched[j].CreatedAt) {
+			return matched[i].CommandID > matched[j].CommandID
+		}
+		return matched[i].CreatedAt.After(matched[j].CreatedAt)
+	})
+
+	start := 0
+	if after != nil {
+		start = sort.Search(len(matched), func(i int) bool { return after.before(matched[i]) })
+	}
+
+	out := make([]*model.Command, 0, limit)
+	for i := start; i < len(matched) && len(out) < limit; i++ {
+		out = append(out, cloneCommand(matched[i]))
+	}
+
+	var next *CommandCursor
+	if start+len(out) < len(matched) && len(out) > 0 {
+		last := out[len(out)-1]
+		next = &CommandCursor{CreatedAt: last.CreatedAt, CommandID: last.CommandID}
+	}
+	return out, next
+}
+
+// SummarizeCommands counts matching commands per status, type and device.
+func (s *StateStore) SummarizeCommands(filter CommandFilter) model.CommandSummary {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	summary := model.CommandSummary{
+		Totals:   model.CommandStats{ByStatus: make(map[model.CommandStatus]int)},
+		ByType:   make(map[string]*model.CommandStats),
+		ByDevice: make(map[string]*model.CommandStats),
+	}
+	for _, command := range s.matchCommandsLocked(filter) {
+		countCommand(&summary.Totals, command.Status)
+		countCommand(statsFor(summary.ByType, command.Type), command.Status)
+		countCommand(statsFor(summary.ByDevice, command.DeviceID), command.Status)
+	}
+
+	finishStats(&summary.Totals)
+	for _, stats := range summary.ByType {
+		finishStats(stats)
+	}
+	for _, stats := range summary.ByDevice {
+		finishStats(stats)
+	}
+	return summary
+}
+
+func (s *StateStore) matchCommandsLocked(filter CommandFilter) []*model.Command {
+	var out []*model.Command
+	for _, commands := range s.state.CommandsByID {
+		for _, command := range commands {
+			if filter.matches(command) {
+				out = append(out, command)
+			}
+		}
+	}
+	return out
+}
+
+func statsFor(groups map[string]*model.CommandStats, key string) *model.CommandStats {
+	stats, ok := groups[key]
+	if !ok {
+		stats = &model.CommandStats{ByStatus: make(map[model.CommandStatus]int)}
+		groups[key] = stats
+	}
+	return stats
+}
+
+func countCommand(stats *model.CommandStats, status model.CommandStatus) {
+	stats.Total++
+	stats.ByStatus[status]++
+}
+
+func finishStats(stats *model.CommandStats) {
+	finished := stats.ByStatus[model.CommandSuccess] + stats.ByStatus[model.CommandFailed]
+	if finished == 0 {
+		return
+	}
+	rate := float64(stats.ByStatus[model.CommandSuccess]) / float64(finished)
+	stats.SuccessRate = &rate
+}
+
+func containsString(items []string, value string) bool {
+	for _, item := range items {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
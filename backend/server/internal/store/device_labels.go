@@ -0,0 +1,25 @@
+package store
+
+import (
+	"lte_swd/backend/server/internal/model"
+)
+
+// SetDeviceLabels replaces operator-assigned labels of device. Caller
+// normalizes labels.
+func (s *StateStore) SetDeviceLabels(deviceID string, labels []string) (*model.Device, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device, ok := s.state.Devices[deviceID]
+	if !ok {
+		return nil, ErrDeviceNotFound
+	}
+
+	previous := device.Labels
+	device.Labels = append([]string(nil), labels...)
+	if err := s.persistLocked(); err != nil {
+		device.Labels = previous
+		return nil, err
+	}
+	return model.CloneDevice(device), nil
+}
@@ -15,4 +15,48 @@ var (
 	ErrCommandNotFound = errors.New("command not found")
 	// ErrArtifactNotFound indicates unknown artifact id.
 	ErrArtifactNotFound = errors.New("artifact not found")
+	// ErrArtifactSegmentNotFound indicates artifact has no segment with requested index.
+	ErrArtifactSegmentNotFound = errors.New("artifact segment not found")
+	// ErrArtifactDeltaNotFound indicates no usable delta to artifact exists for the device.
+	ErrArtifactDeltaNotFound = errors.New("artifact delta not available")
+	// ErrArtifactInUse indicates artifact is referenced by a command that has not finished.
+	ErrArtifactInUse = errors.New("artifact is referenced by active command")
+	// ErrArtifactSignature indicates signature does not verify against a trusted release key.
+	ErrArtifactSignature = errors.New("artifact signature verification failed")
+	// ErrArtifactUnsigned indicates policy requires trusted signature the artifact lacks.
+	ErrArtifactUnsigned = errors.New("artifact is not signed by a trusted release key")
+	// ErrArtifactQuotaExceeded indicates new artifact would exceed storage quota.
+	ErrArtifactQuotaExceeded = errors.New("artifact storage quota exceeded")
+	// ErrArtifactTooLarge indicates upload exceeds configured artifact size limit.
+	ErrArtifactTooLarge = errors.New("artifact exceeds size limit")
+	// ErrInvalidCommandTransition indicates update not allowed in current command status.
+	ErrInvalidCommandTransition = errors.New("invalid command status transition")
+	// ErrCommandAlreadyCompleted indicates a conflicting result for finished command.
+	ErrCommandAlreadyCompleted = errors.New("command already completed with different result")
+	// ErrSelfApproval indicates operator tried to approve own command.
+	ErrSelfApproval = errors.New("command must be approved by a different operator")
+	// ErrNamedOperatorRequired indicates shared-password session tried to create or approve gated command.
+	ErrNamedOperatorRequired = errors.New("approval-gated commands need a named operator account")
+	// ErrResultBlobNotFound indicates command has no uploaded result blob.
+	ErrResultBlobNotFound = errors.New("result blob not found")
+	// ErrResultBlobOffset indicates chunk does not continue already received bytes.
+	ErrResultBlobOffset = errors.New("result blob offset mismatch")
+	// ErrResultBlobIncomplete indicates blob upload has not reached declared size.
+	ErrResultBlobIncomplete = errors.New("result blob upload is incomplete")
+	// ErrResultBlobChecksum indicates uploaded bytes do not match declared sha256.
+	ErrResultBlobChecksum = errors.New("result blob sha256 mismatch")
+	// ErrIdempotencyKeyReused indicates that a key was replayed with a different request body.
+	ErrIdempotencyKeyReused = errors.New("idempotency key already used for a different request")
+	// ErrScheduleNotFound indicates unknown schedule id.
+	ErrScheduleNotFound = errors.New("schedule not found")
+	// ErrScheduleConflict indicates schedule changed while an edit was prepared.
+	ErrScheduleConflict = errors.New("schedule was modified concurrently")
+	// ErrTemplateNotFound indicates unknown command template name.
+	ErrTemplateNotFound = errors.New("command template not found")
+	// ErrTemplateExists indicates template name is already taken.
+	ErrTemplateExists = errors.New("command template already exists")
+	// ErrChannelNotFound indicates unknown release channel.
+	ErrChannelNotFound = errors.New("release channel not found")
+	// ErrChannelConflict indicates channel moved since caller last read it.
+	ErrChannelConflict = errors.New("release channel points at different artifact")
 )
@@ -0,0 +1,64 @@
+package store
+
+import (
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+const (
+	idempotencyScopeCommand  = "command"
+	idempotencyScopeArtifact = "artifact"
+)
+
+// IdempotencyRequest binds a create call to a client supplied Idempotency-Key.
+// Zero value disables idempotency handling.
+type IdempotencyRequest struct {
+	Key         string
+	Fingerprint string
+	Retention   time.Duration
+}
+
+func idempotencyRecordID(scope, operator, key string) string {
+	return scope + ":" + operator + ":" + key
+}
+
+// lookupIdempotencyLocked returns resource id created earlier for the same key.
+func (s *StateStore) lookupIdempotencyLocked(scope, operator string, idem IdempotencyRequest, now time.Time) (string, bool, error) {
+	s.pruneIdempotencyLocked(now)
+	if idem.Key == "" {
+		return "", false, nil
+	}
+
+	record, ok := s.state.IdempotencyKeys[idempotencyRecordID(scope, operator, idem.Key)]
+	if !ok {
+		return "", false, nil
+	}
+	if record.Fingerprint != idem.Fingerprint {
+		return "", false, ErrIdempotencyKeyReused
+	}
+	return record.ResourceID, true, nil
+}
+
+func (s *StateStore) rememberIdempotencyLocked(scope, operator string, idem IdempotencyRequest, resourceID string, now time.Time) {
+	if idem.Key == "" || idem.Retention <= 0 {
+		return
+	}
+	s.state.IdempotencyKeys[idempotencyRecordID(scope, operator, idem.Key)] = &model.IdempotencyRecord{
+		Scope:       scope,
+		Key:         idem.Key,
+		Operator:    operator,
+		Fingerprint: idem.Fingerprint,
+		ResourceID:  resourceID,
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(idem.Retention),
+	}
+}
+
+func (s *StateStore) pruneIdempotencyLocked(now time.Time) {
+	for id, record := range s.state.IdempotencyKeys {
+		if now.After(record.ExpiresAt) {
+			delete(s.state.IdempotencyKeys, id)
+		}
+	}
+}
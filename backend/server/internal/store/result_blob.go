@@ -0,0 +1,274 @@
+package store
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// ResultBlobChunk describes one append to a command result blob.
+type ResultBlobChunk struct {
+	Offset      int64
+	TotalSize   int64
+	ContentType string
+	// SHA256 is optional expected digest of the whole blob, checked on completion.
+	SHA256 string
+	Body   io.Reader
+}
+
+// blobLock is a per-command mutex dropped when its last user leaves.
+type blobLock struct {
+	mu    sync.Mutex
+	users int
+}
+
+// lockResultBlob serializes appends to one command's blob, so a slow upload
+// never blocks blobs of other commands. Call returned func to unlock.
+func (s *StateStore) lockResultBlob(commandID string) func() {
+	s.blobLocksMu.Lock()
+	lock, ok := s.blobLocks[commandID]
+	if !ok {
+		lock = &blobLock{}
+		s.blobLocks[commandID] = lock
+	}
+	lock.users++
+	s.blobLocksMu.Unlock()
+
+	lock.mu.Lock()
+	return func() {
+		lock.mu.Unlock()
+		s.blobLocksMu.Lock()
+		lock.users--
+		if lock.users == 0 {
+			delete(s.blobLocks, commandID)
+		}
+		s.blobLocksMu.Unlock()
+	}
+}
+
+func (s *StateStore) resultBlobPath(commandID string) string {
+	return filepath.Join(s.blobDir, "results", commandID+".bin")
+}
+
+// ResultBlobStatus returns upload state so device can resume after a drop.
+func (s *StateStore) ResultBlobStatus(deviceID, deviceToken, commandID string) (*model.ResultBlob, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.requireDeviceLocked(deviceID, deviceToken); err != nil {
+		return nil, err
+	}
+	item := s.findDeviceCommandLocked(deviceID, commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+	if item.ResultBlob == nil {
+		return &model.ResultBlob{}, nil
+	}
+	blob := *item.ResultBlob
+	return &blob, nil
+}
+
+// AppendResultBlob writes next chunk of command result blob. Chunk must start
+// exactly at already received size; file writes happen outside state lock.
+func (s *StateStore) AppendResultBlob(deviceID, deviceToken, commandID string, chunk ResultBlobChunk, now time.Time) (*model.ResultBlob, error) {
+	unlock := s.lockResultBlob(commandID)
+	defer unlock()
+
+	received, err := s.prepareResultBlob(deviceID, deviceToken, commandID, chunk)
+	if err != nil {
+		return nil, err
+	}
+
+	path := s.resultBlobPath(commandID)
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("create blob dir: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("open result blob: %w", err)
+	}
+	defer file.Close()
+
+	// Bytes past last persisted size belong to an interrupted chunk.
+	if err := file.Truncate(received); err != nil {
+		return nil, fmt.Errorf("truncate result blob: %w", err)
+	}
+	if _, err := file.Seek(received, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seek result blob: %w", err)
+	}
+
+	remaining := chunk.TotalSize - received
+	written, err := io.Copy(file, io.LimitReader(chunk.Body, remaining+1))
+	if err != nil {
+		return nil, fmt.Errorf("write result blob: %w", err)
+	}
+	if written > remaining {
+		_ = file.Truncate(received)
+		return nil, fmt.Errorf("%w: chunk exceeds declared total size", ErrResultBlobOffset)
+	}
+	size := received + written
+
+	var digestHex string
+	if size == chunk.TotalSize {
+		digestHex, err = fileSHA256(path)
+		if err != nil {
+			return nil, err
+		}
+		if chunk.SHA256 != "" && chunk.SHA256 != digestHex {
+			_ = file.Truncate(0)
+			s.mu.Lock()
+			defer s.mu.Unlock()
+			if item := s.findDeviceCommandLocked(deviceID, commandID); item != nil && item.ResultBlob != nil {
+				item.ResultBlob.Size = 0
+				item.ResultBlob.UpdatedAt = now
+				_ = s.persistLocked()
+			}
+			return nil, ErrResultBlobChecksum
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.findDeviceCommandLocked(deviceID, commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+	item.ResultBlob = &model.ResultBlob{
+		Size:        size,
+		TotalSize:   chunk.TotalSize,
+		ContentType: chunk.ContentType,
+		SHA256:      digestHex,
+		Complete:    size == chunk.TotalSize,
+		UpdatedAt:   now,
+	}
+	if device, ok := s.state.Devices[deviceID]; ok {
+		s.markOnlineLocked(device, now)
+	}
+	s.emitCommandLocked(item, now)
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
+	blob := *item.ResultBlob
+	return &blob, nil
+}
+
+func (s *StateStore) prepareResultBlob(deviceID, deviceToken, commandID string, chunk ResultBlobChunk) (int64, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.requireDeviceLocked(deviceID, deviceToken); err != nil {
+		return 0, err
+	}
+	item := s.findDeviceCommandLocked(deviceID, commandID)
+	if item == nil {
+		return 0, ErrCommandNotFound
+	}
+	switch item.Status {
+	case model.CommandDispatched, model.CommandRunning, model.CommandSuccess, model.CommandFailed:
+	default:
+		return 0, fmt.Errorf("%w: result blob for %s command", ErrInvalidCommandTransition, item.Status)
+	}
+
+	var received int64
+	if item.ResultBlob != nil {
+		if item.ResultBlob.TotalSize != chunk.TotalSize {
+			return 0, fmt.Errorf("%w: total size changed from %d", ErrResultBlobOffset, item.ResultBlob.TotalSize)
+		}
+		received = item.ResultBlob.Size
+	}
+	if chunk.Offset != received {
+		return 0, fmt.Errorf("%w: expected offset %d", ErrResultBlobOffset, received)
+	}
+	return received, nil
+}
+
+// OpenResultBlob returns command and opened complete result blob; caller closes file.
+func (s *StateStore) OpenResultBlob(commandID string) (*model.Command, *os.File, error) {
+	s.mu.RLock()
+	item := s.findCommandLocked(commandID)
+	if item == nil {
+		s.mu.RUnlock()
+		return nil, nil, ErrCommandNotFound
+	}
+	command := cloneCommand(item)
+	s.mu.RUnlock()
+
+	if command.ResultBlob == nil {
+		return nil, nil, ErrResultBlobNotFound
+	}
+	if !command.ResultBlob.Complete {
+		return nil, nil, ErrResultBlobIncomplete
+	}
+
+	file, err := os.Open(s.resultBlobPath(commandID))
+	if err != nil {
+		return nil, nil, fmt.Errorf("open result blob: %w", err)
+	}
+	return command, file, nil
+}
+
+// PromoteResultBlob stores complete result blob as a new artifact.
+func (s *StateStore) PromoteResultBlob(commandID, name, contentType, createdBy string, now time.Time) (*model.Artifact, error) {
+	command, file, err := s.OpenResultBlob(commandID)
+	if err != nil {
+		return nil, err
+	}
+	staged, err := s.StageArtifact(file, command.ResultBlob.Size)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	if contentType == "" {
+		contentType = command.ResultBlob.ContentType
+	}
+	if err := s.parseStagedArtifact(staged, name); err != nil {
+		staged.Discard()
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	artifact, restore, err := s.commitArtifactLocked(staged, name, contentType, createdBy, now)
+	if err != nil {
+		return nil, err
+	}
+	item := s.findCommandLocked(commandID)
+	var previousArtifactID string
+	if item != nil && item.ResultBlob != nil {
+		previousArtifactID = item.ResultBlob.ArtifactID
+		item.ResultBlob.ArtifactID = artifact.ArtifactID
+		s.emitCommandLocked(item, now)
+	}
+	if err := s.persistLocked(); err != nil {
+		restore()
+		if item != nil && item.ResultBlob != nil {
+			item.ResultBlob.ArtifactID = previousArtifactID
+		}
+		return nil, err
+	}
+	return cloneArtifact(artifact), nil
+}
+
+func fileSHA256(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("open blob: %w", err)
+	}
+	defer file.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return "", fmt.Errorf("hash blob: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
@@ -0,0 +1,180 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+	"lte_swd/backend/server/internal/util"
+)
+
+// maxScheduleHistory bounds how many past runs each schedule keeps.
+const maxScheduleHistory = 50
+
+// CreateSchedule stores new recurring schedule. Caller computes NextRunAt.
+func (s *StateStore) CreateSchedule(draft model.Schedule, now time.Time) (*model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule := cloneSchedule(&draft)
+	schedule.ScheduleID = util.RandomToken("sch", 12)
+	schedule.CreatedAt = now
+	schedule.UpdatedAt = now
+	schedule.History = nil
+
+	s.state.Schedules[schedule.ScheduleID] = schedule
+	if err := s.persistLocked(); err != nil {
+		delete(s.state.Schedules, schedule.ScheduleID)
+		return nil, err
+	}
+	return cloneSchedule(schedule), nil
+}
+
+// UpdateSchedule replaces stored schedule with draft when it still carries
+// expectedUpdatedAt, so edits prepared outside the lock never overwrite a
+// newer one. History and identity fields are kept from stored schedule.
+func (s *StateStore) UpdateSchedule(draft model.Schedule, expectedUpdatedAt time.Time, now time.Time) (*model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.state.Schedules[draft.ScheduleID]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	if !current.UpdatedAt.Equal(expectedUpdatedAt) {
+		return nil, ErrScheduleConflict
+	}
+
+	updated := cloneSchedule(&draft)
+	updated.CreatedBy = current.CreatedBy
+	updated.CreatedAt = current.CreatedAt
+	updated.LastRunAt = current.LastRunAt
+	updated.History = current.History
+	updated.UpdatedAt = now
+
+	s.state.Schedules[updated.ScheduleID] = updated
+	if err := s.persistLocked(); err != nil {
+		s.state.Schedules[updated.ScheduleID] = current
+		return nil, err
+	}
+	return cloneSchedule(updated), nil
+}
+
+// DeleteSchedule removes schedule. Commands it already spawned stay untouched.
+func (s *StateStore) DeleteSchedule(scheduleID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.state.Schedules[scheduleID]
+	if !ok {
+		return ErrScheduleNotFound
+	}
+	delete(s.state.Schedules, scheduleID)
+	if err := s.persistLocked(); err != nil {
+		s.state.Schedules[scheduleID] = current
+		return err
+	}
+	return nil
+}
+
+// GetSchedule returns schedule by id.
+func (s *StateStore) GetSchedule(scheduleID string) (*model.Schedule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	schedule, ok := s.state.Schedules[scheduleID]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+	return cloneSchedule(schedule), nil
+}
+
+// ListSchedules returns all schedules ordered by creation time.
+func (s *StateStore) ListSchedules() []*model.Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*model.Schedule, 0, len(s.state.Schedules))
+	for _, schedule := range s.state.Schedules {
+		out = append(out, cloneSchedule(schedule))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ScheduleID < out[j].ScheduleID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// DueSchedules returns enabled schedules whose next run is not after now.
+func (s *StateStore) DueSchedules(now time.Time) []*model.Schedule {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var out []*model.Schedule
+	for _, schedule := range s.state.Schedules {
+		if schedule.Enabled && schedule.NextRunAt != nil && !schedule.NextRunAt.After(now) {
+			out = append(out, cloneSchedule(schedule))
+		}
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].NextRunAt.Before(*out[j].NextRunAt)
+	})
+	return out
+}
+
+// RecordScheduleRun appends run to schedule history and moves NextRunAt forward.
+func (s *StateStore) RecordScheduleRun(scheduleID string, run model.ScheduleRun, next *time.Time) (*model.Schedule, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	schedule, ok := s.state.Schedules[scheduleID]
+	if !ok {
+		return nil, ErrScheduleNotFound
+	}
+
+	at := run.At
+	schedule.LastRunAt = &at
+	schedule.NextRunAt = next
+	schedule.History = append(schedule.History, run)
+	if len(schedule.History) > maxScheduleHistory {
+		schedule.History = append([]model.ScheduleRun(nil), schedule.History[len(schedule.History)-maxScheduleHistory:]...)
+	}
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
+	return cloneSchedule(schedule), nil
+}
+
+func cloneSchedule(src *model.Schedule) *model.Schedule {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	out.DeviceIDs = append([]string(nil), src.DeviceIDs...)
+	if src.Payload != nil {
+		out.Payload = append([]byte(nil), src.Payload...)
+	}
+	if src.Target != nil {
+		target := *src.Target
+		out.Target = &target
+	}
+	if src.NextRunAt != nil {
+		next := *src.NextRunAt
+		out.NextRunAt = &next
+	}
+	if src.LastRunAt != nil {
+		last := *src.LastRunAt
+		out.LastRunAt = &last
+	}
+	if src.History != nil {
+		out.History = make([]model.ScheduleRun, len(src.History))
+		for i, run := range src.History {
+			run.CommandIDs = append([]string(nil), run.CommandIDs...)
+			run.Errors = append([]string(nil), run.Errors...)
+			out.History[i] = run
+		}
+	}
+	return &out
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"bytes"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
@@ -11,30 +12,60 @@ import (
 	"sync"
 	"time"
 
+	"lte_swd/backend/server/internal/events"
 	"lte_swd/backend/server/internal/model"
 	"lte_swd/backend/server/internal/util"
 )
 
 const maxTelemetryHistory = 500
 
+// maxResultAttempts bounds conflicting results kept per command, so a probe
+// retrying a stale result cannot grow state without limit.
+const maxResultAttempts = 10
+
 // StateStore keeps R1 runtime state with JSON file persistence.
 type StateStore struct {
 	mu         sync.RWMutex
 	fleetLimit int
 	dataFile   string
+	blobDir    string
 	state      model.PersistedState
+	// commandSignals wakes long-poll pullers; closed when a command is queued.
+	commandSignals map[string]chan struct{}
+	// events receives state changes after they are persisted.
+	events        *events.Hub
+	pendingEvents []pendingEvent
+	// artifactQuota bounds artifact storage in bytes; 0 is unlimited.
+	artifactQuota int64
+	// blobLocks serializes result blob appends per command id.
+	blobLocksMu sync.Mutex
+	blobLocks   map[string]*blobLock
+}
+
+type pendingEvent struct {
+	eventType string
+	deviceID  string
+	data      interface{}
+	at        time.Time
 }
 
 // NewStateStore creates state store and loads prior snapshot when available.
 func NewStateStore(dataFile string, fleetLimit int) (*StateStore, error) {
 	s := &StateStore{
-		fleetLimit: fleetLimit,
-		dataFile:   dataFile,
+		fleetLimit:     fleetLimit,
+		dataFile:       dataFile,
+		blobDir:        filepath.Join(filepath.Dir(dataFile), "blobs"),
+		commandSignals: make(map[string]chan struct{}),
+		blobLocks:      make(map[string]*blobLock),
 		state: model.PersistedState{
-			Devices:       make(map[string]*model.Device),
-			TelemetryByID: make(map[string][]model.TelemetryRecord),
-			CommandsByID:  make(map[string][]*model.Command),
-			Artifacts:     make(map[string]*model.Artifact),
+			Devices:         make(map[string]*model.Device),
+			TelemetryByID:   make(map[string][]model.TelemetryRecord),
+			CommandsByID:    make(map[string][]*model.Command),
+			Artifacts:       make(map[string]*model.Artifact),
+			IdempotencyKeys: make(map[string]*model.IdempotencyRecord),
+			Schedules:       make(map[string]*model.Schedule),
+			Templates:       make(map[string]*model.CommandTemplate),
+			Channels:        make(map[string]*model.ReleaseChannel),
 		},
 	}
 
@@ -73,12 +104,33 @@ func (s *StateStore) load() error {
 	if loaded.Artifacts == nil {
 		loaded.Artifacts = make(map[string]*model.Artifact)
 	}
+	if loaded.IdempotencyKeys == nil {
+		loaded.IdempotencyKeys = make(map[string]*model.IdempotencyRecord)
+	}
+	if loaded.Schedules == nil {
+		loaded.Schedules = make(map[string]*model.Schedule)
+	}
+	if loaded.Templates == nil {
+		loaded.Templates = make(map[string]*model.CommandTemplate)
+	}
+	if loaded.Channels == nil {
+		loaded.Channels = make(map[string]*model.ReleaseChannel)
+	}
 
 	s.state = loaded
-	return nil
+	return s.migrateArtifactPayloadsLocked()
 }
 
 func (s *StateStore) persistLocked() error {
+	if err := s.writeStateLocked(); err != nil {
+		s.pendingEvents = nil
+		return err
+	}
+	s.flushEventsLocked()
+	return nil
+}
+
+func (s *StateStore) writeStateLocked() error {
 	if err := os.MkdirAll(filepath.Dir(s.dataFile), 0o755); err != nil {
 		return fmt.Errorf("create state dir: %w", err)
 	}
@@ -99,11 +151,60 @@ func (s *StateStore) persistLocked() error {
 	return nil
 }
 
+// SetEventHub attaches live event hub; nil disables publishing.
+func (s *StateStore) SetEventHub(hub *events.Hub) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.events = hub
+}
+
+// emitLocked queues event until the mutation is persisted.
+func (s *StateStore) emitLocked(eventType, deviceID string, data interface{}, now time.Time) {
+	if s.events == nil {
+		return
+	}
+	s.pendingEvents = append(s.pendingEvents, pendingEvent{
+		eventType: eventType,
+		deviceID:  deviceID,
+		data:      data,
+		at:        now,
+	})
+}
+
+func (s *StateStore) flushEventsLocked() {
+	pending := s.pendingEvents
+	s.pendingEvents = nil
+	for _, item := range pending {
+		s.events.Publish(item.eventType, item.deviceID, item.data, item.at)
+	}
+}
+
 // RegisterDevice creates or refreshes a device record and returns token.
 func (s *StateStore) RegisterDevice(deviceID, hwUID, modemIMEI, simICCID, firmwareVersion string, now time.Time) (*model.Device, bool, error) {
+	return s.RegisterDeviceWithProfile(deviceID, hwUID, modemIMEI, simICCID, firmwareVersion, DeviceProfile{}, now)
+}
+
+// DeviceProfile is hardware description reported at register. nil fields
+// keep whatever the device reported before.
+type DeviceProfile struct {
+	Capabilities *model.DeviceCapabilities
+	Targets      []model.DeviceTarget
+}
+
+// RegisterDeviceWithProfile registers device and stores reported profile.
+// Re-declared targets keep their state when index and expected MCU match.
+func (s *StateStore) RegisterDeviceWithProfile(deviceID, hwUID, modemIMEI, simICCID, firmwareVersion string, profile DeviceProfile, now time.Time) (*model.Device, bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	capabilities := profile.Capabilities
+	if capabilities != nil {
+		reported := *capabilities
+		reported.CommandTypes = append([]string(nil), capabilities.CommandTypes...)
+		reported.ReportedAt = now
+		capabilities = &reported
+	}
+
 	if existing, ok := s.state.Devices[deviceID]; ok {
 		if (existing.HWUID != "" && hwUID != "" && existing.HWUID != hwUID) ||
 			(existing.ModemIMEI != "" && modemIMEI != "" && existing.ModemIMEI != modemIMEI) {
@@ -113,10 +214,19 @@ func (s *StateStore) RegisterDevice(deviceID, hwUID, modemIMEI, simICCID, firmwa
 		existing.HWUID = firstNonEmpty(existing.HWUID, hwUID)
 		existing.ModemIMEI = firstNonEmpty(existing.ModemIMEI, modemIMEI)
 		existing.SimICCID = firstNonEmpty(existing.SimICCID, simICCID)
+		previousFirmware := existing.FirmwareVersion
 		existing.FirmwareVersion = firstNonEmpty(firmwareVersion, existing.FirmwareVersion)
-		existing.LastSeenAt = now
+		if capabilities != nil {
+			existing.Capabilities = capabilities
+		}
+		if profile.Targets != nil {
+			existing.Targets = mergeTargets(existing.Targets, profile.Targets)
+		}
 		existing.LastHeartbeatAt = now
-		existing.Status = model.DeviceStatusOnline
+		s.markOnlineLocked(existing, now)
+		if capabilities != nil || profile.Targets != nil || existing.FirmwareVersion != previousFirmware {
+			s.emitLocked(events.TypeDeviceRegistered, deviceID, model.CloneDevice(existing), now)
+		}
 
 		if err := s.persistLocked(); err != nil {
 			return nil, false, err
@@ -139,9 +249,12 @@ func (s *StateStore) RegisterDevice(deviceID, hwUID, modemIMEI, simICCID, firmwa
 		LastSeenAt:      now,
 		LastHeartbeatAt: now,
 		Status:          model.DeviceStatusOnline,
+		Capabilities:    capabilities,
+		Targets:         mergeTargets(nil, profile.Targets),
 	}
 
 	s.state.Devices[deviceID] = created
+	s.emitLocked(events.TypeDeviceRegistered, deviceID, model.CloneDevice(created), now)
 	if err := s.persistLocked(); err != nil {
 		return nil, false, err
 	}
@@ -161,8 +274,7 @@ func (s *StateStore) ValidateDeviceToken(deviceID, deviceToken string, now time.
 		return nil, ErrInvalidDeviceToken
 	}
 
-	device.LastSeenAt = now
-	device.Status = model.DeviceStatusOnline
+	s.markOnlineLocked(device, now)
 	if err := s.persistLocked(); err != nil {
 		return nil, err
 	}
@@ -180,9 +292,8 @@ func (s *StateStore) AddHeartbeat(deviceID, deviceToken string, now time.Time) e
 		return err
 	}
 
-	device.LastSeenAt = now
 	device.LastHeartbeatAt = now
-	device.Status = model.DeviceStatusOnline
+	s.markOnlineLocked(device, now)
 	return s.persistLocked()
 }
 
@@ -219,9 +330,19 @@ func (s *StateStore) AddTelemetry(deviceID, deviceToken string, data model.Telem
 
 	device.LastTelemetry = &copyTelemetry
 	device.LastTelemetryAt = now
-	device.LastSeenAt = now
-	device.Status = model.DeviceStatusOnline
-	return s.persistLocked()
+	s.markOnlineLocked(device, now)
+	s.emitLocked(events.TypeDeviceTelemetry, deviceID, record, now)
+	if err := s.persistLocked(); err != nil {
+		return err
+	}
+	// Fresh telemetry may clear interlock hold; let long-poll re-evaluate.
+	for _, item := range s.state.CommandsByID[deviceID] {
+		if item.Hold != nil && item.Status == model.CommandQueued {
+			s.signalCommandLocked(deviceID)
+			break
+		}
+	}
+	return nil
 }
 
 // AddLocation updates latest coordinates for a device.
@@ -237,8 +358,8 @@ func (s *StateStore) AddLocation(deviceID, deviceToken string, location model.Lo
 	copyLocation := location
 	device.LastLocation = &copyLocation
 	device.LastLocationAt = now
-	device.LastSeenAt = now
-	device.Status = model.DeviceStatusOnline
+	s.markOnlineLocked(device, now)
+	s.emitLocked(events.TypeDeviceLocation, deviceID, copyLocation, now)
 	return s.persistLocked()
 }
 
@@ -249,11 +370,7 @@ func (s *StateStore) ListDevices(now time.Time, offlineAfter time.Duration) ([]*
 
 	out := make([]*model.Device, 0, len(s.state.Devices))
 	for _, device := range s.state.Devices {
-		if now.Sub(device.LastSeenAt) > offlineAfter {
-			device.Status = model.DeviceStatusOffline
-		} else {
-			device.Status = model.DeviceStatusOnline
-		}
+		s.refreshStatusLocked(device, now, offlineAfter)
 		out = append(out, model.CloneDevice(device))
 	}
 
@@ -277,11 +394,7 @@ func (s *StateStore) GetDevice(deviceID string, now time.Time, offlineAfter time
 		return nil, ErrDeviceNotFound
 	}
 
-	if now.Sub(device.LastSeenAt) > offlineAfter {
-		device.Status = model.DeviceStatusOffline
-	} else {
-		device.Status = model.DeviceStatusOnline
-	}
+	s.refreshStatusLocked(device, now, offlineAfter)
 
 	if err := s.persistLocked(); err != nil {
 		return nil, err
@@ -309,6 +422,56 @@ func (s *StateStore) ListTelemetry(deviceID string, limit int) ([]model.Telemetr
 
 // AddCommand pushes new command to the selected device queue.
 func (s *StateStore) AddCommand(deviceID, commandType string, payload []byte, createdBy string, now time.Time) (*model.Command, error) {
+	command, _, err := s.InsertCommand(model.Command{
+		DeviceID:  deviceID,
+		Type:      commandType,
+		Payload:   payload,
+		CreatedBy: createdBy,
+	}, IdempotencyRequest{}, now)
+	return command, err
+}
+
+// InsertCommand queues a prepared command draft. When idem carries a key that
+// was already used, the originally created command is returned with replayed=true.
+func (s *StateStore) InsertCommand(draft model.Command, idem IdempotencyRequest, now time.Time) (*model.Command, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.state.Devices[draft.DeviceID]; !ok {
+		return nil, false, ErrDeviceNotFound
+	}
+
+	resourceID, found, err := s.lookupIdempotencyLocked(idempotencyScopeCommand, draft.CreatedBy, idem, now)
+	if err != nil {
+		return nil, false, err
+	}
+	if found {
+		if existing := s.findCommandLocked(resourceID); existing != nil {
+			return cloneCommand(existing), true, nil
+		}
+	}
+
+	command := &draft
+	command.CommandID = util.RandomToken("cmd", 12)
+	command.Payload = append([]byte(nil), draft.Payload...)
+	command.CreatedAt = now
+	if command.Status == "" {
+		command.Status = model.CommandQueued
+	}
+
+	s.state.CommandsByID[draft.DeviceID] = append(s.state.CommandsByID[draft.DeviceID], command)
+	s.emitCommandLocked(command, now)
+	s.rememberIdempotencyLocked(idempotencyScopeCommand, draft.CreatedBy, idem, command.CommandID, now)
+	if err := s.persistLocked(); err != nil {
+		return nil, false, err
+	}
+	s.signalCommandLocked(draft.DeviceID)
+	return cloneCommand(command), false, nil
+}
+
+// CommandSignal returns a channel that is closed when the next command is
+// queued for device. Callers must fetch it before pulling to avoid lost wakeups.
+func (s *StateStore) CommandSignal(deviceID string) (<-chan struct{}, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -316,21 +479,19 @@ func (s *StateStore) AddCommand(deviceID, commandType string, payload []byte, cr
 		return nil, ErrDeviceNotFound
 	}
 
-	command := &model.Command{
-		CommandID: util.RandomToken("cmd", 12),
-		DeviceID:  deviceID,
-		Type:      commandType,
-		Payload:   append([]byte(nil), payload...),
-		CreatedBy: createdBy,
-		CreatedAt: now,
-		Status:    model.CommandQueued,
+	signal, ok := s.commandSignals[deviceID]
+	if !ok {
+		signal = make(chan struct{})
+		s.commandSignals[deviceID] = signal
 	}
+	return signal, nil
+}
 
-	s.state.CommandsByID[deviceID] = append(s.state.CommandsByID[deviceID], command)
-	if err := s.persistLocked(); err != nil {
-		return nil, err
+func (s *StateStore) signalCommandLocked(deviceID string) {
+	if signal, ok := s.commandSignals[deviceID]; ok {
+		close(signal)
+		delete(s.commandSignals, deviceID)
 	}
-	return cloneCommand(command), nil
 }
 
 // ListCommands returns command history for a device.
@@ -354,8 +515,19 @@ func (s *StateStore) ListCommands(deviceID string, limit int) ([]*model.Command,
 	return out, nil
 }
 
+// DispatchGate evaluates preflight rules right before dispatch. Non-empty
+// return value holds command in queue with that reason.
+type DispatchGate func(device *model.Device, command *model.Command, now time.Time) string
+
 // PullNextCommand dispatches first queued command for device.
 func (s *StateStore) PullNextCommand(deviceID, deviceToken string, now time.Time) (*model.Command, error) {
+	return s.DispatchNextCommand(deviceID, deviceToken, nil, now)
+}
+
+// DispatchNextCommand dispatches first queued command that passes gate.
+// Commands awaiting approval and held ones (with visible hold reason) are
+// skipped, so they do not stall the rest of the device queue.
+func (s *StateStore) DispatchNextCommand(deviceID, deviceToken string, gate DispatchGate, now time.Time) (*model.Command, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -367,11 +539,18 @@ func (s *StateStore) PullNextCommand(deviceID, deviceToken string, now time.Time
 	queue := s.state.CommandsByID[deviceID]
 	for _, item := range queue {
 		if item.Status == model.CommandQueued {
+			if gate != nil {
+				if reason := gate(device, item, now); reason != "" {
+					s.holdCommandLocked(item, reason, now)
+					continue
+				}
+			}
+			item.Hold = nil
 			item.Status = model.CommandDispatched
 			dispatchTime := now
 			item.DispatchedAt = &dispatchTime
-			device.LastSeenAt = now
-			device.Status = model.DeviceStatusOnline
+			s.markOnlineLocked(device, now)
+			s.emitCommandLocked(item, now)
 			if err := s.persistLocked(); err != nil {
 				return nil, err
 			}
@@ -379,16 +558,48 @@ func (s *StateStore) PullNextCommand(deviceID, deviceToken string, now time.Time
 		}
 	}
 
-	device.LastSeenAt = now
-	device.Status = model.DeviceStatusOnline
+	s.markOnlineLocked(device, now)
 	if err := s.persistLocked(); err != nil {
 		return nil, err
 	}
 	return nil, nil
 }
 
-// CompleteCommand stores final result for one dispatched command.
-func (s *StateStore) CompleteCommand(deviceID, deviceToken, commandID string, result model.CommandResult, now time.Time) (*model.Command, error) {
+// GetDeviceCommand returns one command after device token check, without touching state.
+func (s *StateStore) GetDeviceCommand(deviceID, deviceToken, commandID string) (*model.Command, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, err := s.requireDeviceLocked(deviceID, deviceToken); err != nil {
+		return nil, err
+	}
+	item := s.findDeviceCommandLocked(deviceID, commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+	return cloneCommand(item), nil
+}
+
+// ListArtifacts returns all artifacts ordered by creation time.
+func (s *StateStore) ListArtifacts() []*model.Artifact {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*model.Artifact, 0, len(s.state.Artifacts))
+	for _, artifact := range s.state.Artifacts {
+		out = append(out, cloneArtifact(artifact))
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ArtifactID < out[j].ArtifactID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out
+}
+
+// UpdateCommandProgress stores intermediate progress and moves command to running.
+func (s *StateStore) UpdateCommandProgress(deviceID, deviceToken, commandID string, progress model.CommandProgress, now time.Time) (*model.Command, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -397,77 +608,180 @@ func (s *StateStore) CompleteCommand(deviceID, deviceToken, commandID string, re
 		return nil, err
 	}
 
-	queue := s.state.CommandsByID[deviceID]
-	for _, item := range queue {
-		if item.CommandID != commandID {
-			continue
-		}
+	item := s.findDeviceCommandLocked(deviceID, commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+	if !item.Status.CanTransitionTo(model.CommandRunning) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidCommandTransition, item.Status, model.CommandRunning)
+	}
 
-		if result.Status == "" {
-			result.Status = model.CommandFailed
-		}
+	progress.UpdatedAt = now
+	item.Progress = &progress
+	item.Status = model.CommandRunning
 
-		completedAt := now
-		item.CompletedAt = &completedAt
-		item.Result = &result
-		if result.Status == model.CommandSuccess {
-			item.Status = model.CommandSuccess
-		} else {
-			item.Status = model.CommandFailed
-		}
+	s.markOnlineLocked(device, now)
+	s.emitCommandLocked(item, now)
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
+	return cloneCommand(item), nil
+}
 
-		device.LastSeenAt = now
-		device.Status = model.DeviceStatusOnline
+// CompleteCommand stores final result for one dispatched command. reported
+// is the result as the device sent it and result the server-processed one.
+// A repeated identical report is acknowledged without changes; a conflicting
+// one is kept in ResultAttempts and rejected.
+func (s *StateStore) CompleteCommand(deviceID, deviceToken, commandID string, reported, result model.CommandResult, now time.Time) (*model.Command, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
+	device, err := s.requireDeviceLocked(deviceID, deviceToken)
+	if err != nil {
+		return nil, err
+	}
+
+	item := s.findDeviceCommandLocked(deviceID, commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+
+	if result.Status != model.CommandSuccess {
+		result.Status = model.CommandFailed
+	}
+	digest := resultDigest(&reported)
+
+	if item.Status.IsTerminal() && item.Result != nil {
+		// Commands completed before digests were kept compare processed results.
+		if digest == item.ReportedSHA256 || (item.ReportedSHA256 == "" && sameCommandResult(item.Result, &result)) {
+			return cloneCommand(item), nil
+		}
+		item.ResultAttempts = append(item.ResultAttempts, model.CommandResultAttempt{
+			ReceivedAt: now,
+			Result:     reported,
+		})
+		if len(item.ResultAttempts) > maxResultAttempts {
+			item.ResultAttempts = append([]model.CommandResultAttempt(nil), item.ResultAttempts[len(item.ResultAttempts)-maxResultAttempts:]...)
+		}
+		item.ResultAttemptCount++
 		if err := s.persistLocked(); err != nil {
 			return nil, err
 		}
-		return cloneCommand(item), nil
+		return nil, ErrCommandAlreadyCompleted
 	}
+	if !item.Status.CanTransitionTo(result.Status) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidCommandTransition, item.Status, result.Status)
+	}
+
+	completedAt := now
+	item.CompletedAt = &completedAt
+	item.Result = &result
+	item.ReportedSHA256 = digest
+	item.Status = result.Status
+	recordTargetResult(device, item, now)
 
-	return nil, ErrCommandNotFound
+	s.markOnlineLocked(device, now)
+	s.emitCommandLocked(item, now)
+
+	if err := s.persistLocked(); err != nil {
+		return nil, err
+	}
+	return cloneCommand(item), nil
 }
 
-// SaveArtifact stores binary payload and returns artifact metadata.
-func (s *StateStore) SaveArtifact(name, contentType string, payload []byte, createdBy string, now time.Time) (*model.Artifact, error) {
+// OverrideCommandInterlocks lets pending command bypass dispatch interlocks.
+func (s *StateStore) OverrideCommandInterlocks(commandID, operator, reason string, now time.Time) (*model.Command, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	digest := sha256.Sum256(payload)
-	digestHex := hex.EncodeToString(digest[:])
-	artifactID := "art_" + digestHex[:24]
+	item := s.findCommandLocked(commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+	if item.Status != model.CommandQueued && item.Status != model.CommandAwaitingApproval {
+		return nil, fmt.Errorf("%w: override for %s command", ErrInvalidCommandTransition, item.Status)
+	}
 
-	if existing, ok := s.state.Artifacts[artifactID]; ok {
-		return cloneArtifact(existing), nil
+	item.InterlockOverride = &model.CommandOverride{By: operator, At: now, Reason: reason}
+	s.emitCommandLocked(item, now)
+	if err := s.persistLocked(); err != nil {
+		return nil, err
 	}
+	s.signalCommandLocked(item.DeviceID)
+	return cloneCommand(item), nil
+}
 
-	artifact := &model.Artifact{
-		ArtifactID:    artifactID,
-		Name:          name,
-		ContentType:   contentType,
-		CreatedBy:     createdBy,
-		CreatedAt:     now,
-		Payload:       append([]byte(nil), payload...),
-		PayloadSHA256: digestHex,
+func (s *StateStore) holdCommandLocked(item *model.Command, reason string, now time.Time) {
+	if item.Hold != nil && item.Hold.Reason == reason {
+		item.Hold.CheckedAt = now
+		return
 	}
+	item.Hold = &model.CommandHold{Reason: reason, Since: now, CheckedAt: now}
+	s.emitCommandLocked(item, now)
+}
 
-	s.state.Artifacts[artifactID] = artifact
+// DecideCommandApproval approves or rejects command held in awaiting_approval.
+// Approval must come from an operator other than the creator or, for
+// scheduled commands, the schedule's author.
+func (s *StateStore) DecideCommandApproval(commandID string, approve bool, operator, reason string, now time.Time) (*model.Command, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	item := s.findCommandLocked(commandID)
+	if item == nil {
+		return nil, ErrCommandNotFound
+	}
+
+	next := model.CommandRejected
+	decision := model.ApprovalRejected
+	if approve {
+		next = model.CommandQueued
+		decision = model.ApprovalApproved
+	}
+	if item.Status != model.CommandAwaitingApproval || !item.Status.CanTransitionTo(next) {
+		return nil, fmt.Errorf("%w: %s -> %s", ErrInvalidCommandTransition, item.Status, next)
+	}
+	if approve && (item.CreatedBy == operator || item.RequestedBy == operator) {
+		return nil, ErrSelfApproval
+	}
+
+	decidedAt := now
+	item.Status = next
+	item.Approval = &model.CommandApproval{
+		Decision:  decision,
+		DecidedBy: operator,
+		DecidedAt: &decidedAt,
+		Reason:    reason,
+	}
+	if !approve {
+		item.CompletedAt = &decidedAt
+	}
+
+	s.emitCommandLocked(item, now)
 	if err := s.persistLocked(); err != nil {
 		return nil, err
 	}
-	return cloneArtifact(artifact), nil
+	// Approval queues the command for long-poll pullers.
+	s.signalCommandLocked(item.DeviceID)
+	return cloneCommand(item), nil
 }
 
-// GetArtifact returns artifact metadata and payload.
-func (s *StateStore) GetArtifact(artifactID string) (*model.Artifact, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// RefreshDeviceStatuses marks stale devices offline and persists only when
+// some status actually changed.
+func (s *StateStore) RefreshDeviceStatuses(now time.Time, offlineAfter time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-	artifact, ok := s.state.Artifacts[artifactID]
-	if !ok {
-		return nil, ErrArtifactNotFound
+	changed := false
+	for _, device := range s.state.Devices {
+		if s.refreshStatusLocked(device, now, offlineAfter) {
+			changed = true
+		}
 	}
-	return cloneArtifact(artifact), nil
+	if !changed {
+		return nil
+	}
+	return s.persistLocked()
 }
 
 // DeviceCount returns registered devices count.
@@ -488,6 +802,56 @@ func (s *StateStore) requireDeviceLocked(deviceID, token string) (*model.Device,
 	return device, nil
 }
 
+func (s *StateStore) markOnlineLocked(device *model.Device, now time.Time) {
+	device.LastSeenAt = now
+	s.setStatusLocked(device, model.DeviceStatusOnline, now)
+}
+
+func (s *StateStore) refreshStatusLocked(device *model.Device, now time.Time, offlineAfter time.Duration) bool {
+	if now.Sub(device.LastSeenAt) > offlineAfter {
+		return s.setStatusLocked(device, model.DeviceStatusOffline, now)
+	}
+	return s.setStatusLocked(device, model.DeviceStatusOnline, now)
+}
+
+func (s *StateStore) setStatusLocked(device *model.Device, status model.DeviceStatus, now time.Time) bool {
+	if device.Status == status {
+		return false
+	}
+	previous := device.Status
+	device.Status = status
+	s.emitLocked(events.TypeDeviceStatus, device.DeviceID, map[string]interface{}{
+		"status":       status,
+		"previous":     previous,
+		"last_seen_at": device.LastSeenAt,
+	}, now)
+	return true
+}
+
+func (s *StateStore) emitCommandLocked(command *model.Command, now time.Time) {
+	s.emitLocked(events.TypeCommandStatus, command.DeviceID, cloneCommand(command), now)
+}
+
+func (s *StateStore) findDeviceCommandLocked(deviceID, commandID string) *model.Command {
+	for _, item := range s.state.CommandsByID[deviceID] {
+		if item.CommandID == commandID {
+			return item
+		}
+	}
+	return nil
+}
+
+func (s *StateStore) findCommandLocked(commandID string) *model.Command {
+	for _, queue := range s.state.CommandsByID {
+		for _, item := range queue {
+			if item.CommandID == commandID {
+				return item
+			}
+		}
+	}
+	return nil
+}
+
 func cloneCommand(src *model.Command) *model.Command {
 	if src == nil {
 		return nil
@@ -496,12 +860,51 @@ func cloneCommand(src *model.Command) *model.Command {
 	if src.Payload != nil {
 		out.Payload = append([]byte(nil), src.Payload...)
 	}
+	if src.Channel != nil {
+		channel := *src.Channel
+		out.Channel = &channel
+	}
+	if src.Target != nil {
+		target := *src.Target
+		out.Target = &target
+	}
 	if src.Result != nil {
 		result := *src.Result
 		result.Metrics = cloneStringAny(src.Result.Metrics)
 		result.Data = cloneStringAny(src.Result.Data)
+		if src.Result.Verification != nil {
+			verification := *src.Result.Verification
+			result.Verification = &verification
+		}
 		out.Result = &result
 	}
+	if src.Approval != nil {
+		approval := *src.Approval
+		if src.Approval.DecidedAt != nil {
+			ts := *src.Approval.DecidedAt
+			approval.DecidedAt = &ts
+		}
+		out.Approval = &approval
+	}
+	if src.Hold != nil {
+		hold := *src.Hold
+		out.Hold = &hold
+	}
+	if src.InterlockOverride != nil {
+		override := *src.InterlockOverride
+		out.InterlockOverride = &override
+	}
+	if src.Progress != nil {
+		progress := *src.Progress
+		out.Progress = &progress
+	}
+	if src.ResultAttempts != nil {
+		out.ResultAttempts = append([]model.CommandResultAttempt(nil), src.ResultAttempts...)
+	}
+	if src.ResultBlob != nil {
+		blob := *src.ResultBlob
+		out.ResultBlob = &blob
+	}
 	if src.DispatchedAt != nil {
 		ts := *src.DispatchedAt
 		out.DispatchedAt = &ts
@@ -513,6 +916,27 @@ func cloneCommand(src *model.Command) *model.Command {
 	return &out
 }
 
+// sameCommandResult compares results by canonical JSON so that map order and
+// numeric decoding differences do not matter.
+func sameCommandResult(a, b *model.CommandResult) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	left, errLeft := json.Marshal(a)
+	right, errRight := json.Marshal(b)
+	return errLeft == nil && errRight == nil && bytes.Equal(left, right)
+}
+
+// resultDigest is sha256 of result's canonical JSON.
+func resultDigest(result *model.CommandResult) string {
+	encoded, err := json.Marshal(result)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(encoded)
+	return hex.EncodeToString(sum[:])
+}
+
 func cloneArtifact(src *model.Artifact) *model.Artifact {
 	if src == nil {
 		return nil
@@ -521,6 +945,22 @@ func cloneArtifact(src *model.Artifact) *model.Artifact {
 	if src.Payload != nil {
 		out.Payload = append([]byte(nil), src.Payload...)
 	}
+	if src.EntryPoint != nil {
+		entry := *src.EntryPoint
+		out.EntryPoint = &entry
+	}
+	out.Segments = append([]model.ArtifactSegment(nil), src.Segments...)
+	out.Tags = append([]string(nil), src.Tags...)
+	if src.Signature != nil {
+		signature := *src.Signature
+		out.Signature = &signature
+	}
+	out.Uploads = append([]model.ArtifactUpload(nil), src.Uploads...)
+	out.Deltas = append([]model.ArtifactDelta(nil), src.Deltas...)
+	if src.UpdatedAt != nil {
+		updatedAt := *src.UpdatedAt
+		out.UpdatedAt = &updatedAt
+	}
 	return &out
 }
 
@@ -1,8 +1,14 @@
 package store
 
 import (
+	"compress/gzip"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
@@ -64,10 +70,8 @@ func TestCommandLifecycle(t *testing.T) {
 		t.Fatalf("expected dispatched status, got %s", pulled.Status)
 	}
 
-	done, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, model.CommandResult{
-		Status:  model.CommandSuccess,
-		Message: "ok",
-	}, now.Add(2*time.Second))
+	result := model.CommandResult{Status: model.CommandSuccess, Message: "ok"}
+	done, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, result, result, now.Add(2*time.Second))
 	if err != nil {
 		t.Fatalf("complete command: %v", err)
 	}
@@ -106,3 +110,723 @@ func TestStorePersistence(t *testing.T) {
 		t.Fatalf("expected one device after reload")
 	}
 }
+
+func TestInsertCommandIdempotencyKey(t *testing.T) {
+	t.Parallel()
+
+	stateFile := filepath.Join(t.TempDir(), "state.json")
+	st, err := NewStateStore(stateFile, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	now := time.Unix(400, 0).UTC()
+	if _, _, err := st.RegisterDevice("dev-1", "uid-1", "imei-1", "iccid-1", "r1", now); err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+
+	draft := model.Command{DeviceID: "dev-1", Type: "swd_program", Payload: []byte(`{}`), CreatedBy: "operator"}
+	idem := IdempotencyRequest{Key: "key-1", Fingerprint: "fp-1", Retention: time.Hour}
+
+	first, replayed, err := st.InsertCommand(draft, idem, now)
+	if err != nil || replayed {
+		t.Fatalf("first insert: replayed=%v err=%v", replayed, err)
+	}
+
+	reloaded, err := NewStateStore(stateFile, 10)
+	if err != nil {
+		t.Fatalf("reload store: %v", err)
+	}
+
+	second, replayed, err := reloaded.InsertCommand(draft, idem, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("replay insert: %v", err)
+	}
+	if !replayed || second.CommandID != first.CommandID {
+		t.Fatalf("expected replay of %s, got %s (replayed=%v)", first.CommandID, second.CommandID, replayed)
+	}
+
+	_, _, err = reloaded.InsertCommand(draft, IdempotencyRequest{Key: "key-1", Fingerprint: "fp-2", Retention: time.Hour}, now.Add(time.Minute))
+	if err != ErrIdempotencyKeyReused {
+		t.Fatalf("expected key reuse error, got %v", err)
+	}
+
+	third, replayed, err := reloaded.InsertCommand(draft, idem, now.Add(2*time.Hour))
+	if err != nil || replayed || third.CommandID == first.CommandID {
+		t.Fatalf("expected fresh command after retention, replayed=%v err=%v", replayed, err)
+	}
+
+	commands, err := reloaded.ListCommands("dev-1", 0)
+	if err != nil {
+		t.Fatalf("list commands: %v", err)
+	}
+	if len(commands) != 2 {
+		t.Fatalf("expected 2 commands, got %d", len(commands))
+	}
+}
+
+func TestResultBlobChunkedUpload(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	now := time.Unix(600, 0).UTC()
+	device, _, err := st.RegisterDevice("dev-1", "uid-1", "imei-1", "iccid-1", "r1", now)
+	if err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+	cmd, err := st.AddCommand("dev-1", "swd_copy_firmware", []byte(`{}`), "operator", now)
+	if err != nil {
+		t.Fatalf("add command: %v", err)
+	}
+
+	chunk := ResultBlobChunk{TotalSize: 8, Body: strings.NewReader("flas")}
+	if _, err := st.AppendResultBlob("dev-1", device.DeviceToken, cmd.CommandID, chunk, now); !errors.Is(err, ErrInvalidCommandTransition) {
+		t.Fatalf("expected queued command rejection, got %v", err)
+	}
+	if _, err := st.PullNextCommand("dev-1", device.DeviceToken, now); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+
+	if _, err := st.AppendResultBlob("dev-1", device.DeviceToken, cmd.CommandID, chunk, now); err != nil {
+		t.Fatalf("append first chunk: %v", err)
+	}
+
+	retry := ResultBlobChunk{Offset: 0, TotalSize: 8, Body: strings.NewReader("flas")}
+	if _, err := st.AppendResultBlob("dev-1", device.DeviceToken, cmd.CommandID, retry, now); !errors.Is(err, ErrResultBlobOffset) {
+		t.Fatalf("expected offset mismatch, got %v", err)
+	}
+
+	status, err := st.ResultBlobStatus("dev-1", device.DeviceToken, cmd.CommandID)
+	if err != nil || status.Size != 4 {
+		t.Fatalf("unexpected status %#v err=%v", status, err)
+	}
+
+	last := ResultBlobChunk{Offset: 4, TotalSize: 8, Body: strings.NewReader("hdmp")}
+	blob, err := st.AppendResultBlob("dev-1", device.DeviceToken, cmd.CommandID, last, now)
+	if err != nil {
+		t.Fatalf("append last chunk: %v", err)
+	}
+	if !blob.Complete || blob.SHA256 == "" {
+		t.Fatalf("expected complete blob: %#v", blob)
+	}
+
+	artifact, err := st.PromoteResultBlob(cmd.CommandID, "dump.bin", "", "operator", now)
+	if err != nil {
+		t.Fatalf("promote: %v", err)
+	}
+	if artifact.Size != 8 || artifact.PayloadSHA256 != blob.SHA256 {
+		t.Fatalf("unexpected promoted artifact: %#v", artifact)
+	}
+	_, file, err := st.OpenArtifact(artifact.ArtifactID)
+	if err != nil {
+		t.Fatalf("open promoted artifact: %v", err)
+	}
+	defer file.Close()
+	if payload, _ := io.ReadAll(file); string(payload) != "flashdmp" {
+		t.Fatalf("unexpected promoted payload: %q", payload)
+	}
+}
+
+func TestResultBlobUploadsAreIsolated(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	now := time.Unix(600, 0).UTC()
+	tokens := make(map[string]string)
+	commandIDs := make(map[string]string)
+	for _, deviceID := range []string{"dev-1", "dev-2"} {
+		device, _, err := st.RegisterDevice(deviceID, "uid-"+deviceID, "imei-"+deviceID, "iccid", "r1", now)
+		if err != nil {
+			t.Fatalf("register %s: %v", deviceID, err)
+		}
+		cmd, err := st.AddCommand(deviceID, "swd_copy_firmware", []byte(`{}`), "operator", now)
+		if err != nil {
+			t.Fatalf("add command: %v", err)
+		}
+		if _, err := st.PullNextCommand(deviceID, device.DeviceToken, now); err != nil {
+			t.Fatalf("pull: %v", err)
+		}
+		tokens[deviceID] = device.DeviceToken
+		commandIDs[deviceID] = cmd.CommandID
+	}
+
+	gated, _, err := st.InsertCommand(model.Command{
+		DeviceID:  "dev-2",
+		Type:      "swd_copy_firmware",
+		Payload:   []byte(`{}`),
+		CreatedBy: "alice",
+		Status:    model.CommandAwaitingApproval,
+	}, IdempotencyRequest{}, now)
+	if err != nil {
+		t.Fatalf("insert gated command: %v", err)
+	}
+	chunk := ResultBlobChunk{TotalSize: 4, Body: strings.NewReader("dump")}
+	if _, err := st.AppendResultBlob("dev-2", tokens["dev-2"], gated.CommandID, chunk, now); !errors.Is(err, ErrInvalidCommandTransition) {
+		t.Fatalf("expected awaiting_approval rejection, got %v", err)
+	}
+
+	// A stalled upload for dev-1 must not hold up dev-2.
+	slow, feed := io.Pipe()
+	slowDone := make(chan error, 1)
+	go func() {
+		_, err := st.AppendResultBlob("dev-1", tokens["dev-1"], commandIDs["dev-1"], ResultBlobChunk{TotalSize: 4, Body: slow}, now)
+		slowDone <- err
+	}()
+	if _, err := feed.Write([]byte("du")); err != nil {
+		t.Fatalf("feed slow upload: %v", err)
+	}
+
+	fastDone := make(chan error, 1)
+	go func() {
+		_, err := st.AppendResultBlob("dev-2", tokens["dev-2"], commandIDs["dev-2"], ResultBlobChunk{TotalSize: 4, Body: strings.NewReader("dump")}, now)
+		fastDone <- err
+	}()
+	select {
+	case err := <-fastDone:
+		if err != nil {
+			t.Fatalf("append dev-2 blob: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("dev-2 upload blocked by stalled dev-1 upload")
+	}
+
+	_, _ = feed.Write([]byte("mp"))
+	feed.Close()
+	if err := <-slowDone; err != nil {
+		t.Fatalf("append dev-1 blob: %v", err)
+	}
+}
+
+func TestStageArtifactStreamsToDisk(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	stateFile := filepath.Join(dir, "state.json")
+	legacy := `{"artifacts":{"art_legacy":{"artifact_id":"art_legacy","name":"old.bin","payload":"b2xkIGltYWdl","payload_sha256":"x"}}}`
+	if err := os.WriteFile(stateFile, []byte(legacy), 0o644); err != nil {
+		t.Fatalf("write legacy state: %v", err)
+	}
+
+	st, err := NewStateStore(stateFile, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	migrated, file, err := st.OpenArtifact("art_legacy")
+	if err != nil {
+		t.Fatalf("open migrated artifact: %v", err)
+	}
+	payload, _ := io.ReadAll(file)
+	file.Close()
+	if string(payload) != "old image" || migrated.Size != 9 || migrated.Payload != nil {
+		t.Fatalf("unexpected migrated artifact: %#v (%q)", migrated, payload)
+	}
+	if raw, _ := os.ReadFile(stateFile); strings.Contains(string(raw), "b2xkIGltYWdl") {
+		t.Fatal("state file still carries inline payload")
+	}
+
+	if _, err := st.StageArtifact(strings.NewReader("0123456789"), 4); !errors.Is(err, ErrArtifactTooLarge) {
+		t.Fatalf("expected size limit error, got %v", err)
+	}
+
+	now := time.Unix(600, 0).UTC()
+	var ids []string
+	for i := 0; i < 2; i++ {
+		staged, err := st.StageArtifact(strings.NewReader("firmware"), 64)
+		if err != nil {
+			t.Fatalf("stage: %v", err)
+		}
+		artifact, _, err := st.CommitArtifact(staged, "fw.bin", "application/octet-stream", "operator", IdempotencyRequest{}, now)
+		if err != nil {
+			t.Fatalf("commit: %v", err)
+		}
+		ids = append(ids, artifact.ArtifactID)
+	}
+	if ids[0] != ids[1] {
+		t.Fatalf("identical uploads must share artifact: %v", ids)
+	}
+
+	entries, err := os.ReadDir(filepath.Join(dir, "blobs", "artifacts"))
+	if err != nil {
+		t.Fatalf("read artifact dir: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected two artifact files and no temp leftovers, got %d", len(entries))
+	}
+}
+
+func TestSaveArtifactParsesSegments(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	now := time.Unix(650, 0).UTC()
+
+	hexImage := ":020000040800F2\n:0400000001020304F2\n:040010001122334442\n:0400000508000101ED\n:00000001FF\n"
+	artifact, err := st.SaveArtifact("app.hex", "text/plain", []byte(hexImage), "operator", now)
+	if err != nil {
+		t.Fatalf("save hex artifact: %v", err)
+	}
+	if artifact.Format != "ihex" || artifact.EntryPoint == nil || *artifact.EntryPoint != 0x08000101 || len(artifact.Segments) != 2 {
+		t.Fatalf("unexpected parsed artifact: %+v", artifact)
+	}
+
+	_, segment, file, err := st.OpenArtifactSegment(artifact.ArtifactID, 1)
+	if err != nil {
+		t.Fatalf("open segment: %v", err)
+	}
+	data, _ := io.ReadAll(file)
+	file.Close()
+	if segment.Address != 0x08000010 || string(data) != "\x11\x22\x33\x44" {
+		t.Fatalf("unexpected segment %+v with %x", segment, data)
+	}
+	if _, _, _, err := st.OpenArtifactSegment(artifact.ArtifactID, 2); !errors.Is(err, ErrArtifactSegmentNotFound) {
+		t.Fatalf("expected missing segment error, got %v", err)
+	}
+
+	if _, err := st.SaveArtifact("broken.hex", "text/plain", []byte(":0400000001020304F3\n"), "operator", now); err == nil || !strings.Contains(err.Error(), "invalid artifact") {
+		t.Fatalf("expected parse error, got %v", err)
+	}
+	raw, err := st.SaveArtifact("raw.bin", "application/octet-stream", []byte{0, 1, 2}, "operator", now)
+	if err != nil || raw.Format != "bin" || len(raw.Segments) != 0 {
+		t.Fatalf("unexpected raw artifact: %+v, %v", raw, err)
+	}
+}
+
+func TestCommitArtifactRollsBackOnPersistFailure(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	dataFile := filepath.Join(dir, "state.json")
+	st, err := NewStateStore(dataFile, 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	now := time.Unix(660, 0).UTC()
+
+	// A directory where the temp state file goes makes every persist fail.
+	if err := os.Mkdir(dataFile+".tmp", 0o755); err != nil {
+		t.Fatalf("block state writes: %v", err)
+	}
+	hexImage := ":020000040800F2\n:0400000001020304F2\n:040010001122334442\n:00000001FF\n"
+	if _, err := st.SaveArtifact("app.hex", "text/plain", []byte(hexImage), "operator", now); err == nil {
+		t.Fatal("expected persist failure")
+	}
+	if items := st.ListArtifacts(); len(items) != 0 {
+		t.Fatalf("failed commit left artifacts in state: %+v", items)
+	}
+	if entries, _ := os.ReadDir(filepath.Join(dir, "blobs", "artifacts")); len(entries) != 0 {
+		t.Fatalf("failed commit left %d files behind", len(entries))
+	}
+
+	if err := os.Remove(dataFile + ".tmp"); err != nil {
+		t.Fatalf("unblock state writes: %v", err)
+	}
+	artifact, err := st.SaveArtifact("app.hex", "text/plain", []byte(hexImage), "operator", now)
+	if err != nil || len(artifact.Segments) != 2 {
+		t.Fatalf("save after failure: %+v, %v", artifact, err)
+	}
+
+	if err := os.Mkdir(dataFile+".tmp", 0o755); err != nil {
+		t.Fatalf("block state writes: %v", err)
+	}
+	if _, err := st.SaveArtifact("again.hex", "text/plain", []byte(hexImage), "bob", now); err == nil {
+		t.Fatal("expected persist failure for repeated upload")
+	}
+	stored, err := st.GetArtifact(artifact.ArtifactID)
+	if err != nil || len(stored.Uploads) != 1 {
+		t.Fatalf("failed repeat upload must not be recorded: %+v, %v", stored, err)
+	}
+	if _, _, _, err := st.OpenArtifactSegment(artifact.ArtifactID, 1); err != nil {
+		t.Fatalf("failed repeat upload removed files: %v", err)
+	}
+}
+
+func TestArtifactCatalogue(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	st, err := NewStateStore(filepath.Join(dir, "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	first, err := st.SaveArtifact("app-v1.bin", "application/octet-stream", []byte("image one"), "alice", time.Unix(800, 0).UTC())
+	if err != nil {
+		t.Fatalf("save artifact: %v", err)
+	}
+	again, err := st.SaveArtifact("app-renamed.bin", "application/octet-stream", []byte("image one"), "bob", time.Unix(801, 0).UTC())
+	if err != nil {
+		t.Fatalf("re-upload artifact: %v", err)
+	}
+	if again.ArtifactID != first.ArtifactID || again.Name != "app-v1.bin" || again.CreatedBy != "alice" || len(again.Uploads) != 2 || again.Uploads[1].CreatedBy != "bob" {
+		t.Fatalf("re-upload must keep original and record new upload: %+v", again)
+	}
+	second, err := st.SaveArtifact("other.bin", "application/octet-stream", []byte("image two"), "alice", time.Unix(802, 0).UTC())
+	if err != nil {
+		t.Fatalf("save second artifact: %v", err)
+	}
+
+	updated, err := st.UpdateArtifact(first.ArtifactID, func(artifact *model.Artifact) error {
+		artifact.Version = "1.2.0"
+		artifact.Tags = []string{"release"}
+		artifact.Size = 1
+		return nil
+	}, "carol", time.Unix(803, 0).UTC())
+	if err != nil {
+		t.Fatalf("update artifact: %v", err)
+	}
+	if updated.Version != "1.2.0" || updated.Size != first.Size || updated.UpdatedBy != "carol" {
+		t.Fatalf("unexpected metadata update: %+v", updated)
+	}
+
+	if items, _ := st.SearchArtifacts(ArtifactFilter{Text: "RENAMED"}, nil, 10); len(items) != 1 || items[0].ArtifactID != first.ArtifactID {
+		t.Fatalf("search by re-upload name: %+v", items)
+	}
+	if items, _ := st.SearchArtifacts(ArtifactFilter{Tags: []string{"release"}, CreatedBy: []string{"bob"}}, nil, 10); len(items) != 1 {
+		t.Fatalf("search by tag and uploader: %+v", items)
+	}
+	page, next := st.SearchArtifacts(ArtifactFilter{}, nil, 1)
+	if len(page) != 1 || page[0].ArtifactID != second.ArtifactID || next == nil {
+		t.Fatalf("unexpected first page: %+v %+v", page, next)
+	}
+	if page, next = st.SearchArtifacts(ArtifactFilter{}, next, 1); len(page) != 1 || page[0].ArtifactID != first.ArtifactID || next != nil {
+		t.Fatalf("unexpected second page: %+v %+v", page, next)
+	}
+
+	now := time.Unix(810, 0).UTC()
+	if _, _, err := st.RegisterDevice("dev-1", "uid-1", "imei-1", "iccid-1", "r1", now); err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+	payload := []byte(`{"artifact_id":"` + first.ArtifactID + `"}`)
+	if _, err := st.AddCommand("dev-1", "swd_program", payload, "alice", now); err != nil {
+		t.Fatalf("add command: %v", err)
+	}
+	if err := st.DeleteArtifact(first.ArtifactID); !errors.Is(err, ErrArtifactInUse) {
+		t.Fatalf("expected in-use error, got %v", err)
+	}
+
+	if err := st.DeleteArtifact(second.ArtifactID); err != nil {
+		t.Fatalf("delete artifact: %v", err)
+	}
+	if _, err := st.GetArtifact(second.ArtifactID); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("expected deleted artifact to be gone, got %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "blobs", "artifacts", second.ArtifactID+".bin")); !os.IsNotExist(err) {
+		t.Fatalf("artifact file not removed: %v", err)
+	}
+}
+
+func TestCompleteCommandStateMachine(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	now := time.Unix(700, 0).UTC()
+	device, _, err := st.RegisterDevice("dev-1", "uid-1", "imei-1", "iccid-1", "r1", now)
+	if err != nil {
+		t.Fatalf("register device: %v", err)
+	}
+	cmd, err := st.AddCommand("dev-1", "swd_verify", []byte(`{}`), "operator", now)
+	if err != nil {
+		t.Fatalf("add command: %v", err)
+	}
+
+	result := model.CommandResult{Status: model.CommandSuccess, Message: "ok", Data: map[string]interface{}{"crc32": "0a0b0c0d"}}
+	if _, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, result, result, now); !errors.Is(err, ErrInvalidCommandTransition) {
+		t.Fatalf("expected transition error for queued command, got %v", err)
+	}
+
+	if _, err := st.PullNextCommand("dev-1", device.DeviceToken, now); err != nil {
+		t.Fatalf("pull: %v", err)
+	}
+	// Server verification downgraded the report; retries still match the raw one.
+	processed := result
+	processed.Status = model.CommandFailed
+	processed.Message = "server verification failed"
+	done, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, result, processed, now.Add(time.Second))
+	if err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+
+	again, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, result, result, now.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("identical retry should be acknowledged: %v", err)
+	}
+	if !again.CompletedAt.Equal(*done.CompletedAt) || len(again.ResultAttempts) != 0 {
+		t.Fatalf("identical retry mutated command: %#v", again)
+	}
+
+	conflicting := model.CommandResult{Status: model.CommandFailed, Message: "timeout"}
+	if _, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, conflicting, conflicting, now.Add(2*time.Minute)); !errors.Is(err, ErrCommandAlreadyCompleted) {
+		t.Fatalf("expected already completed error, got %v", err)
+	}
+
+	history, err := st.ListCommands("dev-1", 1)
+	if err != nil {
+		t.Fatalf("list commands: %v", err)
+	}
+	stored := history[0]
+	if stored.Status != model.CommandFailed || stored.Result.Message != processed.Message || len(stored.ResultAttempts) != 1 {
+		t.Fatalf("unexpected stored command: %#v", stored)
+	}
+
+	for i := 0; i < 2*maxResultAttempts; i++ {
+		late := model.CommandResult{Status: model.CommandFailed, Message: fmt.Sprintf("retry %d", i)}
+		if _, err := st.CompleteCommand("dev-1", device.DeviceToken, cmd.CommandID, late, late, now.Add(time.Hour)); !errors.Is(err, ErrCommandAlreadyCompleted) {
+			t.Fatalf("expected already completed error, got %v", err)
+		}
+	}
+	history, _ = st.ListCommands("dev-1", 1)
+	stored = history[0]
+	if len(stored.ResultAttempts) != maxResultAttempts || stored.ResultAttemptCount != 2*maxResultAttempts+1 {
+		t.Fatalf("attempts not capped: %d kept, count %d", len(stored.ResultAttempts), stored.ResultAttemptCount)
+	}
+	if last := stored.ResultAttempts[maxResultAttempts-1].Result.Message; last != fmt.Sprintf("retry %d", 2*maxResultAttempts-1) {
+		t.Fatalf("latest attempt not kept: %s", last)
+	}
+}
+
+func TestSearchCommandsPagination(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+
+	now := time.Now().UTC()
+	for _, deviceID := range []string{"dev-1", "dev-2"} {
+		if _, _, err := st.RegisterDevice(deviceID, "uid-"+deviceID, "imei-"+deviceID, "iccid", "r1", now); err != nil {
+			t.Fatalf("register %s: %v", deviceID, err)
+		}
+	}
+
+	var created []string
+	for i := 0; i < 5; i++ {
+		deviceID := "dev-1"
+		if i%2 == 1 {
+			deviceID = "dev-2"
+		}
+		command, err := st.AddCommand(deviceID, "swd_reset", []byte(`{}`), "alice", now.Add(time.Duration(i)*time.Second))
+		if err != nil {
+			t.Fatalf("add command: %v", err)
+		}
+		created = append(created, command.CommandID)
+	}
+
+	var seen []string
+	var cursor *CommandCursor
+	for {
+		page, next := st.SearchCommands(CommandFilter{}, cursor, 2)
+		for _, command := range page {
+			seen = append(seen, command.CommandID)
+		}
+		if next == nil {
+			break
+		}
+		cursor = next
+	}
+	if len(seen) != len(created) {
+		t.Fatalf("paged %d commands, want %d", len(seen), len(created))
+	}
+	for i, commandID := range seen {
+		if commandID != created[len(created)-1-i] {
+			t.Fatalf("unexpected order: %v", seen)
+		}
+	}
+
+	page, _ := st.SearchCommands(CommandFilter{DeviceIDs: []string{"dev-2"}}, nil, 10)
+	if len(page) != 2 {
+		t.Fatalf("device filter returned %d commands", len(page))
+	}
+
+	summary := st.SummarizeCommands(CommandFilter{})
+	if summary.Totals.Total != 5 || summary.ByDevice["dev-1"].Total != 3 || summary.ByType["swd_reset"].ByStatus[model.CommandQueued] != 5 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if summary.Totals.SuccessRate != nil {
+		t.Fatalf("success rate without finished commands: %v", *summary.Totals.SuccessRate)
+	}
+}
+
+func TestOpenArtifactEncoded(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	st, err := NewStateStore(filepath.Join(dir, "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	payload := []byte(strings.Repeat("\xff\xff\xff\xff firmware padding ", 512))
+	artifact, err := st.SaveArtifact("fw.bin", "application/octet-stream", payload, "alice", time.Unix(900, 0).UTC())
+	if err != nil {
+		t.Fatalf("save artifact: %v", err)
+	}
+
+	readers := map[string]func(io.Reader) (io.ReadCloser, error){
+		EncodingGzip:    func(r io.Reader) (io.ReadCloser, error) { return gzip.NewReader(r) },
+		EncodingDeflate: zlib.NewReader,
+	}
+	for encoding, newReader := range readers {
+		_, file, err := st.OpenArtifactEncoded(artifact.ArtifactID, encoding)
+		if err != nil {
+			t.Fatalf("open %s: %v", encoding, err)
+		}
+		info, _ := file.Stat()
+		reader, err := newReader(file)
+		if err != nil {
+			t.Fatalf("%s reader: %v", encoding, err)
+		}
+		inflated, err := io.ReadAll(reader)
+		file.Close()
+		if err != nil || string(inflated) != string(payload) {
+			t.Fatalf("%s round trip failed: %v", encoding, err)
+		}
+		if info.Size() >= artifact.Size {
+			t.Fatalf("%s variant is %d bytes, payload %d", encoding, info.Size(), artifact.Size)
+		}
+		if _, err := os.Stat(st.artifactEncodedPath(artifact.ArtifactID, encoding)); err != nil {
+			t.Fatalf("%s variant not cached: %v", encoding, err)
+		}
+	}
+
+	if _, _, err := st.OpenArtifactEncoded(artifact.ArtifactID, "br"); err == nil {
+		t.Fatalf("expected unsupported encoding error")
+	}
+	if err := st.DeleteArtifact(artifact.ArtifactID); err != nil {
+		t.Fatalf("delete artifact: %v", err)
+	}
+	for encoding := range readers {
+		if _, err := os.Stat(st.artifactEncodedPath(artifact.ArtifactID, encoding)); !os.IsNotExist(err) {
+			t.Fatalf("%s variant left after delete: %v", encoding, err)
+		}
+	}
+}
+
+func TestEncodedVariantBuiltDuringDelete(t *testing.T) {
+	t.Parallel()
+
+	st, err := NewStateStore(filepath.Join(t.TempDir(), "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	artifact, err := st.SaveArtifact("fw.bin", "application/octet-stream", []byte("firmware"), "alice", time.Unix(900, 0).UTC())
+	if err != nil {
+		t.Fatalf("save artifact: %v", err)
+	}
+	path := st.artifactEncodedPath(artifact.ArtifactID, EncodingGzip)
+	if err := st.keepEncodedVariant(artifact.ArtifactID, path); err != nil {
+		t.Fatalf("variant of live artifact: %v", err)
+	}
+
+	if err := st.DeleteArtifact(artifact.ArtifactID); err != nil {
+		t.Fatalf("delete artifact: %v", err)
+	}
+	// Variant renamed into place after DeleteArtifact cleaned up.
+	if err := os.WriteFile(path, []byte("late"), 0o644); err != nil {
+		t.Fatalf("write late variant: %v", err)
+	}
+	if err := st.keepEncodedVariant(artifact.ArtifactID, path); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("expected artifact not found, got %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("late variant left behind: %v", err)
+	}
+}
+
+func TestCollectArtifacts(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	st, err := NewStateStore(filepath.Join(dir, "state.json"), 10)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	old := time.Unix(1000, 0).UTC()
+	now := old.Add(48 * time.Hour)
+
+	stale, err := st.SaveArtifact("stale.bin", "application/octet-stream", []byte("stale image"), "alice", old)
+	if err != nil {
+		t.Fatalf("save stale: %v", err)
+	}
+	pinned, err := st.SaveArtifact("pinned.bin", "application/octet-stream", []byte("pinned image"), "alice", old)
+	if err != nil {
+		t.Fatalf("save pinned: %v", err)
+	}
+	fresh, err := st.SaveArtifact("fresh.bin", "application/octet-stream", []byte("fresh image"), "alice", now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("save fresh: %v", err)
+	}
+	schedule, err := st.CreateSchedule(model.Schedule{
+		Name:    "nightly",
+		Cron:    "0 3 * * *",
+		Type:    "swd_program",
+		Payload: []byte(`{"artifact_id":"` + pinned.ArtifactID + `"}`),
+	}, old)
+	if err != nil {
+		t.Fatalf("create schedule: %v", err)
+	}
+
+	refs, err := st.ArtifactReferences(pinned.ArtifactID)
+	if err != nil || len(refs) != 1 || refs[0].Kind != model.ArtifactRefSchedule || refs[0].ID != schedule.ScheduleID || !refs[0].Active {
+		t.Fatalf("unexpected references: %+v, %v", refs, err)
+	}
+
+	report, err := st.CollectArtifacts(24*time.Hour, true, now)
+	if err != nil {
+		t.Fatalf("dry run: %v", err)
+	}
+	if len(report.Removed) != 1 || report.Removed[0].ArtifactID != stale.ArtifactID || report.Referenced != 1 || !report.DryRun {
+		t.Fatalf("unexpected dry-run report: %+v", report)
+	}
+	if _, err := st.GetArtifact(stale.ArtifactID); err != nil {
+		t.Fatalf("dry run removed artifact: %v", err)
+	}
+
+	report, err = st.CollectArtifacts(24*time.Hour, false, now)
+	if err != nil {
+		t.Fatalf("collect: %v", err)
+	}
+	if len(report.Removed) != 1 || report.FreedBytes != stale.Size || report.UsedBytes != pinned.Size+fresh.Size {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if _, err := st.GetArtifact(stale.ArtifactID); !errors.Is(err, ErrArtifactNotFound) {
+		t.Fatalf("expected stale artifact gone, got %v", err)
+	}
+	if _, err := os.Stat(st.artifactPath(stale.ArtifactID)); !os.IsNotExist(err) {
+		t.Fatalf("stale blob left on disk: %v", err)
+	}
+
+	st.SetArtifactQuota(st.ArtifactUsedBytes() + 4)
+	if _, err := st.SaveArtifact("big.bin", "application/octet-stream", []byte("too big"), "alice", now); !errors.Is(err, ErrArtifactQuotaExceeded) {
+		t.Fatalf("expected quota error, got %v", err)
+	}
+	if _, err := st.SaveArtifact("again.bin", "application/octet-stream", []byte("fresh image"), "bob", now); err != nil {
+		t.Fatalf("re-upload of stored bytes must not count against quota: %v", err)
+	}
+	if _, err := st.SaveArtifactDelta(fresh.ArtifactID, pinned.ArtifactID, []byte("oversized patch"), "alice", now); !errors.Is(err, ErrArtifactQuotaExceeded) {
+		t.Fatalf("expected quota error for delta, got %v", err)
+	}
+	if _, err := os.Stat(st.artifactDeltaPath(fresh.ArtifactID, pinned.ArtifactID)); !os.IsNotExist(err) {
+		t.Fatalf("refused delta left on disk: %v", err)
+	}
+	if temps, _ := filepath.Glob(filepath.Join(st.artifactDir(), "delta-*.tmp")); len(temps) != 0 {
+		t.Fatalf("refused delta temp files left: %v", temps)
+	}
+	if _, err := st.SaveArtifactDelta(fresh.ArtifactID, pinned.ArtifactID, []byte("ptch"), "alice", now); err != nil {
+		t.Fatalf("delta within quota: %v", err)
+	}
+}
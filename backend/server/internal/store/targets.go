@@ -0,0 +1,82 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// mergeTargets replaces declared target list, carrying over state of targets
+// whose index and expected MCU did not change.
+func mergeTargets(current []*model.DeviceTarget, declared []model.DeviceTarget) []*model.DeviceTarget {
+	if len(declared) == 0 {
+		return nil
+	}
+
+	previous := make(map[int]*model.DeviceTarget, len(current))
+	for _, target := range current {
+		previous[target.Index] = target
+	}
+
+	out := make([]*model.DeviceTarget, 0, len(declared))
+	for _, item := range declared {
+		target := &model.DeviceTarget{
+			Index:       item.Index,
+			Name:        item.Name,
+			ExpectedMCU: item.ExpectedMCU,
+		}
+		if old, ok := previous[item.Index]; ok && old.ExpectedMCU == item.ExpectedMCU {
+			target.LastConnect = old.LastConnect
+			target.LastProgram = old.LastProgram
+		}
+		out = append(out, target)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Index < out[j].Index })
+	return out
+}
+
+// recordTargetResult updates per-target state after command finished.
+// Successful swd_program without target is recorded on the device itself.
+func recordTargetResult(device *model.Device, command *model.Command, now time.Time) {
+	if command.Target == nil {
+		if command.Type == "swd_program" && command.Result != nil && command.Result.Status == model.CommandSuccess {
+			device.LastProgram = programRecord(command, now)
+		}
+		return
+	}
+
+	var target *model.DeviceTarget
+	for _, item := range device.Targets {
+		if item.Index == *command.Target {
+			target = item
+			break
+		}
+	}
+	if target == nil || command.Result == nil {
+		return
+	}
+
+	switch command.Type {
+	case "swd_connect":
+		target.LastConnect = &model.TargetConnect{
+			CommandID: command.CommandID,
+			Status:    command.Result.Status,
+			Message:   command.Result.Message,
+			At:        now,
+		}
+	case "swd_program":
+		if command.Result.Status != model.CommandSuccess {
+			return
+		}
+		target.LastProgram = programRecord(command, now)
+	}
+}
+
+func programRecord(command *model.Command, now time.Time) *model.TargetProgram {
+	return &model.TargetProgram{
+		CommandID:  command.CommandID,
+		ArtifactID: payloadArtifactID(command.Payload),
+		At:         now,
+	}
+}
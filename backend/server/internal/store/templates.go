@@ -0,0 +1,104 @@
+package store
+
+import (
+	"sort"
+	"time"
+
+	"lte_swd/backend/server/internal/model"
+)
+
+// SaveTemplate creates template or replaces existing one. With create=true an
+// existing name is an error; with create=false a missing name is.
+func (s *StateStore) SaveTemplate(template model.CommandTemplate, create bool, now time.Time) (*model.CommandTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, exists := s.state.Templates[template.Name]
+	switch {
+	case create && exists:
+		return nil, ErrTemplateExists
+	case !create && !exists:
+		return nil, ErrTemplateNotFound
+	}
+
+	saved := cloneTemplate(&template)
+	saved.CreatedAt = now
+	if exists {
+		saved.CreatedBy = current.CreatedBy
+		saved.CreatedAt = current.CreatedAt
+	}
+	saved.UpdatedAt = now
+
+	s.state.Templates[saved.Name] = saved
+	if err := s.persistLocked(); err != nil {
+		if exists {
+			s.state.Templates[saved.Name] = current
+		} else {
+			delete(s.state.Templates, saved.Name)
+		}
+		return nil, err
+	}
+	return cloneTemplate(saved), nil
+}
+
+// DeleteTemplate removes template by name.
+func (s *StateStore) DeleteTemplate(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, ok := s.state.Templates[name]
+	if !ok {
+		return ErrTemplateNotFound
+	}
+	delete(s.state.Templates, name)
+	if err := s.persistLocked(); err != nil {
+		s.state.Templates[name] = current
+		return err
+	}
+	return nil
+}
+
+// GetTemplate returns template by name.
+func (s *StateStore) GetTemplate(name string) (*model.CommandTemplate, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	template, ok := s.state.Templates[name]
+	if !ok {
+		return nil, ErrTemplateNotFound
+	}
+	return cloneTemplate(template), nil
+}
+
+// ListTemplates returns templates ordered by name.
+func (s *StateStore) ListTemplates() []*model.CommandTemplate {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*model.CommandTemplate, 0, len(s.state.Templates))
+	for _, template := range s.state.Templates {
+		out = append(out, cloneTemplate(template))
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func cloneTemplate(src *model.CommandTemplate) *model.CommandTemplate {
+	if src == nil {
+		return nil
+	}
+	out := *src
+	if src.Payload != nil {
+		out.Payload = append([]byte(nil), src.Payload...)
+	}
+	if src.Params != nil {
+		out.Params = make([]model.TemplateParam, len(src.Params))
+		for i, param := range src.Params {
+			if param.Default != nil {
+				param.Default = append([]byte(nil), param.Default...)
+			}
+			out.Params[i] = param
+		}
+	}
+	return &out
+}
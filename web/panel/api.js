@@ -8,8 +8,8 @@ export class ApiClient {
     this.token = token || "";
   }
 
-  async login(password) {
-    return this.#request("POST", "/api/v1/operator/login", { password }, false);
+  async login(username, password) {
+    return this.#request("POST", "/api/v1/operator/login", { username, password }, false);
   }
 
   async capabilities() {
@@ -38,28 +38,68 @@ export class ApiClient {
     );
   }
 
+  // EventSource cannot send Authorization header, so token travels as query.
+  openEventStream() {
+    const url = `${this.baseUrl}/api/v1/events?access_token=${encodeURIComponent(this.token)}`;
+    return new EventSource(url);
+  }
+
+  async listTemplates() {
+    return this.#request("GET", "/api/v1/templates");
+  }
+
   async createCommand(payload) {
-    return this.#request("POST", "/api/v1/commands", payload);
+    return this.#request("POST", "/api/v1/commands", payload, true, newIdempotencyKey());
+  }
+
+  async approveCommand(commandId, reason = "") {
+    return this.#request("POST", `/api/v1/commands/${encodeURIComponent(commandId)}/approve`, { reason });
+  }
+
+  async rejectCommand(commandId, reason) {
+    return this.#request("POST", `/api/v1/commands/${encodeURIComponent(commandId)}/reject`, { reason });
   }
 
   async uploadArtifact(payload) {
-    return this.#request("POST", "/api/v1/artifacts", payload);
+    return this.#request("POST", "/api/v1/artifacts", payload, true, newIdempotencyKey());
+  }
+
+  async listArtifacts(query = "", limit = 20) {
+    const params = new URLSearchParams({ limit: String(limit) });
+    if (query) {
+      params.set("q", query);
+    }
+    return this.#request("GET", `/api/v1/artifacts?${params}`);
   }
 
-  async #request(method, path, body, withAuth = true) {
+  // Raw upload: the file is sent as request body without base64 overhead.
+  async uploadArtifactFile(file, name) {
+    const path = `/api/v1/artifacts?name=${encodeURIComponent(name || file.name)}`;
+    return this.#request("PUT", path, file, true, newIdempotencyKey());
+  }
+
+  async #request(method, path, body, withAuth = true, idempotencyKey = "") {
+    const isBlob = body instanceof Blob;
     const headers = {
-      "Content-Type": "application/json",
+      "Content-Type": isBlob ? body.type || "application/octet-stream" : "application/json",
     };
 
     if (withAuth && this.token) {
       headers.Authorization = `Bearer ${this.token}`;
     }
+    if (idempotencyKey) {
+      headers["Idempotency-Key"] = idempotencyKey;
+    }
 
-    const response = await fetch(this.baseUrl + path, {
-      method,
-      headers,
-      body: body ? JSON.stringify(body) : undefined,
-    });
+    const response = await fetchWithRetry(
+      this.baseUrl + path,
+      {
+        method,
+        headers,
+        body: isBlob ? body : body ? JSON.stringify(body) : undefined,
+      },
+      idempotencyKey ? 3 : 1
+    );
 
     const isJSON = (response.headers.get("content-type") || "").includes("application/json");
     const data = isJSON ? await response.json() : null;
@@ -73,6 +113,28 @@ export class ApiClient {
   }
 }
 
+// Only requests carrying an Idempotency-Key are retried: the backend replays
+// the originally created resource instead of creating a duplicate.
+async function fetchWithRetry(url, options, attempts) {
+  for (let attempt = 1; ; attempt += 1) {
+    try {
+      return await fetch(url, options);
+    } catch (error) {
+      if (attempt >= attempts) {
+        throw error;
+      }
+      await new Promise((resolve) => setTimeout(resolve, 500 * attempt));
+    }
+  }
+}
+
+function newIdempotencyKey() {
+  if (globalThis.crypto?.randomUUID) {
+    return crypto.randomUUID();
+  }
+  return `${Date.now().toString(16)}-${Math.random().toString(16).slice(2)}`;
+}
+
 export async function fileToBase64(file) {
   const buffer = await file.arrayBuffer();
   const bytes = new Uint8Array(buffer);
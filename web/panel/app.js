@@ -1,18 +1,27 @@
-import { ApiClient, fileToBase64 } from "./api.js";
+import { ApiClient } from "./api.js";
 import { DeviceMap } from "./map.js";
 import { ProvisioningUSB } from "./webusb.js";
 
 const state = {
   token: "",
+  operator: "",
   devices: [],
   selectedDeviceId: "",
   selectedDevice: null,
   commands: [],
   supportedCommands: [],
+  templates: [],
   refreshTimer: null,
+  artifactSearchTimer: null,
   refreshInFlight: false,
+  eventSource: null,
+  streamLive: false,
 };
 
+// Live stream keeps state fresh; the timer is only a safety net.
+const REFRESH_INTERVAL_MS = 5000;
+const REFRESH_INTERVAL_LIVE_MS = 60000;
+
 const api = new ApiClient("");
 const mapView = new DeviceMap("map");
 const usb = new ProvisioningUSB();
@@ -32,11 +41,16 @@ const deviceList = document.getElementById("deviceList");
 const deviceDetail = document.getElementById("deviceDetail");
 const commandHistory = document.getElementById("commandHistory");
 const commandType = document.getElementById("commandType");
+const commandTemplate = document.getElementById("commandTemplate");
+const commandTarget = document.getElementById("commandTarget");
+const commandPayloadLabel = document.getElementById("commandPayloadLabel");
 const commandForm = document.getElementById("commandForm");
 const commandResult = document.getElementById("commandResult");
 
 const artifactForm = document.getElementById("artifactForm");
 const artifactResult = document.getElementById("artifactResult");
+const artifactSearch = document.getElementById("artifactSearch");
+const artifactList = document.getElementById("artifactList");
 
 const refreshBtn = document.getElementById("refreshBtn");
 
@@ -68,11 +82,13 @@ loginForm.addEventListener("submit", async (event) => {
   loginError.textContent = "";
 
   const formData = new FormData(loginForm);
+  const username = String(formData.get("username") || "").trim();
   const password = String(formData.get("password") || "").trim();
 
   try {
-    const response = await api.login(password);
+    const response = await api.login(username, password);
     state.token = response.token;
+    state.operator = response.operator || "";
     api.setToken(state.token);
 
     loginSection.classList.add("hidden");
@@ -84,6 +100,7 @@ loginForm.addEventListener("submit", async (event) => {
 
     await loadCapabilities();
     await refreshAll();
+    openEventStream();
     scheduleRefresh();
   } catch (error) {
     loginError.textContent = String(error.message || error);
@@ -110,13 +127,18 @@ commandForm.addEventListener("submit", async (event) => {
     const payload = JSON.parse(payloadRaw);
 
     setLedState(ledCommand, "yellow", true);
-    const command = await api.createCommand({
-      device_id: state.selectedDeviceId,
-      type: commandType.value,
-      payload,
-    });
+    const request = commandTemplate.value
+      ? { device_id: state.selectedDeviceId, template: commandTemplate.value, params: payload }
+      : { device_id: state.selectedDeviceId, type: commandType.value, payload };
+    if (!commandTarget.hidden && commandTarget.value !== "") {
+      request.target = Number(commandTarget.value);
+    }
+    const command = await api.createCommand(request);
 
-    commandResult.textContent = `Command ${command.command_id} queued`;
+    commandResult.textContent =
+      command.status === "awaiting_approval"
+        ? `Command ${command.command_id} awaits approval by another operator`
+        : `Command ${command.command_id} queued`;
     await refreshCommands();
   } catch (error) {
     commandResult.textContent = String(error.message || error);
@@ -136,19 +158,27 @@ artifactForm.addEventListener("submit", async (event) => {
       throw new Error("No file selected");
     }
 
-    const base64Data = await fileToBase64(file);
-    const response = await api.uploadArtifact({
-      name: nameInput.value || file.name,
-      content_type: file.type || "application/octet-stream",
-      base64_data: base64Data,
-    });
+    const response = await api.uploadArtifactFile(file, nameInput.value || file.name);
 
-    artifactResult.textContent = `artifact_id: ${response.artifact_id} (sha256: ${response.payload_sha256})`;
+    const segments = (response.segments || [])
+      .map((segment) => `#${segment.index} @0x${segment.address.toString(16).padStart(8, "0")} (${segment.size} B)`)
+      .join(", ");
+    artifactResult.textContent = `artifact_id: ${response.artifact_id} (sha256: ${response.payload_sha256}, format: ${response.format || "bin"})${segments ? ` | segments: ${segments}` : ""}`;
+    await loadArtifacts();
   } catch (error) {
     artifactResult.textContent = String(error.message || error);
   }
 });
 
+artifactSearch.addEventListener("input", () => {
+  clearTimeout(state.artifactSearchTimer);
+  state.artifactSearchTimer = setTimeout(() => {
+    loadArtifacts().catch((error) => {
+      artifactResult.textContent = String(error.message || error);
+    });
+  }, 300);
+});
+
 usbRefreshBtn.addEventListener("click", async () => {
   await refreshUsbDeviceList();
 });
@@ -459,8 +489,72 @@ async function loadCapabilities() {
     option.textContent = type;
     commandType.appendChild(option);
   });
+
+  await loadTemplates();
+  await loadArtifacts();
+}
+
+async function loadArtifacts() {
+  const response = await api.listArtifacts(artifactSearch.value.trim());
+  artifactList.innerHTML = "";
+
+  const items = response.items || [];
+  if (items.length === 0) {
+    const empty = document.createElement("li");
+    empty.className = "history-item";
+    empty.textContent = "No artifacts";
+    artifactList.appendChild(empty);
+    return;
+  }
+
+  items.forEach((artifact) => {
+    const item = document.createElement("li");
+    item.className = "history-item";
+    const labels = [artifact.version, artifact.target_mcu, ...(artifact.tags || [])].filter(Boolean).map(escapeHtml);
+    item.innerHTML = [
+      `<strong>${escapeHtml(artifact.name)}</strong> (${escapeHtml(artifact.format || "bin")}, ${artifact.size} B)`,
+      `<div class="muted">id: ${artifact.artifact_id}</div>`,
+      labels.length ? `<div class="muted">${labels.join(" | ")}</div>` : "",
+      `<div class="muted">uploaded: ${formatTimestamp(artifact.created_at)} by ${escapeHtml(artifact.created_by)}</div>`,
+    ].join("");
+    artifactList.appendChild(item);
+  });
+}
+
+async function loadTemplates() {
+  const response = await api.listTemplates();
+  state.templates = response.items || [];
+
+  commandTemplate.querySelectorAll("option[value]:not([value=''])").forEach((option) => option.remove());
+  state.templates.forEach((template) => {
+    const option = document.createElement("option");
+    option.value = template.name;
+    option.textContent = template.description ? `${template.name} - ${template.description}` : template.name;
+    commandTemplate.appendChild(option);
+  });
 }
 
+// With template selected, the textarea holds template params instead of payload.
+commandTemplate.addEventListener("change", () => {
+  const template = state.templates.find((item) => item.name === commandTemplate.value);
+  const payloadInput = document.getElementById("commandPayload");
+
+  commandType.disabled = Boolean(template);
+  if (!template) {
+    commandPayloadLabel.textContent = "Payload JSON";
+    payloadInput.value = "{}";
+    return;
+  }
+
+  commandType.value = template.type;
+  commandPayloadLabel.textContent = "Template Params JSON";
+  const params = {};
+  (template.params || []).forEach((param) => {
+    params[param.name] = param.default === undefined ? "" : param.default;
+  });
+  payloadInput.value = JSON.stringify(params, null, 2);
+});
+
 async function refreshAll() {
   if (state.refreshInFlight) {
     return;
@@ -579,7 +673,117 @@ function scheduleRefresh() {
   }
   state.refreshTimer = setTimeout(() => {
     refreshAll();
-  }, 5000);
+  }, state.streamLive ? REFRESH_INTERVAL_LIVE_MS : REFRESH_INTERVAL_MS);
+}
+
+function openEventStream() {
+  if (state.eventSource) {
+    state.eventSource.close();
+  }
+
+  const source = api.openEventStream();
+  state.eventSource = source;
+
+  source.addEventListener("open", () => {
+    state.streamLive = true;
+    scheduleRefresh();
+  });
+  source.addEventListener("error", () => {
+    // EventSource reconnects on its own and resumes via Last-Event-ID.
+    state.streamLive = false;
+    connectionState.textContent = "degraded";
+    setLedState(ledCloud, "yellow", true);
+  });
+
+  source.addEventListener("resync", () => refreshAll());
+  source.addEventListener("device.registered", (event) => {
+    upsertDevice(JSON.parse(event.data).data);
+  });
+  source.addEventListener("device.status", (event) => {
+    const payload = JSON.parse(event.data);
+    patchDevice(payload.device_id, { status: payload.data.status });
+  });
+  source.addEventListener("device.telemetry", (event) => {
+    const payload = JSON.parse(event.data);
+    patchDevice(payload.device_id, {
+      last_telemetry: payload.data.data,
+      last_telemetry_at: payload.data.timestamp,
+      last_seen_at: payload.data.timestamp,
+    });
+  });
+  source.addEventListener("device.location", (event) => {
+    const payload = JSON.parse(event.data);
+    patchDevice(payload.device_id, {
+      last_location: payload.data,
+      last_location_at: payload.timestamp,
+      last_seen_at: payload.timestamp,
+    });
+  });
+  source.addEventListener("command.status", (event) => {
+    upsertCommand(JSON.parse(event.data).data);
+  });
+}
+
+function upsertDevice(device) {
+  const index = state.devices.findIndex((item) => item.device_id === device.device_id);
+  if (index >= 0) {
+    state.devices[index] = device;
+  } else {
+    state.devices.push(device);
+    state.devices.sort((a, b) => a.device_id.localeCompare(b.device_id));
+  }
+  applyDeviceUpdate(device);
+}
+
+function patchDevice(deviceId, changes) {
+  const device = state.devices.find((item) => item.device_id === deviceId);
+  if (!device) {
+    refreshAll();
+    return;
+  }
+  Object.assign(device, changes);
+  applyDeviceUpdate(device);
+}
+
+function applyDeviceUpdate(device) {
+  connectionState.textContent = "online";
+  setLedState(ledCloud, "green", false);
+
+  renderDeviceList();
+  mapView.updateDevices(state.devices);
+
+  if (device.device_id !== state.selectedDeviceId) {
+    return;
+  }
+  state.selectedDevice = device;
+  renderDeviceDetail(device);
+  if (device.status === "online") {
+    setLedState(ledModem, "green", false);
+  } else {
+    setLedState(ledModem, "red", true);
+  }
+}
+
+function upsertCommand(command) {
+  if (command.device_id !== state.selectedDeviceId) {
+    return;
+  }
+
+  const index = state.commands.findIndex((item) => item.command_id === command.command_id);
+  if (index >= 0) {
+    state.commands[index] = command;
+  } else {
+    state.commands.push(command);
+  }
+  renderCommandHistory();
+
+  if (command.status === "success") {
+    setLedState(ledCommand, "green", false);
+  } else if (command.status === "failed") {
+    setLedState(ledCommand, "red", true);
+  } else {
+    setLedState(ledCommand, "yellow", true);
+  }
 }
 
 function escapeHtml(str) {
@@ -611,8 +815,16 @@ function renderDeviceDetail(device) {
     ["network", device.last_telemetry?.network_state ?? "n/a"],
     ["coordinates", coordinates],
     ["accuracy_m", device.last_location?.accuracy_m ?? "n/a"],
+    ["swd_clock_max", device.capabilities?.max_swd_clock_hz ? `${device.capabilities.max_swd_clock_hz} Hz` : "n/a"],
+    ["ram_buffer", device.capabilities?.ram_buffer_bytes ? `${device.capabilities.ram_buffer_bytes} B` : "n/a"],
   ];
 
+  (device.targets || []).forEach((target) => {
+    const program = target.last_program ? ` | programmed ${target.last_program.artifact_id || "?"}` : "";
+    const connect = target.last_connect ? ` | connect ${target.last_connect.status}` : "";
+    fields.push([`target_${target.index}`, `${target.name} (${target.expected_mcu || "any"})${connect}${program}`]);
+  });
+
   deviceDetail.innerHTML = "";
   fields.forEach(([label, value]) => {
     const row = document.createElement("div");
@@ -622,6 +834,41 @@ function renderDeviceDetail(device) {
     row.innerHTML = `<span class="detail-label">${escapeHtml(label)}</span><span class="detail-value">${safeValue}</span>`;
     deviceDetail.appendChild(row);
   });
+
+  applyCommandCapabilities(device);
+  renderTargetOptions(device);
+}
+
+function renderTargetOptions(device) {
+  const targets = device.targets || [];
+  const previous = commandTarget.value;
+
+  commandTarget.innerHTML = "";
+  targets.forEach((target) => {
+    const option = document.createElement("option");
+    option.value = String(target.index);
+    option.textContent = `${target.index}: ${target.name}`;
+    commandTarget.appendChild(option);
+  });
+  if (targets.some((target) => String(target.index) === previous)) {
+    commandTarget.value = previous;
+  }
+
+  document.querySelectorAll(".target-field").forEach((element) => {
+    element.hidden = targets.length === 0;
+  });
+}
+
+// Probes that report capabilities get unsupported command types disabled.
+function applyCommandCapabilities(device) {
+  const supported = device.capabilities?.command_types || [];
+  Array.from(commandType.options).forEach((option) => {
+    option.disabled = supported.length > 0 && !supported.includes(option.value);
+  });
+  if (commandType.selectedOptions[0]?.disabled) {
+    const firstEnabled = Array.from(commandType.options).find((option) => !option.disabled);
+    commandType.value = firstEnabled ? firstEnabled.value : "";
+  }
 }
 
 function formatSignalReadout(rssi) {
@@ -665,17 +912,81 @@ function renderCommandHistory() {
       const payload = safeJSONStringify(command.payload);
       const result = safeJSONStringify(command.result);
 
+      const stalled = command.stalled ? " [stalled]" : "";
+      const approval = command.approval?.decided_by
+        ? `<div class="muted">${escapeHtml(command.approval.decision)} by ${escapeHtml(command.approval.decided_by)}${
+            command.approval.reason ? `: ${escapeHtml(command.approval.reason)}` : ""
+          }</div>`
+        : "";
+
       item.innerHTML = [
-        `<strong>${command.type}</strong> (${command.status})`,
+        `<strong>${command.type}</strong> (${command.status}${stalled})`,
         `<div class="muted">id: ${command.command_id}</div>`,
-        `<div class="muted">created: ${formatTimestamp(command.created_at)}</div>`,
+        `<div class="muted">created: ${formatTimestamp(command.created_at)} by ${escapeHtml(command.created_by)}</div>`,
+        approval,
+        command.hold ? `<div class="muted">held: ${escapeHtml(command.hold.reason)}</div>` : "",
+        command.progress ? `<div class="muted">progress: ${formatProgress(command.progress)}</div>` : "",
         `<div class="muted">payload: ${payload}</div>`,
         `<div class="muted">result: ${result}</div>`,
       ].join("");
+      if (command.status === "awaiting_approval") {
+        item.appendChild(renderApprovalActions(command));
+      }
       commandHistory.appendChild(item);
     });
 }
 
+function renderApprovalActions(command) {
+  const actions = document.createElement("div");
+  actions.className = "history-actions";
+
+  const approve = document.createElement("button");
+  approve.type = "button";
+  approve.textContent = "Approve";
+  approve.disabled = command.created_by === state.operator;
+  approve.title = approve.disabled ? "Must be approved by a different operator" : "";
+  approve.addEventListener("click", () => decideCommand(command, true));
+
+  const reject = document.createElement("button");
+  reject.type = "button";
+  reject.textContent = "Reject";
+  reject.addEventListener("click", () => decideCommand(command, false));
+
+  actions.append(approve, reject);
+  return actions;
+}
+
+async function decideCommand(command, approve) {
+  const reason = window.prompt(approve ? "Approval note (optional)" : "Rejection reason", "");
+  if (reason === null || (!approve && !reason.trim())) {
+    return;
+  }
+
+  try {
+    const updated = approve
+      ? await api.approveCommand(command.command_id, reason.trim())
+      : await api.rejectCommand(command.command_id, reason.trim());
+    upsertCommand(updated);
+  } catch (error) {
+    commandResult.textContent = String(error.message || error);
+    setLedState(ledCommand, "red", true);
+  }
+}
+
+function formatProgress(progress) {
+  const parts = [`${Number(progress.percent || 0).toFixed(0)}%`];
+  if (progress.phase) {
+    parts.push(escapeHtml(progress.phase));
+  }
+  if (progress.bytes_total) {
+    parts.push(`${progress.bytes_written}/${progress.bytes_total} B`);
+  } else if (progress.bytes_written) {
+    parts.push(`${progress.bytes_written} B`);
+  }
+  parts.push(formatTimestamp(progress.updated_at));
+  return parts.join(" | ");
+}
+
 function setLedState(node, color, pulse) {
   if (!node) {
     return;